@@ -46,3 +46,8 @@ func Configurations() *Configs {
 	config.LoadConfig(corralConfigConfigurationFileKey, &corralConfigs)
 	return &corralConfigs
 }
+
+func init() {
+	config.Register(corralPackageConfigConfigurationFileKey, Packages{})
+	config.Register(corralConfigConfigurationFileKey, Configs{})
+}
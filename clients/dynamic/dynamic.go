@@ -19,7 +19,8 @@ import (
 // The session.Session attributes is passed all way down to the ResourceClient to keep track of the resources created by the dynamic client
 type Client struct {
 	dynamic.Interface
-	ts *session.Session
+	ts        *session.Session
+	clusterID string
 }
 
 // NewForConfig creates a new dynamic client or returns an error.
@@ -34,6 +35,7 @@ func NewForConfig(ts *session.Session, inConfig *rest.Config) (dynamic.Interface
 	return &Client{
 		Interface: dynamicClient,
 		ts:        ts,
+		clusterID: session.ClusterIDFromURL(inConfig.Host),
 	}, nil
 }
 
@@ -48,6 +50,8 @@ func (d *Client) Resource(resource schema.GroupVersionResource) dynamic.Namespac
 	return &NamespaceableResourceClient{
 		NamespaceableResourceInterface: d.Interface.Resource(resource),
 		ts:                             d.ts,
+		resource:                       resource,
+		clusterID:                      d.clusterID,
 	}
 }
 
@@ -55,7 +59,9 @@ func (d *Client) Resource(resource schema.GroupVersionResource) dynamic.Namespac
 // This is inorder to overwrite dynamic.NamespaceableResourceInterface's Namespace function.
 type NamespaceableResourceClient struct {
 	dynamic.NamespaceableResourceInterface
-	ts *session.Session
+	ts        *session.Session
+	resource  schema.GroupVersionResource
+	clusterID string
 }
 
 // Namespace returns a dynamic.ResourceInterface that is embedded in ResourceClient, so ultimately its Create is overwritten.
@@ -63,18 +69,22 @@ func (d *NamespaceableResourceClient) Namespace(s string) dynamic.ResourceInterf
 	return &ResourceClient{
 		ResourceInterface: d.NamespaceableResourceInterface.Namespace(s),
 		ts:                d.ts,
+		resource:          d.resource,
+		clusterID:         d.clusterID,
 	}
 }
 
 // ResourceClient has dynamic.ResourceInterface embedded so dynamic.ResourceInterface's Create can be overwritten.
 type ResourceClient struct {
 	dynamic.ResourceInterface
-	ts *session.Session
+	ts        *session.Session
+	resource  schema.GroupVersionResource
+	clusterID string
 }
 
 var (
 	// some GVKs are special and cannot be cleaned up because they do not exist
-	// after being created (eg: SelfSubjectAccessReview). We'll not register
+	// after being created (eg: SelfSubjectAccessReview, Eviction). We'll not register
 	// cleanup functions when creating objects of these kinds.
 	noCleanupGVKs = []schema.GroupVersionKind{
 		{
@@ -82,6 +92,11 @@ var (
 			Version: "v1",
 			Kind:    "SelfSubjectAccessReview",
 		},
+		{
+			Group:   "policy",
+			Version: "v1",
+			Kind:    "Eviction",
+		},
 	}
 )
 
@@ -96,26 +111,43 @@ func needsCleanup(obj *unstructured.Unstructured) bool {
 
 // Create is dynamic.ResourceInterface's Create function, that is being overwritten to register its delete function to the session.Session
 // that is being reference.
+// The run labels of the session are added to the object, see session.RunLabels.
 func (c *ResourceClient) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if needsCleanup(obj) {
+		obj = obj.DeepCopy()
+		c.ts.StampRunLabels(obj)
+	}
+
 	unstructuredObj, err := c.ResourceInterface.Create(ctx, obj, opts, subresources...)
 	if err != nil {
 		return nil, err
 	}
 
 	if needsCleanup(obj) {
-		c.ts.RegisterCleanupFunc(func() error {
-			err := c.Delete(context.TODO(), unstructuredObj.GetName(), metav1.DeleteOptions{}, subresources...)
-			if errors.IsNotFound(err) {
-				return nil
-			}
-
-			name := unstructuredObj.GetName()
-			if unstructuredObj.GetNamespace() != "" {
-				name = unstructuredObj.GetNamespace() + "/" + name
-			}
-			gvk := unstructuredObj.GetObjectKind().GroupVersionKind()
-
-			return fmt.Errorf("unable to delete (%v) %v: %w", gvk, name, err)
+		gvk := unstructuredObj.GetObjectKind().GroupVersionKind()
+		c.ts.RegisterCleanupEntry(session.CleanupEntry{
+			Resource: &session.Resource{
+				Group:     gvk.Group,
+				Version:   gvk.Version,
+				Kind:      gvk.Kind,
+				Resource:  c.resource.Resource,
+				Namespace: unstructuredObj.GetNamespace(),
+				Name:      unstructuredObj.GetName(),
+				ClusterID: c.clusterID,
+			},
+			Cleanup: func() error {
+				err := c.Delete(context.TODO(), unstructuredObj.GetName(), metav1.DeleteOptions{}, subresources...)
+				if err == nil || errors.IsNotFound(err) {
+					return nil
+				}
+
+				name := unstructuredObj.GetName()
+				if unstructuredObj.GetNamespace() != "" {
+					name = unstructuredObj.GetNamespace() + "/" + name
+				}
+
+				return fmt.Errorf("unable to delete (%v) %v: %w", gvk, name, err)
+			},
 		})
 	}
 
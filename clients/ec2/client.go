@@ -3,13 +3,16 @@ package ec2
 import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/rancher/shepherd/extensions/cloudcredentials"
 	"github.com/rancher/shepherd/pkg/config"
+	testsession "github.com/rancher/shepherd/pkg/session"
 )
 
 // Client is a struct that wraps the needed AWSEC2Config object, and ec2.EC2 which makes the actual calls to aws.
+// The instances and volumes created by SVC are tagged with the run labels, see session.RunLabels.
 type Client struct {
 	SVC          *ec2.EC2
 	ClientConfig *AWSEC2Configs
@@ -32,6 +35,7 @@ func NewClient() (*Client, error) {
 	}
 
 	svc := ec2.New(sess)
+	svc.Handlers.Build.PushFront(tagRunResources)
 	return &Client{
 		SVC:          svc,
 		ClientConfig: awsEC2ClientConfig,
@@ -50,6 +54,7 @@ func NewClientFromConfig(awsCredentials *cloudcredentials.AmazonEC2CredentialCon
 	}
 
 	svc := ec2.New(ec2Session)
+	svc.Handlers.Build.PushFront(tagRunResources)
 	return &Client{
 		SVC: svc,
 		ClientConfig: &AWSEC2Configs{
@@ -59,3 +64,39 @@ func NewClientFromConfig(awsCredentials *cloudcredentials.AmazonEC2CredentialCon
 		},
 	}, nil
 }
+
+// tagRunResources adds the run labels as tags of the instances and volumes created by a RunInstances request.
+func tagRunResources(r *request.Request) {
+	input, ok := r.Params.(*ec2.RunInstancesInput)
+	if !ok {
+		return
+	}
+
+	for _, resourceType := range []string{ec2.ResourceTypeInstance, ec2.ResourceTypeVolume} {
+		var specification *ec2.TagSpecification
+		for _, s := range input.TagSpecifications {
+			if aws.StringValue(s.ResourceType) == resourceType {
+				specification = s
+			}
+		}
+		if specification == nil {
+			specification = &ec2.TagSpecification{ResourceType: aws.String(resourceType)}
+			input.TagSpecifications = append(input.TagSpecifications, specification)
+		}
+
+		for key, value := range testsession.RunLabels() {
+			if !hasTag(specification.Tags, key) {
+				specification.Tags = append(specification.Tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+			}
+		}
+	}
+}
+
+func hasTag(tags []*ec2.Tag, key string) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return true
+		}
+	}
+	return false
+}
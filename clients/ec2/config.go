@@ -1,5 +1,7 @@
 package ec2
 
+import "github.com/rancher/shepherd/pkg/config"
+
 // The json/yaml config key for the AWSEC2onfig
 const ConfigurationFileKey = "awsEC2Configs"
 
@@ -25,3 +27,7 @@ type AWSEC2Config struct {
 	VolumeSize         int      `json:"volumeSize" yaml:"volumeSize"`
 	Roles              []string `json:"roles" yaml:"roles"`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, AWSEC2Configs{})
+}
@@ -1,5 +1,7 @@
 package harvester
 
+import "github.com/rancher/shepherd/pkg/config"
+
 // The json/yaml config key for the harvester config
 const ConfigurationFileKey = "harvester"
 
@@ -11,3 +13,7 @@ type Config struct {
 	Insecure      *bool  `yaml:"insecure" json:"insecure" default:"true"`
 	Cleanup       *bool  `yaml:"cleanup" json:"cleanup" default:"true"`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
@@ -1,5 +1,7 @@
 package k3d
 
+import "github.com/rancher/shepherd/pkg/config"
+
 // The json/yaml config key for the k3d config
 const ConfigurationFileKey = "k3d"
 
@@ -7,3 +9,7 @@ const ConfigurationFileKey = "k3d"
 type Config struct {
 	createTimeout int `yaml:"createTimeout" default:"120s"`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
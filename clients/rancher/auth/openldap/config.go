@@ -1,5 +1,7 @@
 package openldap
 
+import "github.com/rancher/shepherd/pkg/config"
+
 const (
 	ConfigurationFileKey = "openLDAP"
 )
@@ -42,3 +44,7 @@ type User struct {
 	Password string `json:"password,omitempty" yaml:"password,omitempty"`
 	Username string `json:"username,omitempty" yaml:"username,omitempty"`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
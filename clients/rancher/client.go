@@ -26,6 +26,7 @@ import (
 	"github.com/rancher/shepherd/pkg/clientbase"
 	"github.com/rancher/shepherd/pkg/config"
 	"github.com/rancher/shepherd/pkg/environmentflag"
+	"github.com/rancher/shepherd/pkg/recorder"
 	"github.com/rancher/shepherd/pkg/session"
 	"github.com/rancher/shepherd/pkg/wrangler"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -59,11 +60,18 @@ type Client struct {
 	Flags      *environmentflag.EnvironmentFlags
 	restConfig *rest.Config
 	UserID     string
+	ctx        context.Context
 }
 
 // NewClient is the constructor to the initializing a rancher Client. It takes a bearer token and session.Session. If bearer token is not provided,
 // the bearer token provided in the configuration file is used.
 func NewClient(bearerToken string, session *session.Session) (*Client, error) {
+	return NewClientWithContext(context.Background(), bearerToken, session)
+}
+
+// NewClientWithContext is the constructor initializing a rancher Client whose requests are bound to ctx, e.g. the context cancelled by
+// the killserver.KillServer. Clients derived from the returned Client, with AsUser or WithSession for instance, inherit ctx.
+func NewClientWithContext(ctx context.Context, bearerToken string, session *session.Session) (*Client, error) {
 	rancherConfig := new(Config)
 	config.LoadConfig(ConfigurationFileKey, rancherConfig)
 
@@ -77,6 +85,7 @@ func NewClient(bearerToken string, session *session.Session) (*Client, error) {
 	c := &Client{
 		RancherConfig: rancherConfig,
 		Flags:         &environmentFlags,
+		ctx:           ctx,
 	}
 
 	return newClient(c, bearerToken, rancherConfig, session)
@@ -84,6 +93,10 @@ func NewClient(bearerToken string, session *session.Session) (*Client, error) {
 
 // NewClientForConfig is the constructor for initializing a rancher Client for the given config and session.
 func NewClientForConfig(bearerToken string, rancherConfig *Config, session *session.Session) (*Client, error) {
+	return newClientForConfig(context.Background(), bearerToken, rancherConfig, session)
+}
+
+func newClientForConfig(ctx context.Context, bearerToken string, rancherConfig *Config, session *session.Session) (*Client, error) {
 	environmentFlags := environmentflag.NewEnvironmentFlags()
 	environmentflag.LoadEnvironmentFlags(environmentflag.ConfigurationFileKey, environmentFlags)
 
@@ -94,6 +107,7 @@ func NewClientForConfig(bearerToken string, rancherConfig *Config, session *sess
 	c := &Client{
 		RancherConfig: rancherConfig,
 		Flags:         &environmentFlags,
+		ctx:           ctx,
 	}
 
 	return newClient(c, bearerToken, rancherConfig, session)
@@ -109,14 +123,47 @@ func newClient(c *Client, bearerToken string, config *Config, session *session.S
 	c.restConfig = restConfig
 	c.Session = session
 
-	c.Management, err = management.NewClient(clientOpts(restConfig, c.RancherConfig))
+	var rec *recorder.Recorder
+	if config.Recorder != nil && config.Recorder.Cassette != "" {
+		rec, err = recorder.Shared(config.Recorder.Cassette, config.Recorder.Mode)
+		if err != nil {
+			return nil, err
+		}
+
+		// the cassette is closed once the session has cleaned up, and the deletion of the resources is recorded
+		if session != nil {
+			session.RegisterCloseFunc(rec.Close)
+		}
+
+		restConfig.WrapTransport = rec.WrapTransport
+	}
+
+	var retryPolicy *clientbase.RetryPolicy
+	if config.Retry != nil {
+		retryPolicy, err = config.Retry.RetryPolicy()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	managementOpts := clientOpts(restConfig, c.RancherConfig)
+	managementOpts.Context = c.ctx
+	managementOpts.RetryPolicy = retryPolicy
+	installRecorder(managementOpts, rec)
+
+	c.Management, err = management.NewClient(managementOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	c.Management.Ops.Session = session
 
-	c.Steve, err = v1.NewClient(clientOptsV1(restConfig, c.RancherConfig))
+	steveOpts := clientOptsV1(restConfig, c.RancherConfig)
+	steveOpts.Context = c.ctx
+	steveOpts.RetryPolicy = retryPolicy
+	installRecorder(steveOpts, rec)
+
+	c.Steve, err = v1.NewClient(steveOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +184,7 @@ func newClient(c *Client, bearerToken string, config *Config, session *session.S
 
 	c.Catalog = catalogClient
 
-	wranglerContext, err := wrangler.NewContext(context.TODO(), restConfig, session)
+	wranglerContext, err := wrangler.NewContext(c.ctx, restConfig, session)
 	if err != nil {
 		return nil, err
 	}
@@ -207,10 +254,20 @@ func clientOptsV1(restConfig *rest.Config, rancherConfig *Config) *clientbase.Cl
 	}
 }
 
+// installRecorder records or replays the HTTP and websocket traffic of the client configured by opts.
+func installRecorder(opts *clientbase.ClientOpts, rec *recorder.Recorder) {
+	if rec == nil {
+		return
+	}
+
+	opts.WrapTransport = rec.WrapTransport
+	opts.WSDialer = rec.WrapDialer(opts.WSDialer)
+}
+
 // doAction is used to post an action to an endpoint, and marshal the response into the output parameter.
 func (c *Client) doAction(endpoint, action string, body []byte, output interface{}) error {
 	url := "https://" + c.restConfig.Host + endpoint + "?action=" + action
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(c.Context(), "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
@@ -252,7 +309,7 @@ func (c *Client) AsUser(user *management.User) (*Client, error) {
 		return nil, err
 	}
 
-	return NewClient(returnedToken.Token, c.Session)
+	return NewClientWithContext(c.Context(), returnedToken.Token, c.Session)
 }
 
 // AsPublicAPIUser accepts a v3 user object, and then creates a token for said `user`. Then it instantiates and returns a Client using the token created.
@@ -262,7 +319,7 @@ func (c *Client) AsPublicAPIUser(user *v3.User, password string) (*Client, error
 		return nil, err
 	}
 
-	return NewClient(returnedToken.Token, c.Session)
+	return NewClientWithContext(c.Context(), returnedToken.Token, c.Session)
 }
 
 // AsAuthUser accepts a user object, and then creates a token for said `user`. Then it instantiates and returns a Client using the token created.
@@ -273,31 +330,51 @@ func (c *Client) AsAuthUser(user *management.User, authProvider auth.Provider) (
 		return nil, err
 	}
 
-	return NewClientForConfig(returnedToken.Token, c.RancherConfig, c.Session)
+	return newClientForConfig(c.Context(), returnedToken.Token, c.RancherConfig, c.Session)
 }
 
 // ReLogin reinstantiates a Client to update its API schema. This function would be used for a non admin user that needs to be
 // "reloaded" inorder to have updated permissions for certain resources.
 func (c *Client) ReLogin() (*Client, error) {
-	return NewClient(c.restConfig.BearerToken, c.Session)
+	return NewClientWithContext(c.Context(), c.restConfig.BearerToken, c.Session)
 }
 
 // ReLoginForconfig reinstantiates a Client to update its API schema with the same Config. This function would be used for a non admin user that needs to be
 // "reloaded" inorder to have updated permissions for certain resources.
 func (c *Client) ReLoginForConfig(rancherConfig *Config) (*Client, error) {
-	return NewClientForConfig(c.restConfig.BearerToken, rancherConfig, c.Session)
+	return newClientForConfig(c.Context(), c.restConfig.BearerToken, rancherConfig, c.Session)
 }
 
 // WithSession accepts a session.Session and instantiates a new Client to reference this new session.Session. The main purpose is to use it
 // when created "sub sessions" when tracking resources created at a test case scope.
 func (c *Client) WithSession(session *session.Session) (*Client, error) {
-	return NewClient(c.restConfig.BearerToken, session)
+	return NewClientWithContext(c.Context(), c.restConfig.BearerToken, session)
 }
 
 // WithSessionForConfig accepts a Config and a session.Session and instantiates a new Client to reference this new session.Session. The main purpose is to use it
 // when created "sub sessions" when tracking resources created at a test case scope.
 func (c *Client) WithSessionForConfig(rancherConfig *Config, session *session.Session) (*Client, error) {
-	return NewClientForConfig(c.restConfig.BearerToken, rancherConfig, session)
+	return newClientForConfig(c.Context(), c.restConfig.BearerToken, rancherConfig, session)
+}
+
+// WithContext returns a copy of the Client whose Management and Steve requests are bound to ctx, as are the clients
+// derived from it.
+func (c *Client) WithContext(ctx context.Context) (*Client, error) {
+	client := *c
+	client.ctx = ctx
+	client.Management = c.Management.WithContext(ctx)
+	client.Steve = c.Steve.WithContext(ctx)
+
+	return &client, nil
+}
+
+// Context returns the root context of the Client's requests.
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
 }
 
 // GetClusterCatalogClient is a function that takes a clusterID and instantiates a catalog client to directly communicate with that specific cluster.
@@ -402,7 +479,7 @@ func (c *Client) GetManagementWatchInterface(schemaType string, opts metav1.List
 		return nil, err
 	}
 
-	return dynamicClient.Resource(groupVersionResource).Watch(context.TODO(), opts)
+	return dynamicClient.Resource(groupVersionResource).Watch(c.Context(), opts)
 }
 
 // loginWithCredentials uses the local authentication provider to authenticate a user and return the token.
@@ -468,7 +545,7 @@ func (c *Client) ping(httpClient *http.Client) (bool, error) {
 	url := "https://" + c.restConfig.Host + "/ping"
 	pong := "pong"
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(c.Context(), "GET", url, nil)
 	if err != nil {
 		return false, err
 	}
@@ -1,18 +1,77 @@
 package rancher
 
+import (
+	"time"
+
+	"github.com/rancher/shepherd/pkg/clientbase"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/recorder"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
 // The json/yaml config key for the rancher config
 const ConfigurationFileKey = "rancher"
 
 // Config is configuration need to test against a rancher instance
 type Config struct {
-	Host          string `yaml:"host" json:"host"`
-	AdminToken    string `yaml:"adminToken" json:"adminToken"`
-	AdminPassword string `yaml:"adminPassword" json:"adminPassword"`
-	Insecure      *bool  `yaml:"insecure" json:"insecure" default:"true"`
-	Cleanup       *bool  `yaml:"cleanup" json:"cleanup" default:"true"`
-	CAFile        string `yaml:"caFile" json:"caFile" default:""`
-	CACerts       string `yaml:"caCerts" json:"caCerts" default:""`
-	ClusterName   string `yaml:"clusterName" json:"clusterName" default:""`
-	ShellImage    string `yaml:"shellImage" json:"shellImage" default:""`
-	RancherCLI    bool   `yaml:"rancherCLI" json:"rancherCLI" default:"false"`
+	Host          string          `yaml:"host" json:"host"`
+	AdminToken    string          `yaml:"adminToken" json:"adminToken"`
+	AdminPassword string          `yaml:"adminPassword" json:"adminPassword"`
+	Insecure      *bool           `yaml:"insecure" json:"insecure" default:"true"`
+	Cleanup       *bool           `yaml:"cleanup" json:"cleanup" default:"true"`
+	CAFile        string          `yaml:"caFile" json:"caFile" default:""`
+	CACerts       string          `yaml:"caCerts" json:"caCerts" default:""`
+	ClusterName   string          `yaml:"clusterName" json:"clusterName" default:""`
+	ShellImage    string          `yaml:"shellImage" json:"shellImage" default:""`
+	RancherCLI    bool            `yaml:"rancherCLI" json:"rancherCLI" default:"false"`
+	Retry         *RetryConfig    `yaml:"retry" json:"retry"`
+	Recorder      *RecorderConfig `yaml:"recorder" json:"recorder"`
+}
+
+// RecorderConfig is the configuration of the recorder.Recorder installed on the Norman, Steve and kube API clients.
+// In record mode all the traffic is written to the cassette, in replay mode it is answered from the cassette and
+// no request reaches Rancher.
+type RecorderConfig struct {
+	Cassette string        `yaml:"cassette" json:"cassette" validate:"required"`
+	Mode     recorder.Mode `yaml:"mode" json:"mode" default:"replay" validate:"oneof=record replay"`
+}
+
+// RetryConfig is the configuration of the retries of the Management and Steve clients requests failing with
+// transient errors, only idempotent requests are retried.
+type RetryConfig struct {
+	Attempts          int      `yaml:"attempts" json:"attempts" default:"5" validate:"min=1"`
+	Interval          string   `yaml:"interval" json:"interval" default:"500ms"`
+	Factor            *float64 `yaml:"factor" json:"factor" default:"2"`
+	StatusCodes       []int    `yaml:"statusCodes" json:"statusCodes"`
+	ConnectionErrors  *bool    `yaml:"connectionErrors" json:"connectionErrors" default:"true"`
+	RefetchOnConflict *bool    `yaml:"refetchOnConflict" json:"refetchOnConflict" default:"true"`
+}
+
+// RetryPolicy converts the RetryConfig to the clientbase.RetryPolicy of the client options.
+func (r *RetryConfig) RetryPolicy() (*clientbase.RetryPolicy, error) {
+	interval, err := time.ParseDuration(r.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// the fields left unset, when the config is not loaded with its defaults, take their default values
+	factor := 2.0
+	if r.Factor != nil {
+		factor = *r.Factor
+	}
+
+	return &clientbase.RetryPolicy{
+		Backoff: wait.Backoff{
+			Duration: interval,
+			Factor:   factor,
+			Steps:    r.Attempts,
+		},
+		StatusCodes:           r.StatusCodes,
+		RetryConnectionErrors: r.ConnectionErrors == nil || *r.ConnectionErrors,
+		RefetchOnConflict:     r.RefetchOnConflict == nil || *r.RefetchOnConflict,
+	}, nil
+}
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
 }
@@ -0,0 +1,539 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	provisioningv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/shepherd/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/schemes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/watch"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// objectKey identifies an object of the kube store.
+type objectKey struct {
+	resource  schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// kubeStore is an in memory kube apiserver storage for every type known to the wrangler scheme.
+type kubeStore struct {
+	lock            sync.RWMutex
+	objects         map[objectKey]*unstructured.Unstructured
+	resourceVersion int
+	watchers        map[schema.GroupVersionResource][]chan watch.Event
+	history         []storeEvent
+
+	kinds      map[schema.GroupVersionResource]schema.GroupVersionKind
+	namespaced map[schema.GroupVersionResource]bool
+}
+
+// maxHistory is the number of events kept by the kube store to replay them to watches starting from an older
+// resource version.
+const maxHistory = 1000
+
+// storeEvent is an event of the kube store history.
+type storeEvent struct {
+	resource        schema.GroupVersionResource
+	resourceVersion int
+	event           watch.Event
+}
+
+// scheme holds the types of the kube store: the types of the wrangler scheme, and the provisioning, RKE and CAPI types
+// Rancher serves for the RKE2 and K3s clusters.
+var scheme = runtime.NewScheme()
+
+func init() {
+	metav1.AddToGroupVersion(scheme, schema.GroupVersion{Version: "v1"})
+	utilruntime.Must(wrangler.AddToScheme(scheme))
+	utilruntime.Must(schemes.AddToScheme(scheme))
+	utilruntime.Must(provisioningv1.AddToScheme(scheme))
+	utilruntime.Must(rkev1.AddToScheme(scheme))
+	utilruntime.Must(capi.AddToScheme(scheme))
+}
+
+// clusterScopedKinds are the kinds of the scheme that are not namespaced.
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"APIService":                     true,
+	"StorageClass":                   true,
+	"PriorityClass":                  true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+}
+
+// namespacedManagementKinds are the management.cattle.io kinds that are namespaced, the others are cluster scoped as
+// are all the ext.cattle.io kinds.
+var namespacedManagementKinds = map[string]bool{
+	"CloudCredential":            true,
+	"ClusterProxyConfig":         true,
+	"ClusterRegistrationToken":   true,
+	"ClusterRoleTemplateBinding": true,
+	"ClusterTemplate":            true,
+	"ClusterTemplateRevision":    true,
+	"EtcdBackup":                 true,
+	"ManagedChart":               true,
+	"Node":                       true,
+	"NodePool":                   true,
+	"NodeTemplate":               true,
+	"Preference":                 true,
+	"Project":                    true,
+	"ProjectNetworkPolicy":       true,
+	"ProjectRoleTemplateBinding": true,
+	"RkeAddon":                   true,
+	"RkeK8sServiceOption":        true,
+	"RkeK8sSystemImage":          true,
+	"SamlToken":                  true,
+}
+
+func newKubeStore() *kubeStore {
+	store := &kubeStore{
+		objects:    map[objectKey]*unstructured.Unstructured{},
+		watchers:   map[schema.GroupVersionResource][]chan watch.Event{},
+		kinds:      map[schema.GroupVersionResource]schema.GroupVersionKind{},
+		namespaced: map[schema.GroupVersionResource]bool{},
+	}
+
+	for gvk := range scheme.AllKnownTypes() {
+		if strings.HasSuffix(gvk.Kind, "List") || gvk.Version == "__internal" {
+			continue
+		}
+
+		resource, _ := meta.UnsafeGuessKindToResource(gvk)
+		store.kinds[resource] = gvk
+		switch gvk.Group {
+		case "management.cattle.io":
+			store.namespaced[resource] = namespacedManagementKinds[gvk.Kind]
+		case "ext.cattle.io":
+			store.namespaced[resource] = false
+		default:
+			store.namespaced[resource] = !clusterScopedKinds[gvk.Kind]
+		}
+	}
+
+	return store
+}
+
+// statusError writes err as a metav1.Status, the way the kube apiserver reports errors.
+func statusError(w http.ResponseWriter, err error) {
+	status := apierrors.NewInternalError(err).ErrStatus
+	if apiStatus, ok := err.(apierrors.APIStatus); ok {
+		status = apiStatus.Status()
+	}
+	status.Kind = "Status"
+	status.APIVersion = "v1"
+
+	writeJSON(w, int(status.Code), status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// serveKube serves the /api and /apis paths of the kube apiserver.
+func (s *kubeStore) serveKube(w http.ResponseWriter, r *http.Request) {
+	var group, version string
+	var rest []string
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case segments[0] == "api" && len(segments) >= 2:
+		version, rest = segments[1], segments[2:]
+	case segments[0] == "apis" && len(segments) >= 3:
+		group, version, rest = segments[1], segments[2], segments[3:]
+	default:
+		statusError(w, apierrors.NewNotFound(schema.GroupResource{}, r.URL.Path))
+		return
+	}
+
+	var namespace, resource, name string
+	if len(rest) >= 3 && rest[0] == "namespaces" {
+		namespace, rest = rest[1], rest[2:]
+	}
+	if len(rest) == 0 {
+		statusError(w, apierrors.NewNotFound(schema.GroupResource{}, r.URL.Path))
+		return
+	}
+	resource = rest[0]
+	if len(rest) > 1 {
+		name = rest[1]
+	}
+
+	gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+	if _, ok := s.kinds[gvr]; !ok {
+		statusError(w, apierrors.NewNotFound(gvr.GroupResource(), name))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && name == "" && r.URL.Query().Get("watch") == "true":
+		s.serveWatch(w, r, gvr, namespace)
+	case r.Method == http.MethodGet && name == "":
+		writeJSON(w, http.StatusOK, s.list(gvr, namespace))
+	case r.Method == http.MethodGet:
+		obj, err := s.get(gvr, namespace, name)
+		respond(w, http.StatusOK, obj, err)
+	case r.Method == http.MethodPost:
+		obj, err := decodeObject(r.Body)
+		if err == nil {
+			obj, err = s.create(gvr, namespace, obj)
+		}
+		respond(w, http.StatusCreated, obj, err)
+	case r.Method == http.MethodPut:
+		obj, err := decodeObject(r.Body)
+		if err == nil {
+			obj, err = s.update(gvr, namespace, name, obj)
+		}
+		respond(w, http.StatusOK, obj, err)
+	case r.Method == http.MethodPatch:
+		patch, err := io.ReadAll(r.Body)
+		var obj *unstructured.Unstructured
+		if err == nil {
+			obj, err = s.patch(gvr, namespace, name, types.PatchType(r.Header.Get("Content-Type")), patch)
+		}
+		respond(w, http.StatusOK, obj, err)
+	case r.Method == http.MethodDelete:
+		obj, err := s.delete(gvr, namespace, name)
+		respond(w, http.StatusOK, obj, err)
+	default:
+		statusError(w, apierrors.NewMethodNotSupported(gvr.GroupResource(), r.Method))
+	}
+}
+
+func respond(w http.ResponseWriter, code int, obj *unstructured.Unstructured, err error) {
+	if err != nil {
+		statusError(w, err)
+		return
+	}
+
+	writeJSON(w, code, obj.Object)
+}
+
+func decodeObject(body io.Reader) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	err := json.NewDecoder(body).Decode(&obj.Object)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	return obj, nil
+}
+
+func (s *kubeStore) key(gvr schema.GroupVersionResource, namespace, name string) objectKey {
+	if !s.namespaced[gvr] {
+		namespace = ""
+	}
+
+	return objectKey{resource: gvr, namespace: namespace, name: name}
+}
+
+// list returns the objects of gvr in namespace, or in all namespaces if namespace is empty.
+func (s *kubeStore) list(gvr schema.GroupVersionResource, namespace string) map[string]any {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	items := []any{}
+	for _, obj := range s.sortedObjects(gvr, namespace) {
+		items = append(items, obj.Object)
+	}
+
+	gvk := s.kinds[gvr]
+	return map[string]any{
+		"apiVersion": gvk.GroupVersion().String(),
+		"kind":       gvk.Kind + "List",
+		"metadata": map[string]any{
+			"resourceVersion": strconv.Itoa(s.resourceVersion),
+		},
+		"items": items,
+	}
+}
+
+// sortedObjects returns the objects of gvr in namespace sorted by namespace and name, the lock must be held.
+func (s *kubeStore) sortedObjects(gvr schema.GroupVersionResource, namespace string) []*unstructured.Unstructured {
+	var objects []*unstructured.Unstructured
+	for key, obj := range s.objects {
+		if key.resource == gvr && (namespace == "" || key.namespace == namespace) {
+			objects = append(objects, obj.DeepCopy())
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].GetNamespace() != objects[j].GetNamespace() {
+			return objects[i].GetNamespace() < objects[j].GetNamespace()
+		}
+		return objects[i].GetName() < objects[j].GetName()
+	})
+
+	return objects
+}
+
+func (s *kubeStore) get(gvr schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	obj, ok := s.objects[s.key(gvr, namespace, name)]
+	if !ok {
+		return nil, apierrors.NewNotFound(gvr.GroupResource(), name)
+	}
+
+	return obj.DeepCopy(), nil
+}
+
+func (s *kubeStore) create(gvr schema.GroupVersionResource, namespace string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if obj.GetName() == "" && obj.GetGenerateName() != "" {
+		name, err := namegenerator.AppendRandomString(strings.TrimSuffix(obj.GetGenerateName(), "-"))
+		if err != nil {
+			return nil, apierrors.NewInternalError(err)
+		}
+		obj.SetName(name)
+	}
+	if obj.GetName() == "" {
+		return nil, apierrors.NewBadRequest("name or generateName is required")
+	}
+
+	if s.namespaced[gvr] {
+		if namespace == "" {
+			namespace = obj.GetNamespace()
+		}
+		obj.SetNamespace(namespace)
+	}
+
+	key := s.key(gvr, obj.GetNamespace(), obj.GetName())
+	if _, ok := s.objects[key]; ok {
+		return nil, apierrors.NewAlreadyExists(gvr.GroupResource(), obj.GetName())
+	}
+
+	gvk := s.kinds[gvr]
+	obj.SetAPIVersion(gvk.GroupVersion().String())
+	obj.SetKind(gvk.Kind)
+	obj.SetUID(uuid.NewUUID())
+	obj.SetCreationTimestamp(metav1.NewTime(time.Now()))
+	obj.SetGeneration(1)
+	s.store(key, obj, watch.Added)
+
+	return obj.DeepCopy(), nil
+}
+
+func (s *kubeStore) update(gvr schema.GroupVersionResource, namespace, name string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := s.key(gvr, namespace, name)
+	existing, ok := s.objects[key]
+	if !ok {
+		return nil, apierrors.NewNotFound(gvr.GroupResource(), name)
+	}
+
+	if obj.GetResourceVersion() != "" && obj.GetResourceVersion() != existing.GetResourceVersion() {
+		return nil, apierrors.NewConflict(gvr.GroupResource(), name, fmt.Errorf("the object has been modified"))
+	}
+
+	obj.SetNamespace(existing.GetNamespace())
+	obj.SetUID(existing.GetUID())
+	obj.SetCreationTimestamp(existing.GetCreationTimestamp())
+	obj.SetAPIVersion(existing.GetAPIVersion())
+	obj.SetKind(existing.GetKind())
+	// as the apiserver, only the changes of the spec make a new generation
+	obj.SetGeneration(existing.GetGeneration())
+	if !reflect.DeepEqual(obj.Object["spec"], existing.Object["spec"]) {
+		obj.SetGeneration(existing.GetGeneration() + 1)
+	}
+	s.store(key, obj, watch.Modified)
+
+	return obj.DeepCopy(), nil
+}
+
+func (s *kubeStore) patch(gvr schema.GroupVersionResource, namespace, name string, patchType types.PatchType, patch []byte) (*unstructured.Unstructured, error) {
+	existing, err := s.get(gvr, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(existing.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch patchType {
+	case types.JSONPatchType:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = operations.Apply(original)
+		}
+	default:
+		// strategic merge patches are applied as merge patches, which is enough for the objects tests create
+		patched, err = jsonpatch.MergePatch(original, patch)
+	}
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	obj := &unstructured.Unstructured{}
+	err = json.Unmarshal(patched, &obj.Object)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	obj.SetResourceVersion("")
+
+	return s.update(gvr, namespace, name, obj)
+}
+
+func (s *kubeStore) delete(gvr schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := s.key(gvr, namespace, name)
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, apierrors.NewNotFound(gvr.GroupResource(), name)
+	}
+
+	delete(s.objects, key)
+	s.resourceVersion++
+	obj.SetResourceVersion(strconv.Itoa(s.resourceVersion))
+	s.notify(gvr, watch.Deleted, obj)
+
+	return obj.DeepCopy(), nil
+}
+
+// store saves obj with a new resource version and notifies the watchers, the lock must be held.
+func (s *kubeStore) store(key objectKey, obj *unstructured.Unstructured, eventType watch.EventType) {
+	s.resourceVersion++
+	obj.SetResourceVersion(strconv.Itoa(s.resourceVersion))
+	s.objects[key] = obj.DeepCopy()
+	s.notify(key.resource, eventType, obj)
+}
+
+// notify records an event in the history and sends it to the watchers of gvr, the lock must be held.
+func (s *kubeStore) notify(gvr schema.GroupVersionResource, eventType watch.EventType, obj *unstructured.Unstructured) {
+	s.history = append(s.history, storeEvent{
+		resource:        gvr,
+		resourceVersion: s.resourceVersion,
+		event:           watch.Event{Type: eventType, Object: obj.DeepCopy()},
+	})
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+
+	watchers := s.watchers[gvr][:0]
+	for _, watcher := range s.watchers[gvr] {
+		select {
+		case watcher <- watch.Event{Type: eventType, Object: obj.DeepCopy()}:
+			watchers = append(watchers, watcher)
+		default:
+			// a watcher that does not keep up is closed rather than missing events, the informers relist when their
+			// watch is closed
+			close(watcher)
+		}
+	}
+	s.watchers[gvr] = watchers
+}
+
+// addWatcher returns a channel receiving the events of gvr, until it is removed with removeWatcher or closed because
+// its buffer is full. If resourceVersion is set the events of the history past it are replayed first.
+func (s *kubeStore) addWatcher(gvr schema.GroupVersionResource, resourceVersion string) chan watch.Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var backlog []watch.Event
+	if since, err := strconv.Atoi(resourceVersion); err == nil {
+		for _, event := range s.history {
+			if event.resource == gvr && event.resourceVersion > since {
+				backlog = append(backlog, watch.Event{Type: event.event.Type, Object: event.event.Object.DeepCopyObject()})
+			}
+		}
+	}
+
+	events := make(chan watch.Event, len(backlog)+100)
+	for _, event := range backlog {
+		events <- event
+	}
+
+	s.watchers[gvr] = append(s.watchers[gvr], events)
+	return events
+}
+
+func (s *kubeStore) removeWatcher(gvr schema.GroupVersionResource, events chan watch.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	watchers := s.watchers[gvr]
+	for i, watcher := range watchers {
+		if watcher == events {
+			s.watchers[gvr] = append(watchers[:i], watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// serveWatch streams the events of gvr in namespace until the request is cancelled.
+func (s *kubeStore) serveWatch(w http.ResponseWriter, r *http.Request, gvr schema.GroupVersionResource, namespace string) {
+	events := s.addWatcher(gvr, r.URL.Query().Get("resourceVersion"))
+	defer s.removeWatcher(gvr, events)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			obj := event.Object.(*unstructured.Unstructured)
+			if namespace != "" && obj.GetNamespace() != namespace {
+				continue
+			}
+
+			encoder.Encode(map[string]any{
+				"type":   event.Type,
+				"object": obj.Object,
+			})
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/shepherd/pkg/namegenerator"
+	schema "github.com/rancher/shepherd/pkg/schemas/management.cattle.io/v3"
+)
+
+const tokenSecretLength = 54
+
+// normanStore is an in memory store of the Norman /v3 API resources, served with the management schemas.
+type normanStore struct {
+	lock      sync.RWMutex
+	schemas   map[string]*types.Schema
+	plurals   map[string]*types.Schema
+	resources map[string]map[string]map[string]any
+}
+
+func newNormanStore() *normanStore {
+	store := &normanStore{
+		schemas:   map[string]*types.Schema{},
+		plurals:   map[string]*types.Schema{},
+		resources: map[string]map[string]map[string]any{},
+	}
+
+	for id, s := range schema.Schemas.SchemasForVersion(schema.Version) {
+		store.schemas[id] = s
+		store.plurals[s.PluralName] = s
+		store.resources[id] = map[string]map[string]any{}
+	}
+
+	return store
+}
+
+// normanError writes a Norman API error.
+func normanError(w http.ResponseWriter, code int, errorCode, message string) {
+	writeJSON(w, code, map[string]any{
+		"type":    "error",
+		"status":  code,
+		"code":    errorCode,
+		"message": message,
+	})
+}
+
+// serveRoot serves /v3, pointing the client to the schemas.
+func (s *normanStore) serveRoot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-API-Schemas", baseURL(r)+"/v3/schemas")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type": "apiRoot",
+		"links": map[string]string{
+			"self":    baseURL(r) + "/v3",
+			"schemas": baseURL(r) + "/v3/schemas",
+		},
+	})
+}
+
+// serveSchemas serves /v3/schemas, with the links of the schemas pointing to this server.
+func (s *normanStore) serveSchemas(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	for id := range s.schemas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data := []types.Schema{}
+	for _, id := range ids {
+		schema := *s.schemas[id]
+		schema.Links = map[string]string{
+			"self": baseURL(r) + "/v3/schemas/" + id,
+		}
+		if len(schema.CollectionMethods) > 0 {
+			schema.Links["collection"] = baseURL(r) + "/v3/" + schema.PluralName
+		}
+		data = append(data, schema)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type":         "collection",
+		"resourceType": "schema",
+		"data":         data,
+	})
+}
+
+// serveResources serves the /v3/<pluralName>[/<id>] collections and resources.
+func (s *normanStore) serveResources(w http.ResponseWriter, r *http.Request) {
+	segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v3/"), "/", 2)
+	schema, ok := s.plurals[segments[0]]
+	if !ok {
+		normanError(w, http.StatusNotFound, "NotFound", "unknown collection "+segments[0])
+		return
+	}
+
+	var id string
+	if len(segments) > 1 {
+		id = segments[1]
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		s.list(w, r, schema)
+	case r.Method == http.MethodGet:
+		s.respond(w, http.StatusOK, schema, id)
+	case r.Method == http.MethodPost && id == "":
+		resource, err := decodeResource(r)
+		if err != nil {
+			normanError(w, http.StatusUnprocessableEntity, "InvalidBodyContent", err.Error())
+			return
+		}
+		id, err = s.create(r, schema, resource)
+		if err != nil {
+			normanError(w, http.StatusInternalServerError, "ServerError", err.Error())
+			return
+		}
+		s.respond(w, http.StatusCreated, schema, id)
+	case r.Method == http.MethodPost && r.URL.Query().Get("action") != "":
+		// actions are acknowledged without side effects, tests relying on them seed the expected state themselves
+		s.respond(w, http.StatusOK, schema, id)
+	case r.Method == http.MethodPut:
+		updates, err := decodeResource(r)
+		if err != nil {
+			normanError(w, http.StatusUnprocessableEntity, "InvalidBodyContent", err.Error())
+			return
+		}
+		s.update(schema, id, updates)
+		s.respond(w, http.StatusOK, schema, id)
+	case r.Method == http.MethodDelete:
+		s.respond(w, http.StatusOK, schema, id)
+		s.lock.Lock()
+		delete(s.resources[schema.ID], id)
+		s.lock.Unlock()
+	default:
+		normanError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not allowed")
+	}
+}
+
+func decodeResource(r *http.Request) (map[string]any, error) {
+	resource := map[string]any{}
+	err := json.NewDecoder(r.Body).Decode(&resource)
+	return resource, err
+}
+
+func (s *normanStore) respond(w http.ResponseWriter, code int, schema *types.Schema, id string) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	resource, ok := s.resources[schema.ID][id]
+	if !ok {
+		normanError(w, http.StatusNotFound, "NotFound", schema.ID+" "+id+" not found")
+		return
+	}
+
+	writeJSON(w, code, resource)
+}
+
+// list writes the resources of schema matching the field filters of the query.
+func (s *normanStore) list(w http.ResponseWriter, r *http.Request, schema *types.Schema) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var ids []string
+	for id := range s.resources[schema.ID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data := []any{}
+	for _, id := range ids {
+		resource := s.resources[schema.ID][id]
+		if matchesFilters(resource, r.URL.Query()) {
+			data = append(data, resource)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type":         "collection",
+		"resourceType": schema.ID,
+		"links": map[string]string{
+			"self": baseURL(r) + "/v3/" + schema.PluralName,
+		},
+		"data": data,
+	})
+}
+
+func matchesFilters(resource map[string]any, filters map[string][]string) bool {
+	for field, values := range filters {
+		if field == "limit" || field == "marker" || field == "sort" || field == "order" {
+			continue
+		}
+
+		value, ok := resource[field]
+		if !ok {
+			return false
+		}
+
+		if values[0] != toString(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toString(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// create stores resource with a generated id, unless it has one, and the Norman type, links and actions.
+func (s *normanStore) create(r *http.Request, schema *types.Schema, resource map[string]any) (string, error) {
+	id, _ := resource["id"].(string)
+	if id == "" {
+		var err error
+		id, err = namegenerator.AppendRandomString(strings.ToLower(schema.ID))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// created tokens get a secret and can authenticate further requests, as they would against Rancher
+	if schema.ID == "token" {
+		resource["token"] = id + ":" + namegenerator.RandStringLower(tokenSecretLength)
+	}
+
+	s.add(baseURL(r), schema, id, resource)
+	return id, nil
+}
+
+// add stores resource under id with the Norman type, links and actions.
+func (s *normanStore) add(baseURL string, schema *types.Schema, id string, resource map[string]any) {
+	selfURL := baseURL + "/v3/" + schema.PluralName + "/" + id
+
+	actions := map[string]string{}
+	for action := range schema.ResourceActions {
+		actions[action] = selfURL + "?action=" + action
+	}
+
+	resource["id"] = id
+	resource["type"] = schema.ID
+	resource["baseType"] = schema.ID
+	resource["links"] = map[string]string{
+		"self":   selfURL,
+		"update": selfURL,
+		"remove": selfURL,
+	}
+	resource["actions"] = actions
+	if _, ok := resource["state"]; !ok {
+		resource["state"] = "active"
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.resources[schema.ID][id] = resource
+}
+
+// update merges updates into the resource, keeping its id, type, links and actions.
+func (s *normanStore) update(schema *types.Schema, id string, updates map[string]any) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	resource, ok := s.resources[schema.ID][id]
+	if !ok {
+		return
+	}
+
+	for key, value := range updates {
+		switch key {
+		case "id", "type", "baseType", "links", "actions":
+		default:
+			resource[key] = value
+		}
+	}
+}
+
+// authenticated returns whether bearerToken is the <id>:<secret> of a token of the store.
+func (s *normanStore) authenticated(bearerToken string) bool {
+	id, _, ok := strings.Cut(bearerToken, ":")
+	if !ok {
+		return false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	token, ok := s.resources["token"][id]
+	return ok && token["token"] == bearerToken
+}
@@ -0,0 +1,192 @@
+package fakeserver
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	setFilterRegexp = regexp.MustCompile(`^([^ ]+) (IN|NOTIN) \((.*)\)$`)
+	filterRegexp    = regexp.MustCompile(`^([^=!~<>]+)(=|!=|~|!~|<|>)(.*)$`)
+	fieldRegexp     = regexp.MustCompile(`([^.\[\]]+)(?:\[([^\]]+)\])?`)
+)
+
+// steveQuery is the subset of the Steve SQL cache list parameters supported by the fake server: filters, sorting and
+// pagination by page number.
+type steveQuery struct {
+	filters  [][]string
+	sort     []string
+	page     int
+	pageSize int
+}
+
+func parseSteveQuery(values url.Values) (*steveQuery, error) {
+	query := &steveQuery{
+		page: 1,
+	}
+
+	for _, filter := range values["filter"] {
+		query.filters = append(query.filters, strings.Split(filter, ","))
+	}
+	if sort := values.Get("sort"); sort != "" {
+		query.sort = strings.Split(sort, ",")
+	}
+
+	var err error
+	if pageSize := values.Get("pagesize"); pageSize != "" {
+		query.pageSize, err = strconv.Atoi(pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pagesize %q", pageSize)
+		}
+	}
+	if page := values.Get("page"); page != "" {
+		query.page, err = strconv.Atoi(page)
+		if err != nil || query.page < 1 {
+			return nil, fmt.Errorf("invalid page %q", page)
+		}
+	}
+
+	// set filters contain commas, they are joined back together
+	for i, filters := range query.filters {
+		var joined []string
+		for _, filter := range filters {
+			if n := len(joined); n > 0 && strings.Count(joined[n-1], "(") > strings.Count(joined[n-1], ")") {
+				joined[n-1] += "," + filter
+				continue
+			}
+			joined = append(joined, filter)
+		}
+		query.filters[i] = joined
+	}
+
+	return query, nil
+}
+
+// apply filters, sorts and paginates objects. It returns the page and the number of pages.
+func (q *steveQuery) apply(objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, int, error) {
+	var matching []*unstructured.Unstructured
+	for _, obj := range objects {
+		matches, err := q.matches(obj)
+		if err != nil {
+			return nil, 0, err
+		}
+		if matches {
+			matching = append(matching, obj)
+		}
+	}
+
+	if len(q.sort) > 0 {
+		sort.SliceStable(matching, func(i, j int) bool {
+			for _, field := range q.sort {
+				descending := strings.HasPrefix(field, "-")
+				a, b := fieldValue(matching[i], strings.TrimPrefix(field, "-")), fieldValue(matching[j], strings.TrimPrefix(field, "-"))
+				if a != b {
+					return (a < b) != descending
+				}
+			}
+			return false
+		})
+	}
+
+	if q.pageSize == 0 {
+		return matching, 1, nil
+	}
+
+	pages := (len(matching) + q.pageSize - 1) / q.pageSize
+	start := min((q.page-1)*q.pageSize, len(matching))
+	end := min(start+q.pageSize, len(matching))
+
+	return matching[start:end], pages, nil
+}
+
+// matches returns whether obj matches every filter parameter, each matching if any of its filters matches.
+func (q *steveQuery) matches(obj *unstructured.Unstructured) (bool, error) {
+	for _, filters := range q.filters {
+		matched := false
+		for _, filter := range filters {
+			matches, err := matchesFilter(obj, filter)
+			if err != nil {
+				return false, err
+			}
+			matched = matched || matches
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchesFilter(obj *unstructured.Unstructured, filter string) (bool, error) {
+	if match := setFilterRegexp.FindStringSubmatch(filter); match != nil {
+		value := fieldValue(obj, match[1])
+		in := false
+		for _, candidate := range strings.Split(match[3], ",") {
+			in = in || value == candidate
+		}
+		return in == (match[2] == "IN"), nil
+	}
+
+	match := filterRegexp.FindStringSubmatch(filter)
+	if match == nil {
+		return false, fmt.Errorf("invalid filter %q", filter)
+	}
+
+	value := fieldValue(obj, match[1])
+	switch match[2] {
+	case "=":
+		return value == match[3], nil
+	case "!=":
+		return value != match[3], nil
+	case "~":
+		return strings.Contains(value, match[3]), nil
+	case "!~":
+		return !strings.Contains(value, match[3]), nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return false, nil
+	}
+	bound, err := strconv.Atoi(match[3])
+	if err != nil {
+		return false, fmt.Errorf("invalid number in filter %q", filter)
+	}
+	if match[2] == "<" {
+		return number < bound, nil
+	}
+	return number > bound, nil
+}
+
+// fieldValue returns the value of the field of obj at path, e.g. metadata.labels[app], as a string.
+func fieldValue(obj *unstructured.Unstructured, path string) string {
+	var current any = obj.Object
+	for _, part := range fieldRegexp.FindAllStringSubmatch(path, -1) {
+		fields, ok := current.(map[string]any)
+		if !ok {
+			return ""
+		}
+		current = fields[part[1]]
+
+		if part[2] != "" {
+			fields, ok := current.(map[string]any)
+			if !ok {
+				return ""
+			}
+			current = fields[part[2]]
+		}
+	}
+
+	if current == nil {
+		return ""
+	}
+
+	return fmt.Sprint(current)
+}
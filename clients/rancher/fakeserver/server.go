@@ -0,0 +1,203 @@
+// Package fakeserver provides an in process fake of the Rancher API, so that extensions can be tested with
+// rancher.NewClient in CI without a Rancher or a cluster. It serves the Norman /v3 API from the management schemas,
+// the Steve /v1 API with its subscribe websocket and a kube apiserver for every type of the wrangler scheme and the
+// provisioning, RKE and CAPI types, all backed by in memory stores.
+// The kube apiserver and Steve are also served under /k8s/clusters/<clusterID> and share the same store, so the
+// downstream clients work against the "local" cluster.
+package fakeserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/namegenerator"
+	schema "github.com/rancher/shepherd/pkg/schemas/management.cattle.io/v3"
+	"github.com/rancher/shepherd/pkg/session"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// AdminUserID is the ID of the admin user the admin token belongs to.
+	AdminUserID = "user-admin"
+	// AdminTokenID is the ID of the admin token.
+	AdminTokenID = "token-admin"
+	// LocalClusterID is the ID of the local cluster.
+	LocalClusterID = "local"
+)
+
+var downstreamPrefixRegexp = regexp.MustCompile(`^/k8s/clusters/[^/]+`)
+
+// Server is a fake Rancher API server listening on a local TLS port.
+type Server struct {
+	server *httptest.Server
+	kube   *kubeStore
+	norman *normanStore
+}
+
+// New starts a Server seeded with the admin user, its token and the local cluster. It must be closed once done. It
+// panics if the Server cannot be seeded.
+func New() *Server {
+	s := &Server{
+		kube:   newKubeStore(),
+		norman: newNormanStore(),
+	}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+
+	s.AddNormanResource("user", AdminUserID, map[string]any{
+		"username": "admin",
+		"name":     "Default Admin",
+		"enabled":  true,
+	})
+	s.AddNormanResource("token", AdminTokenID, map[string]any{
+		"userId": AdminUserID,
+		"token":  AdminTokenID + ":" + namegenerator.RandStringLower(tokenSecretLength),
+	})
+	s.AddNormanResource("cluster", LocalClusterID, map[string]any{
+		"name":     LocalClusterID,
+		"internal": true,
+	})
+
+	err := s.AddObjects(
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]any{"name": "default"},
+		}},
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "management.cattle.io/v3",
+			"kind":       "Cluster",
+			"metadata":   map[string]any{"name": LocalClusterID},
+		}},
+	)
+	if err != nil {
+		// the seeded objects are known to the wrangler scheme, failing to add them is a bug of the Server
+		panic(fmt.Sprintf("failed to seed the fake server: %v", err))
+	}
+
+	return s
+}
+
+// Close shuts the Server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Host returns the host:port the Server listens on, as configured in rancher.Config.Host.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.server.URL, "https://")
+}
+
+// AdminToken returns the bearer token of the admin user.
+func (s *Server) AdminToken() string {
+	s.norman.lock.RLock()
+	defer s.norman.lock.RUnlock()
+
+	return s.norman.resources["token"][AdminTokenID]["token"].(string)
+}
+
+// RancherConfig returns a rancher.Config pointing to the Server with the admin token.
+func (s *Server) RancherConfig() *rancher.Config {
+	insecure := true
+	cleanup := true
+
+	return &rancher.Config{
+		Host:       s.Host(),
+		AdminToken: s.AdminToken(),
+		Insecure:   &insecure,
+		Cleanup:    &cleanup,
+	}
+}
+
+// WriteConfig writes the RancherConfig of the Server to the configuration file CATTLE_TEST_CONFIG points to, so that
+// the clients reloading their configuration, e.g. with rancher.Client.ReLogin, reach the Server.
+func (s *Server) WriteConfig() error {
+	return config.WriteConfig(rancher.ConfigurationFileKey, s.RancherConfig())
+}
+
+// NewClient returns an admin rancher.Client of the Server, registering its cleanups with ts.
+func (s *Server) NewClient(ts *session.Session) (*rancher.Client, error) {
+	return rancher.NewClientForConfig("", s.RancherConfig(), ts)
+}
+
+// AddObjects adds objs to the kube store, objs must be types of the wrangler scheme, provisioning, RKE or CAPI types,
+// or unstructured objects.
+func (s *Server) AddObjects(objs ...runtime.Object) error {
+	for _, obj := range objs {
+		gvks, _, err := scheme.ObjectKinds(obj)
+		if err != nil {
+			return err
+		}
+
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+
+		resource, _ := meta.UnsafeGuessKindToResource(gvks[0])
+		object := &unstructured.Unstructured{Object: data}
+		_, err = s.kube.create(resource, object.GetNamespace(), object)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddNormanResource adds resource to the Norman store with the given schema type and id.
+func (s *Server) AddNormanResource(schemaType, id string, resource map[string]any) {
+	s.norman.add(s.server.URL, schema.Schemas.Schema(&schema.Version, schemaType), id, resource)
+}
+
+func baseURL(r *http.Request) string {
+	return "https://" + r.Host
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ping" {
+		w.Write([]byte("pong"))
+		return
+	}
+
+	bearerToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !s.norman.authenticated(bearerToken) {
+		normanError(w, http.StatusUnauthorized, "Unauthorized", "must authenticate")
+		return
+	}
+
+	path := downstreamPrefixRegexp.ReplaceAllString(r.URL.Path, "")
+	if path != r.URL.Path {
+		// downstream requests are served from the same stores, under the downstream URLs
+		r = r.Clone(r.Context())
+		r.Host = r.Host + downstreamPrefixRegexp.FindString(r.URL.Path)
+		r.URL.Path = path
+	}
+
+	switch {
+	case path == "/v3":
+		s.norman.serveRoot(w, r)
+	case path == "/v3/schemas" || strings.HasPrefix(path, "/v3/schemas/"):
+		s.norman.serveSchemas(w, r)
+	case strings.HasPrefix(path, "/v3/"):
+		s.norman.serveResources(w, r)
+	case path == "/v1":
+		s.kube.serveSteveRoot(w, r)
+	case path == "/v1/schemas" || strings.HasPrefix(path, "/v1/schemas/"):
+		s.kube.serveSteveSchemas(w, r)
+	case path == "/v1/subscribe":
+		s.kube.serveSteveSubscribe(w, r)
+	case strings.HasPrefix(path, "/v1/"):
+		s.kube.serveSteve(w, r)
+	case strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/apis/"):
+		s.kube.serveKube(w, r)
+	default:
+		normanError(w, http.StatusNotFound, "NotFound", "unknown path "+r.URL.Path)
+	}
+}
@@ -0,0 +1,143 @@
+package fakeserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rancher/shepherd/pkg/session"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func Test_NewClient(t *testing.T) {
+	server := New()
+	defer server.Close()
+
+	ts := session.NewSession()
+	client, err := server.NewClient(ts)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if client.UserID != AdminUserID {
+		t.Errorf("UserID = %q, want %q", client.UserID, AdminUserID)
+	}
+
+	_, err = client.WranglerContext.Core.Namespace().Create(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "fake"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	namespaces, err := client.Steve.SteveType("namespace").List(nil)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if names := namespaces.Names(); len(names) != 2 || names[0] != "default" || names[1] != "fake" {
+		t.Errorf("namespaces = %v, want [default fake]", names)
+	}
+
+	cluster, err := client.Management.Cluster.ByID(LocalClusterID)
+	if err != nil {
+		t.Fatalf("ByID() error = %v", err)
+	}
+	if cluster.Name != LocalClusterID {
+		t.Errorf("cluster name = %q, want %q", cluster.Name, LocalClusterID)
+	}
+
+	ts.Cleanup()
+
+	_, err = client.WranglerContext.Core.Namespace().Get("fake", metav1.GetOptions{})
+	if err == nil {
+		t.Error("Get() of a cleaned up namespace error = nil, want NotFound")
+	}
+}
+
+func Test_KubeStoreScope(t *testing.T) {
+	store := newKubeStore()
+
+	tests := []struct {
+		resource   schema.GroupVersionResource
+		namespaced bool
+	}{
+		{resource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, namespaced: true},
+		{resource: schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}},
+		{resource: schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "projects"}, namespaced: true},
+		{resource: schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "projectroletemplatebindings"}, namespaced: true},
+		{resource: schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "clusters"}},
+		{resource: schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "globalroles"}},
+		{resource: schema.GroupVersionResource{Group: "ext.cattle.io", Version: "v1", Resource: "tokens"}},
+	}
+
+	for _, tt := range tests {
+		if _, ok := store.kinds[tt.resource]; !ok {
+			t.Errorf("%s is not known to the store", tt.resource)
+			continue
+		}
+		if store.namespaced[tt.resource] != tt.namespaced {
+			t.Errorf("%s namespaced = %t, want %t", tt.resource, store.namespaced[tt.resource], tt.namespaced)
+		}
+	}
+}
+
+func Test_KubeStoreSlowWatcher(t *testing.T) {
+	store := newKubeStore()
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	events := store.addWatcher(gvr, "")
+
+	store.lock.Lock()
+	for i := 0; i <= cap(events); i++ {
+		obj := &unstructured.Unstructured{}
+		obj.SetName(fmt.Sprintf("configmap-%d", i))
+		store.store(objectKey{resource: gvr, namespace: "default", name: obj.GetName()}, obj, watch.Added)
+	}
+	store.lock.Unlock()
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != cap(events) {
+		t.Errorf("watcher received %d events before being closed, want %d", received, cap(events))
+	}
+
+	// removing a watcher closed by the store is a no-op
+	store.removeWatcher(gvr, events)
+}
+
+func Test_KubeStoreGeneration(t *testing.T) {
+	store := newKubeStore()
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	obj := &unstructured.Unstructured{Object: map[string]any{"data": map[string]any{"key": "value"}}}
+	obj.SetName("generation")
+	created, err := store.create(gvr, "default", obj)
+	if err != nil {
+		t.Fatalf("create() error = %v", err)
+	}
+	if created.GetGeneration() != 1 {
+		t.Errorf("generation of the created object = %d, want 1", created.GetGeneration())
+	}
+
+	created.SetLabels(map[string]string{"updated": "true"})
+	updated, err := store.update(gvr, "default", "generation", created)
+	if err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+	if updated.GetGeneration() != 1 {
+		t.Errorf("generation after updating the metadata = %d, want 1", updated.GetGeneration())
+	}
+
+	updated.Object["spec"] = map[string]any{"key": "value"}
+	updated, err = store.update(gvr, "default", "generation", updated)
+	if err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+	if updated.GetGeneration() != 2 {
+		t.Errorf("generation after updating the spec = %d, want 2", updated.GetGeneration())
+	}
+}
@@ -0,0 +1,388 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/wrangler/v3/pkg/summary"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// steveTypes maps the Steve type of every kind of the kube store to its preferred version resource.
+func (s *kubeStore) steveTypes() map[string]schema.GroupVersionResource {
+	steveTypes := map[string]schema.GroupVersionResource{}
+	for gvr, gvk := range s.kinds {
+		steveType := strings.ToLower(gvk.Kind)
+		if gvk.Group != "" {
+			steveType = gvk.Group + "." + steveType
+		}
+
+		existing, ok := steveTypes[steveType]
+		if !ok || version.CompareKubeAwareVersionStrings(gvr.Version, existing.Version) > 0 {
+			steveTypes[steveType] = gvr
+		}
+	}
+
+	return steveTypes
+}
+
+// serveSteveRoot serves /v1, pointing the client to the schemas.
+func (s *kubeStore) serveSteveRoot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-API-Schemas", baseURL(r)+"/v1/schemas")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type": "apiRoot",
+		"links": map[string]string{
+			"self":    baseURL(r) + "/v1",
+			"schemas": baseURL(r) + "/v1/schemas",
+		},
+	})
+}
+
+// serveSteveSchemas serves /v1/schemas, with a schema for every Steve type.
+func (s *kubeStore) serveSteveSchemas(w http.ResponseWriter, r *http.Request) {
+	steveTypes := s.steveTypes()
+
+	var ids []string
+	for id := range steveTypes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data := []any{}
+	for _, id := range ids {
+		gvr := steveTypes[id]
+		data = append(data, map[string]any{
+			"id":   id,
+			"type": "schema",
+			"links": map[string]string{
+				"self":       baseURL(r) + "/v1/schemas/" + id,
+				"collection": baseURL(r) + "/v1/" + id,
+			},
+			"pluralName":        id + "s",
+			"resourceMethods":   []string{http.MethodGet, http.MethodDelete, http.MethodPut, http.MethodPatch},
+			"collectionMethods": []string{http.MethodGet, http.MethodPost},
+			"attributes": map[string]any{
+				"group":      gvr.Group,
+				"version":    gvr.Version,
+				"kind":       s.kinds[gvr].Kind,
+				"resource":   gvr.Resource,
+				"namespaced": s.namespaced[gvr],
+			},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type":         "collection",
+		"resourceType": "schema",
+		"data":         data,
+	})
+}
+
+// serveSteve serves the /v1/<type>[/<namespace>][/<name>] collections and objects, backed by the kube store.
+func (s *kubeStore) serveSteve(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"), "/")
+	steveType := segments[0]
+	gvr, ok := s.steveTypes()[steveType]
+	if !ok {
+		normanError(w, http.StatusNotFound, "NotFound", "unknown type "+steveType)
+		return
+	}
+
+	var namespace, name string
+	switch {
+	case len(segments) == 3:
+		namespace, name = segments[1], segments[2]
+	case len(segments) == 2 && s.namespaced[gvr] && r.Method != http.MethodGet && r.Method != http.MethodPost:
+		normanError(w, http.StatusNotFound, "NotFound", "missing name of "+steveType)
+		return
+	case len(segments) == 2 && s.namespaced[gvr]:
+		namespace = segments[1]
+	case len(segments) == 2:
+		name = segments[1]
+	}
+
+	var obj *unstructured.Unstructured
+	var err error
+	code := http.StatusOK
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		s.serveSteveList(w, r, steveType, gvr, namespace)
+		return
+	case r.Method == http.MethodGet:
+		obj, err = s.get(gvr, namespace, name)
+	case r.Method == http.MethodPost && name == "":
+		obj, err = decodeObject(r.Body)
+		if err == nil {
+			obj, err = s.create(gvr, namespace, steveObject(obj))
+		}
+		code = http.StatusCreated
+	case r.Method == http.MethodPut:
+		obj, err = decodeObject(r.Body)
+		if err == nil {
+			obj, err = s.update(gvr, namespace, name, steveObject(obj))
+		}
+	case r.Method == http.MethodDelete:
+		obj, err = s.delete(gvr, namespace, name)
+	default:
+		err = apierrors.NewMethodNotSupported(gvr.GroupResource(), r.Method)
+	}
+
+	if err != nil {
+		steveError(w, err)
+		return
+	}
+
+	writeJSON(w, code, toSteve(baseURL(r), steveType, obj))
+}
+
+func (s *kubeStore) serveSteveList(w http.ResponseWriter, r *http.Request, steveType string, gvr schema.GroupVersionResource, namespace string) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		normanError(w, http.StatusBadRequest, "InvalidFormat", err.Error())
+		return
+	}
+	query, err := parseSteveQuery(r.URL.Query())
+	if err != nil {
+		normanError(w, http.StatusBadRequest, "InvalidFormat", err.Error())
+		return
+	}
+
+	s.lock.RLock()
+	objects := s.sortedObjects(gvr, namespace)
+	revision := strconv.Itoa(s.resourceVersion)
+	s.lock.RUnlock()
+
+	var selected []*unstructured.Unstructured
+	for _, obj := range objects {
+		if selector.Matches(labels.Set(obj.GetLabels())) {
+			selected = append(selected, obj)
+		}
+	}
+
+	page, pages, err := query.apply(selected)
+	if err != nil {
+		normanError(w, http.StatusBadRequest, "InvalidFormat", err.Error())
+		return
+	}
+
+	data := []any{}
+	for _, obj := range page {
+		data = append(data, toSteve(baseURL(r), steveType, obj))
+	}
+
+	collection := map[string]any{
+		"type":         "collection",
+		"resourceType": steveType,
+		"links": map[string]string{
+			"self": baseURL(r) + r.URL.Path,
+		},
+		"revision": revision,
+		"data":     data,
+	}
+	if query.pageSize > 0 {
+		collection["pages"] = pages
+		collection["count"] = len(selected)
+	}
+
+	writeJSON(w, http.StatusOK, collection)
+}
+
+// steveError writes err the way Steve reports kube apiserver errors.
+func steveError(w http.ResponseWriter, err error) {
+	status := apierrors.NewInternalError(err).ErrStatus
+	if apiStatus, ok := err.(apierrors.APIStatus); ok {
+		status = apiStatus.Status()
+	}
+
+	normanError(w, int(status.Code), string(status.Reason), status.Message)
+}
+
+// steveObject strips the Steve fields from obj before it is stored.
+func steveObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	for _, field := range []string{"id", "type", "links", "actions"} {
+		delete(obj.Object, field)
+	}
+
+	return obj
+}
+
+// toSteve adds the Steve id, type and links to obj, and its metadata.state summarized from its status as Steve does,
+// unless the state was stored with obj.
+func toSteve(baseURL, steveType string, obj *unstructured.Unstructured) map[string]any {
+	id := obj.GetName()
+	if obj.GetNamespace() != "" {
+		id = obj.GetNamespace() + "/" + id
+	}
+	selfURL := baseURL + "/v1/" + steveType + "/" + id
+
+	object := obj.DeepCopy().Object
+	if _, ok, _ := unstructured.NestedFieldNoCopy(object, "metadata", "state"); !ok {
+		summarized := summary.Summarize(obj)
+		_ = unstructured.SetNestedField(object, map[string]any{
+			"name":          summarized.State,
+			"error":         summarized.Error,
+			"transitioning": summarized.Transitioning,
+			"message":       strings.Join(summarized.Message, "; "),
+		}, "metadata", "state")
+	}
+	object["id"] = id
+	object["type"] = steveType
+	object["links"] = map[string]string{
+		"self":   selfURL,
+		"update": selfURL,
+		"remove": selfURL,
+		"view":   selfURL,
+	}
+
+	return object
+}
+
+// subscribeRequest is a subscription sent on the Steve subscribe websocket.
+type subscribeRequest struct {
+	Stop            bool   `json:"stop"`
+	ResourceType    string `json:"resourceType"`
+	ResourceVersion string `json:"resourceVersion"`
+	Namespace       string `json:"namespace"`
+	ID              string `json:"id"`
+	Selector        string `json:"selector"`
+}
+
+var subscribeEventNames = map[watch.EventType]string{
+	watch.Added:    "resource.create",
+	watch.Modified: "resource.change",
+	watch.Deleted:  "resource.remove",
+}
+
+// serveSteveSubscribe serves the /v1/subscribe websocket, streaming the changes of the subscribed Steve types from the
+// resource version of the subscriptions.
+func (s *kubeStore) serveSteveSubscribe(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var writeLock sync.Mutex
+	write := func(event map[string]any) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+
+		return conn.WriteJSON(event)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	stops := map[subscribeRequest]chan struct{}{}
+	for {
+		var request subscribeRequest
+		err := conn.ReadJSON(&request)
+		if err != nil {
+			return
+		}
+
+		// subscriptions are identified without their resource version, as Steve does
+		resourceVersion := request.ResourceVersion
+		request.ResourceVersion = ""
+
+		if request.Stop {
+			request.Stop = false
+			if stop, ok := stops[request]; ok {
+				close(stop)
+				delete(stops, request)
+			}
+			continue
+		}
+
+		gvr, ok := s.steveTypes()[request.ResourceType]
+		if !ok {
+			write(map[string]any{
+				"name":         "resource.error",
+				"resourceType": request.ResourceType,
+				"data":         map[string]any{"error": "failed to find schema " + request.ResourceType},
+			})
+			continue
+		}
+
+		selector, err := labels.Parse(request.Selector)
+		if err != nil {
+			write(map[string]any{
+				"name":         "resource.error",
+				"resourceType": request.ResourceType,
+				"data":         map[string]any{"error": err.Error()},
+			})
+			continue
+		}
+
+		stop := make(chan struct{})
+		stops[request] = stop
+		go s.subscribe(baseURL(r), request, resourceVersion, gvr, selector, write, stop, done)
+	}
+}
+
+// subscribe sends the events of gvr matching request with write, until stop or done is closed.
+func (s *kubeStore) subscribe(baseURL string, request subscribeRequest, resourceVersion string, gvr schema.GroupVersionResource, selector labels.Selector, write func(map[string]any) error, stop, done chan struct{}) {
+	events := s.addWatcher(gvr, resourceVersion)
+	defer s.removeWatcher(gvr, events)
+
+	err := write(map[string]any{
+		"name":         "resource.start",
+		"resourceType": request.ResourceType,
+	})
+	if err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-stop:
+			write(map[string]any{
+				"name":         "resource.stop",
+				"resourceType": request.ResourceType,
+			})
+			return
+		case <-done:
+			return
+		case event, ok := <-events:
+			if !ok {
+				write(map[string]any{
+					"name":         "resource.stop",
+					"resourceType": request.ResourceType,
+				})
+				return
+			}
+
+			obj := event.Object.(*unstructured.Unstructured)
+			steveObject := toSteve(baseURL, request.ResourceType, obj)
+
+			if request.Namespace != "" && obj.GetNamespace() != request.Namespace ||
+				request.ID != "" && steveObject["id"] != request.ID ||
+				!selector.Matches(labels.Set(obj.GetLabels())) {
+				continue
+			}
+
+			data, _ := json.Marshal(steveObject)
+			err := write(map[string]any{
+				"name":         subscribeEventNames[event.Type],
+				"resourceType": request.ResourceType,
+				"revision":     obj.GetResourceVersion(),
+				"data":         json.RawMessage(data),
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+}
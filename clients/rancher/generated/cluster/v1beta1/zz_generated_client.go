@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+
 	"github.com/rancher/shepherd/pkg/clientbase"
 )
 
@@ -16,11 +18,24 @@ func NewClient(opts *clientbase.ClientOpts) (*Client, error) {
 		return nil, err
 	}
 
+	return newClient(baseClient), nil
+}
+
+// WithContext returns a copy of the Client whose requests are bound to ctx, so they are cancelled along with it.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	baseClient := c.APIBaseClient
+	baseClient.Ops = c.Ops.WithContext(ctx)
+	baseClient.Opts = baseClient.Ops.Opts
+
+	return newClient(baseClient)
+}
+
+func newClient(baseClient clientbase.APIBaseClient) *Client {
 	client := &Client{
 		APIBaseClient: baseClient,
 	}
 
 	client.Machine = newMachineClient(client)
 
-	return client, nil
+	return client
 }
@@ -0,0 +1,205 @@
+// Package steve provides strongly typed clients on top of the Steve API clients of clients/rancher/v1.
+package steve
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Object is a typed object returned by a TypedClient, along with the Steve only data of the object.
+type Object[T runtime.Object] struct {
+	// Object is the kubernetes object.
+	Object T
+	// ID is the Steve ID of the object, <namespace>/<name> for namespaced objects and <name> otherwise.
+	ID            string
+	State         *v1.State
+	Relationships []v1.Relationship
+	Fields        []any
+
+	// steveObject is the object as returned by Steve, its links are used to update and delete the object.
+	steveObject *v1.SteveAPIObject
+}
+
+// SteveObject returns the untyped Steve object the Object was converted from.
+func (o *Object[T]) SteveObject() *v1.SteveAPIObject {
+	return o.steveObject
+}
+
+// TypedClient is a client of a Steve type whose objects are converted to T, the native kubernetes type of the
+// Steve type, e.g. *corev1.Namespace for the "namespace" type.
+//
+//	namespaces := steve.NewTypedClient[*corev1.Namespace](client.Steve, "namespace")
+//	namespace, err := namespaces.Get("default")
+//	namespace.Object.Status.Phase
+type TypedClient[T runtime.Object] struct {
+	apiClient *v1.Client
+	steveType string
+	namespace string
+}
+
+// NewTypedClient returns a TypedClient of steveType using apiClient.
+func NewTypedClient[T runtime.Object](apiClient *v1.Client, steveType string) *TypedClient[T] {
+	return &TypedClient[T]{
+		apiClient: apiClient,
+		steveType: steveType,
+	}
+}
+
+// Namespaced returns a copy of the TypedClient whose List and Watch are restricted to namespace.
+func (c *TypedClient[T]) Namespaced(namespace string) *TypedClient[T] {
+	client := *c
+	client.namespace = namespace
+
+	return &client
+}
+
+// WithContext returns a copy of the TypedClient whose requests are bound to ctx.
+func (c *TypedClient[T]) WithContext(ctx context.Context) *TypedClient[T] {
+	client := *c
+	client.apiClient = c.apiClient.WithContext(ctx)
+
+	return &client
+}
+
+// ProxyDownstream returns a copy of the TypedClient making its requests to the Steve API of the downstream cluster
+// clusterID.
+func (c *TypedClient[T]) ProxyDownstream(clusterID string) (*TypedClient[T], error) {
+	apiClient, err := c.apiClient.ProxyDownstream(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	client := *c
+	client.apiClient = apiClient
+
+	return &client, nil
+}
+
+func (c *TypedClient[T]) steveClient() *v1.SteveClient {
+	return c.apiClient.SteveType(c.steveType)
+}
+
+// Create creates obj, its cleanup is registered with the session of the client.
+func (c *TypedClient[T]) Create(obj T) (*Object[T], error) {
+	created, err := c.steveClient().Create(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return toObject[T](created)
+}
+
+// Get returns the object of the given Steve ID.
+func (c *TypedClient[T]) Get(id string) (*Object[T], error) {
+	steveObject, err := c.steveClient().ByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toObject[T](steveObject)
+}
+
+// List returns all the objects matching query, going through every page of the collection.
+func (c *TypedClient[T]) List(query url.Values) ([]*Object[T], error) {
+	var collection *v1.SteveCollection
+	var err error
+	if c.namespace != "" {
+		collection, err = c.steveClient().NamespacedSteveClient(c.namespace).ListAll(query)
+	} else {
+		collection, err = c.steveClient().ListAll(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*Object[T], 0, len(collection.Data))
+	for i := range collection.Data {
+		object, err := toObject[T](&collection.Data[i])
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// Update replaces existing with its Object, which must have been modified from an object returned by the client.
+func (c *TypedClient[T]) Update(existing *Object[T]) (*Object[T], error) {
+	updated, err := c.steveClient().Update(existing.steveObject, existing.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return toObject[T](updated)
+}
+
+// Delete deletes obj.
+func (c *TypedClient[T]) Delete(obj *Object[T]) error {
+	return c.steveClient().Delete(obj.steveObject)
+}
+
+// Watch returns a watch.Interface of the objects, whose events carry T objects, see v1.SteveClient.Watch.
+func (c *TypedClient[T]) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	var watcher watch.Interface
+	var err error
+	if c.namespace != "" {
+		watcher, err = c.steveClient().NamespacedSteveClient(c.namespace).Watch(opts)
+	} else {
+		watcher, err = c.steveClient().Watch(opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(watcher, func(event watch.Event) (watch.Event, bool) {
+		steveObject, ok := event.Object.(*v1.SteveAPIObject)
+		if !ok {
+			return event, true
+		}
+
+		object, err := toObject[T](steveObject)
+		if err != nil {
+			logrus.Errorf("failed to convert watched %s %s: %v", c.steveType, steveObject.ID, err)
+			return event, false
+		}
+
+		event.Object = object.Object
+		return event, true
+	}), nil
+}
+
+// toObject converts steveObject to an Object of type T.
+func toObject[T runtime.Object](steveObject *v1.SteveAPIObject) (*Object[T], error) {
+	obj := newObject[T]()
+	err := v1.ConvertToK8sType(steveObject.JSONResp, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	object := &Object[T]{
+		Object:      obj,
+		ID:          steveObject.ID,
+		State:       steveObject.ObjectMeta.State,
+		Fields:      steveObject.ObjectMeta.Fields,
+		steveObject: steveObject,
+	}
+	if steveObject.ObjectMeta.Relationships != nil {
+		object.Relationships = *steveObject.ObjectMeta.Relationships
+	}
+
+	return object, nil
+}
+
+// newObject returns a new T, T is expected to be a pointer type as every runtime.Object implementation.
+func newObject[T runtime.Object]() T {
+	var zero T
+	return reflect.New(reflect.TypeOf(zero).Elem()).Interface().(T)
+}
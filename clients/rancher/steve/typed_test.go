@@ -0,0 +1,75 @@
+package steve
+
+import (
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher/fakeserver"
+	"github.com/rancher/shepherd/pkg/session"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func Test_TypedClient(t *testing.T) {
+	server := fakeserver.New()
+	defer server.Close()
+
+	ts := session.NewSession()
+	defer ts.Cleanup()
+
+	client, err := server.NewClient(ts)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	configMaps := NewTypedClient[*corev1.ConfigMap](client.Steve, "configmap")
+
+	watcher, err := configMaps.Namespaced("default").Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	created, err := configMaps.Create(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "typed", Namespace: "default"},
+		Data:       map[string]string{"key": "value"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != "default/typed" || created.Object.Data["key"] != "value" {
+		t.Errorf("Create() = %s %v, want default/typed with the data", created.ID, created.Object.Data)
+	}
+
+	event := <-watcher.ResultChan()
+	if configMap, ok := event.Object.(*corev1.ConfigMap); event.Type != watch.Added || !ok || configMap.Name != "typed" {
+		t.Errorf("watch event = %s %v, want the typed ConfigMap to be added", event.Type, event.Object)
+	}
+
+	created.Object.Data["key"] = "updated"
+	_, err = configMaps.Update(created)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := configMaps.Get("default/typed")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Object.Data["key"] != "updated" {
+		t.Errorf("Get() data = %v, want the updated value", got.Object.Data)
+	}
+
+	list, err := configMaps.Namespaced("default").List(nil)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 || list[0].Object.Name != "typed" {
+		t.Errorf("List() returned %d objects, want the typed ConfigMap", len(list))
+	}
+
+	err = configMaps.Delete(got)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+}
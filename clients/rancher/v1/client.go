@@ -2,6 +2,7 @@ package v1
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -110,6 +111,17 @@ func NewClient(opts *clientbase.ClientOpts) (*Client, error) {
 	return client, nil
 }
 
+// WithContext returns a copy of the Client whose requests are bound to ctx, so they are cancelled along with it.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	client := &Client{
+		APIBaseClient: c.APIBaseClient,
+	}
+	client.Ops = c.Ops.WithContext(ctx)
+	client.Opts = client.Ops.Opts
+
+	return client
+}
+
 // SteveType is a function that sets the resource type for the SteveClient
 // e.g. accessing the Steve namespace resource
 //
@@ -121,6 +133,11 @@ func (c *Client) SteveType(steveType string) *SteveClient {
 	}
 }
 
+// WithContext returns a copy of the SteveClient whose requests are bound to ctx.
+func (c *SteveClient) WithContext(ctx context.Context) *SteveClient {
+	return c.apiClient.WithContext(ctx).SteveType(c.steveType)
+}
+
 func (c *SteveClient) NamespacedSteveClient(namespace string) *NamespacedSteveClient {
 	return &NamespacedSteveClient{*c, namespace}
 }
@@ -191,14 +208,20 @@ func (c *SteveClient) Replace(obj *SteveAPIObject) (*SteveAPIObject, error) {
 }
 
 func (c *SteveClient) List(query url.Values) (*SteveCollection, error) {
-	resp := &SteveCollection{}
-	var jsonResp map[string]any
 	url, err := c.apiClient.Ops.GetCollectionURL(c.steveType, "GET")
 	if err != nil {
 		return nil, err
 	}
 	url = url + "?" + query.Encode()
-	err = c.apiClient.Ops.DoGet(url, nil, &jsonResp)
+
+	return c.getCollection(url)
+}
+
+// getCollection gets the collection at url.
+func (c *SteveClient) getCollection(url string) (*SteveCollection, error) {
+	resp := &SteveCollection{}
+	var jsonResp map[string]any
+	err := c.apiClient.Ops.DoGet(url, nil, &jsonResp)
 	if err != nil {
 		return nil, err
 	}
@@ -206,18 +229,17 @@ func (c *SteveClient) List(query url.Values) (*SteveCollection, error) {
 	if err = ConvertToK8sType(jsonResp, resp); err != nil {
 		return nil, err
 	}
+	resp.client = c
 
-	steveList := jsonResp["data"]
-	for index, item := range steveList.([]any) {
+	steveList, _ := jsonResp["data"].([]any)
+	for index, item := range steveList {
 		resp.Data[index].JSONResp = item.(map[string]any)
 	}
-	steveSummary, okSummary := jsonResp["summary"]
-	if okSummary {
-		for index, item := range steveSummary.([]any) {
-			resp.Summary[index].JSONResp = item.(map[string]any)
-		}
+	steveSummary, _ := jsonResp["summary"].([]any)
+	for index, item := range steveSummary {
+		resp.Summary[index].JSONResp = item.(map[string]any)
 	}
-	return resp, err
+	return resp, nil
 }
 
 func (c *SteveClient) ListAll(params url.Values) (*SteveCollection, error) {
@@ -284,6 +306,10 @@ func (c *NamespacedSteveClient) Create(container any) (*SteveAPIObject, error) {
 	if c.namespace != "" {
 		url += "/" + c.namespace
 	}
+	container, err = c.apiClient.Ops.WithRunLabels(c.apiClient.Ops.Types[c.steveType], container)
+	if err != nil {
+		return nil, err
+	}
 	err = c.apiClient.Ops.DoModify("POST", url, container, &jsonResp)
 	if err != nil {
 		return nil, err
@@ -293,6 +319,11 @@ func (c *NamespacedSteveClient) Create(container any) (*SteveAPIObject, error) {
 	return resp, err
 }
 
+// WithContext returns a copy of the NamespacedSteveClient whose requests are bound to ctx.
+func (c *NamespacedSteveClient) WithContext(ctx context.Context) *NamespacedSteveClient {
+	return c.SteveClient.WithContext(ctx).NamespacedSteveClient(c.namespace)
+}
+
 func (c *NamespacedSteveClient) Update(existing *SteveAPIObject, updates any) (*SteveAPIObject, error) {
 	return c.SteveClient.Update(existing, updates)
 }
@@ -347,8 +378,6 @@ func (c *NamespacedSteveClient) Replace(obj *SteveAPIObject) (*SteveAPIObject, e
 }
 
 func (c *NamespacedSteveClient) List(query url.Values) (*SteveCollection, error) {
-	resp := &SteveCollection{}
-	var jsonResp map[string]any
 	url, err := c.apiClient.Ops.GetCollectionURL(c.steveType, "GET")
 	if err != nil {
 		return nil, err
@@ -359,21 +388,8 @@ func (c *NamespacedSteveClient) List(query url.Values) (*SteveCollection, error)
 	if len(query) > 0 {
 		url += "?" + query.Encode()
 	}
-	err = c.apiClient.Ops.DoGet(url, nil, &jsonResp)
-	if err != nil {
-		return nil, err
-	}
 
-	err = ConvertToK8sType(jsonResp, resp)
-	if err != nil {
-		return nil, err
-	}
-
-	steveList := jsonResp["data"]
-	for index, item := range steveList.([]any) {
-		resp.Data[index].JSONResp = item.(map[string]any)
-	}
-	return resp, err
+	return c.getCollection(url)
 }
 
 func (c *NamespacedSteveClient) ListAll(params url.Values) (*SteveCollection, error) {
@@ -0,0 +1,141 @@
+package v1
+
+import "net/url"
+
+// Pager iterates over the pages of a Steve list, one page at a time, so that large lists are not held in memory.
+// With a page size the pages are requested by number at the revision of the first page, otherwise the next links
+// and continue tokens returned by Steve are followed.
+//
+//	pager := client.SteveType("pod").Pages(v1.NewQuery().PageSize(1000))
+//	for pager.Next() {
+//		for _, pod := range pager.Page().Data {
+//			...
+//		}
+//	}
+//	err := pager.Err()
+type Pager struct {
+	client    *SteveClient
+	namespace string
+	query     Query
+
+	page *SteveCollection
+	err  error
+	done bool
+}
+
+// Pages returns a Pager over the objects matching query.
+func (c *SteveClient) Pages(query *Query) *Pager {
+	return newPager(c, "", query)
+}
+
+// Pages returns a Pager over the objects of the namespace matching query.
+func (c *NamespacedSteveClient) Pages(query *Query) *Pager {
+	return newPager(&c.SteveClient, c.namespace, query)
+}
+
+func newPager(client *SteveClient, namespace string, query *Query) *Pager {
+	if query == nil {
+		query = NewQuery()
+	}
+
+	return &Pager{
+		client:    client,
+		namespace: namespace,
+		query:     *query,
+	}
+}
+
+// Next gets the next page, it returns false once there are no more pages or getting a page failed.
+func (p *Pager) Next() bool {
+	if p.done {
+		return false
+	}
+
+	var next string
+	var err error
+	switch {
+	case p.page == nil:
+		if p.query.pageSize > 0 && p.query.page == 0 {
+			p.query.page = 1
+		}
+		next, err = p.url(nil)
+	case p.page.Pagination != nil && p.page.Pagination.Next != "":
+		next = p.page.Pagination.Next
+	case p.query.pageSize > 0 && (p.query.page < p.page.Pages || p.page.Pages == 0 && len(p.page.Data) == p.query.pageSize):
+		p.query.page++
+		if p.query.revision == "" {
+			p.query.revision = p.page.Revision
+		}
+		next, err = p.url(nil)
+	case p.page.Continue != "":
+		next, err = p.url(url.Values{"continue": []string{p.page.Continue}})
+	default:
+		p.done = true
+		return false
+	}
+
+	if err == nil {
+		p.page, err = p.client.getCollection(next)
+	}
+	if err != nil {
+		p.err = err
+		p.done = true
+		return false
+	}
+
+	// without a page count, a full last page is followed by an empty one
+	if p.query.page > 1 && len(p.page.Data) == 0 {
+		p.done = true
+		return false
+	}
+
+	return true
+}
+
+// Page returns the current page.
+func (p *Pager) Page() *SteveCollection {
+	return p.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// Each calls f with every object of every page, until f returns an error.
+func (p *Pager) Each(f func(*SteveAPIObject) error) error {
+	for p.Next() {
+		for i := range p.page.Data {
+			err := f(&p.page.Data[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.Err()
+}
+
+// url returns the URL of the collection with the parameters of the query and extra.
+func (p *Pager) url(extra url.Values) (string, error) {
+	collectionURL, err := p.client.apiClient.Ops.GetCollectionURL(p.client.steveType, "GET")
+	if err != nil {
+		return "", err
+	}
+	if p.namespace != "" {
+		collectionURL += "/" + p.namespace
+	}
+
+	values, err := p.query.Values()
+	if err != nil {
+		return "", err
+	}
+	for key, value := range extra {
+		values[key] = value
+	}
+	if len(values) == 0 {
+		return collectionURL, nil
+	}
+
+	return collectionURL + "?" + values.Encode(), nil
+}
@@ -0,0 +1,286 @@
+package v1
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Operator is the operator of a Steve list filter.
+type Operator string
+
+const (
+	Equals      Operator = "="
+	NotEquals   Operator = "!="
+	Contains    Operator = "~"
+	NotContains Operator = "!~"
+	LessThan    Operator = "<"
+	GreaterThan Operator = ">"
+	In          Operator = " IN "
+	NotIn       Operator = " NOTIN "
+)
+
+const fieldPathRegex = `^[A-Za-z_][\w-]*(\[[^\[\]]+\])?(\.[A-Za-z_][\w-]*(\[[^\[\]]+\])?)*$`
+
+var fieldPathRegexp = regexp.MustCompile(fieldPathRegex)
+
+// Filter is a condition on a field of the listed objects, e.g. Filter{"metadata.labels[app]", Equals, "nginx"}.
+// Fields are dotted paths, with labels and annotations keys in brackets.
+type Filter struct {
+	Field    string
+	Operator Operator
+	Values   []string
+}
+
+// Eq returns a Filter matching the objects whose field equals value.
+func Eq(field, value string) Filter {
+	return Filter{Field: field, Operator: Equals, Values: []string{value}}
+}
+
+// NotEq returns a Filter matching the objects whose field does not equal value.
+func NotEq(field, value string) Filter {
+	return Filter{Field: field, Operator: NotEquals, Values: []string{value}}
+}
+
+// Like returns a Filter matching the objects whose field contains value.
+func Like(field, value string) Filter {
+	return Filter{Field: field, Operator: Contains, Values: []string{value}}
+}
+
+// NotLike returns a Filter matching the objects whose field does not contain value.
+func NotLike(field, value string) Filter {
+	return Filter{Field: field, Operator: NotContains, Values: []string{value}}
+}
+
+// Lt returns a Filter matching the objects whose numeric field is lower than value.
+func Lt(field string, value int) Filter {
+	return Filter{Field: field, Operator: LessThan, Values: []string{strconv.Itoa(value)}}
+}
+
+// Gt returns a Filter matching the objects whose numeric field is greater than value.
+func Gt(field string, value int) Filter {
+	return Filter{Field: field, Operator: GreaterThan, Values: []string{strconv.Itoa(value)}}
+}
+
+// OneOf returns a Filter matching the objects whose field is one of values.
+func OneOf(field string, values ...string) Filter {
+	return Filter{Field: field, Operator: In, Values: values}
+}
+
+// NoneOf returns a Filter matching the objects whose field is none of values.
+func NoneOf(field string, values ...string) Filter {
+	return Filter{Field: field, Operator: NotIn, Values: values}
+}
+
+func (f Filter) validate() error {
+	if !fieldPathRegexp.MatchString(f.Field) {
+		return fmt.Errorf("invalid filter field %q", f.Field)
+	}
+
+	switch f.Operator {
+	case In, NotIn:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("filter on %s needs at least one value", f.Field)
+		}
+	case Equals, NotEquals, Contains, NotContains, LessThan, GreaterThan:
+		if len(f.Values) != 1 {
+			return fmt.Errorf("filter on %s needs exactly one value", f.Field)
+		}
+	default:
+		return fmt.Errorf("invalid filter operator %q on %s", f.Operator, f.Field)
+	}
+
+	for _, value := range f.Values {
+		// commas separate the filters of a filter parameter and parentheses delimit the values of set operators
+		if strings.ContainsAny(value, ",()") {
+			return fmt.Errorf("invalid filter value %q on %s: commas and parentheses are not supported", value, f.Field)
+		}
+	}
+
+	return nil
+}
+
+func (f Filter) String() string {
+	switch f.Operator {
+	case In, NotIn:
+		return f.Field + string(f.Operator) + "(" + strings.Join(f.Values, ",") + ")"
+	default:
+		return f.Field + string(f.Operator) + f.Values[0]
+	}
+}
+
+// Query is a builder of the query parameters of Steve lists. Filters, sorting, pagination, projections and summaries
+// are only all supported by Steve with the SQL cache enabled, see extensions/vai.
+//
+//	query := v1.NewQuery().
+//		Where(v1.Eq("metadata.labels[app]", "nginx")).
+//		Where(v1.Like("metadata.name", "web"), v1.Like("metadata.name", "api")).
+//		Sort("metadata.namespace", "-metadata.creationTimestamp").
+//		PageSize(1000)
+//	values, err := query.Values()
+type Query struct {
+	filters                 [][]Filter
+	sort                    []string
+	page                    int
+	pageSize                int
+	projectsOrNamespaces    []string
+	notProjectsOrNamespaces bool
+	summary                 []string
+	include                 []string
+	exclude                 []string
+	revision                string
+	labelSelector           string
+}
+
+// NewQuery returns an empty Query.
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Where adds a filter parameter matching the objects that match any of filters. The filters of successive calls must
+// all match.
+func (q *Query) Where(filters ...Filter) *Query {
+	q.filters = append(q.filters, filters)
+	return q
+}
+
+// Sort sorts the objects by fields, in descending order for the fields prefixed with "-".
+func (q *Query) Sort(fields ...string) *Query {
+	q.sort = append(q.sort, fields...)
+	return q
+}
+
+// PageSize sets the number of objects per page.
+func (q *Query) PageSize(pageSize int) *Query {
+	q.pageSize = pageSize
+	return q
+}
+
+// Page sets the page to list, starting at 1. It requires a PageSize.
+func (q *Query) Page(page int) *Query {
+	q.page = page
+	return q
+}
+
+// ProjectsOrNamespaces restricts the list to the objects of the given projects or namespaces.
+func (q *Query) ProjectsOrNamespaces(names ...string) *Query {
+	q.projectsOrNamespaces = names
+	q.notProjectsOrNamespaces = false
+	return q
+}
+
+// NotProjectsOrNamespaces excludes the objects of the given projects or namespaces from the list.
+func (q *Query) NotProjectsOrNamespaces(names ...string) *Query {
+	q.projectsOrNamespaces = names
+	q.notProjectsOrNamespaces = true
+	return q
+}
+
+// Summary requests the counts of the values of fields across the listed objects instead of the objects.
+func (q *Query) Summary(fields ...string) *Query {
+	q.summary = append(q.summary, fields...)
+	return q
+}
+
+// Include restricts the fields of the returned objects to fields.
+func (q *Query) Include(fields ...string) *Query {
+	q.include = append(q.include, fields...)
+	return q
+}
+
+// Exclude removes fields from the returned objects, e.g. "metadata.managedFields".
+func (q *Query) Exclude(fields ...string) *Query {
+	q.exclude = append(q.exclude, fields...)
+	return q
+}
+
+// Revision lists the objects at the given revision, so that the pages of a list are consistent.
+func (q *Query) Revision(revision string) *Query {
+	q.revision = revision
+	return q
+}
+
+// LabelSelector restricts the list to the objects matching the kubernetes label selector.
+func (q *Query) LabelSelector(selector string) *Query {
+	q.labelSelector = selector
+	return q
+}
+
+// Values validates the Query and returns its url.Values.
+func (q *Query) Values() (url.Values, error) {
+	var errs error
+	values := url.Values{}
+
+	for _, filters := range q.filters {
+		var parts []string
+		for _, filter := range filters {
+			if err := filter.validate(); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			parts = append(parts, filter.String())
+		}
+		if len(parts) > 0 {
+			values.Add("filter", strings.Join(parts, ","))
+		}
+	}
+
+	for _, field := range q.sort {
+		if !fieldPathRegexp.MatchString(strings.TrimPrefix(field, "-")) {
+			errs = multierror.Append(errs, fmt.Errorf("invalid sort field %q", field))
+		}
+	}
+	if len(q.sort) > 0 {
+		values.Set("sort", strings.Join(q.sort, ","))
+	}
+
+	if q.pageSize < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("invalid page size %d", q.pageSize))
+	}
+	if q.page < 0 || q.page > 0 && q.pageSize == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("invalid page %d, pages start at 1 and need a page size", q.page))
+	}
+	if q.pageSize > 0 {
+		values.Set("pagesize", strconv.Itoa(q.pageSize))
+	}
+	if q.page > 0 {
+		values.Set("page", strconv.Itoa(q.page))
+	}
+
+	if len(q.projectsOrNamespaces) > 0 {
+		if q.notProjectsOrNamespaces {
+			// Steve reads the negated parameter as projectsornamespaces! with a value
+			values.Set("projectsornamespaces!", strings.Join(q.projectsOrNamespaces, ","))
+		} else {
+			values.Set("projectsornamespaces", strings.Join(q.projectsOrNamespaces, ","))
+		}
+	}
+
+	for _, param := range []struct {
+		name   string
+		fields []string
+	}{{"summary", q.summary}, {"include", q.include}, {"exclude", q.exclude}} {
+		name, fields := param.name, param.fields
+		for _, field := range fields {
+			if !fieldPathRegexp.MatchString(field) {
+				errs = multierror.Append(errs, fmt.Errorf("invalid %s field %q", name, field))
+			}
+		}
+		if len(fields) > 0 {
+			values.Set(name, strings.Join(fields, ","))
+		}
+	}
+
+	if q.revision != "" {
+		values.Set("revision", q.revision)
+	}
+	if q.labelSelector != "" {
+		values.Set("labelSelector", q.labelSelector)
+	}
+
+	return values, errs
+}
@@ -0,0 +1,116 @@
+package v1_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher/fakeserver"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/pkg/session"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_QueryValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *v1.Query
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "filters",
+			query: v1.NewQuery().
+				Where(v1.Eq("metadata.labels[app]", "nginx")).
+				Where(v1.Like("metadata.name", "web"), v1.OneOf("metadata.namespace", "a", "b")),
+			want: "filter=metadata.labels%5Bapp%5D%3Dnginx&filter=metadata.name~web%2Cmetadata.namespace+IN+%28a%2Cb%29",
+		},
+		{
+			name:  "sort and pagination",
+			query: v1.NewQuery().Sort("metadata.name", "-metadata.creationTimestamp").PageSize(10).Page(2),
+			want:  "page=2&pagesize=10&sort=metadata.name%2C-metadata.creationTimestamp",
+		},
+		{
+			name:  "negated namespaces",
+			query: v1.NewQuery().NotProjectsOrNamespaces("kube-system"),
+			want:  "projectsornamespaces%21=kube-system",
+		},
+		{
+			name:    "invalid field",
+			query:   v1.NewQuery().Where(v1.Eq("metadata..name", "x")),
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			query:   v1.NewQuery().Where(v1.OneOf("metadata.name", "a,b")),
+			wantErr: true,
+		},
+		{
+			name:    "page without page size",
+			query:   v1.NewQuery().Page(2),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := tt.query.Values()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Values() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && values.Encode() != tt.want {
+				t.Errorf("Values() = %s, want %s", values.Encode(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_Pager(t *testing.T) {
+	server := fakeserver.New()
+	defer server.Close()
+
+	var objects []runtime.Object
+	for i := range 5 {
+		objects = append(objects, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pager-%d", i),
+				Namespace: "default",
+				Labels:    map[string]string{"index": fmt.Sprint(i % 2)},
+			},
+		})
+	}
+	err := server.AddObjects(objects...)
+	if err != nil {
+		t.Fatalf("AddObjects() error = %v", err)
+	}
+
+	ts := session.NewSession()
+	defer ts.Cleanup()
+
+	client, err := server.NewClient(ts)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	query := v1.NewQuery().
+		Where(v1.Like("metadata.name", "pager-")).
+		Where(v1.NotEq("metadata.labels[index]", "1")).
+		Sort("-metadata.name").
+		PageSize(2)
+	pager := client.Steve.SteveType("configmap").NamespacedSteveClient("default").Pages(query)
+
+	var names []string
+	err = pager.Each(func(obj *v1.SteveAPIObject) error {
+		names = append(names, obj.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each() error = %v", err)
+	}
+	pages := pager.Page().Pages
+
+	want := []string{"pager-4", "pager-2", "pager-0"}
+	if fmt.Sprint(names) != fmt.Sprint(want) || pages != 2 {
+		t.Errorf("Each() = %v over %d pages, want %v over 2 pages", names, pages, want)
+	}
+}
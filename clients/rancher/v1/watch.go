@@ -0,0 +1,308 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	stopEvent   = "resource.stop"
+	errorEvent  = "resource.error"
+	createEvent = "resource.create"
+	changeEvent = "resource.change"
+	removeEvent = "resource.remove"
+)
+
+// subscribeRequest is the message sent on the Steve subscribe websocket to start or stop a subscription.
+type subscribeRequest struct {
+	Stop            bool   `json:"stop,omitempty"`
+	ResourceType    string `json:"resourceType"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	Namespace       string `json:"namespace,omitempty"`
+	ID              string `json:"id,omitempty"`
+	Selector        string `json:"selector,omitempty"`
+}
+
+// subscribeEvent is a message received on the Steve subscribe websocket.
+type subscribeEvent struct {
+	Name         string          `json:"name"`
+	ResourceType string          `json:"resourceType"`
+	Revision     string          `json:"revision"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// DeepCopyObject implements runtime.Object, so that SteveAPIObjects can be sent as watch events.
+func (s *SteveAPIObject) DeepCopyObject() runtime.Object {
+	if s == nil {
+		return nil
+	}
+
+	out := &SteveAPIObject{}
+	err := ConvertToK8sType(s.JSONResp, out)
+	if err != nil {
+		logrus.Errorf("failed to copy %s %s: %v", s.Type, s.ID, err)
+	}
+	out.JSONResp = runtime.DeepCopyJSON(s.JSONResp)
+
+	return out
+}
+
+// Watch returns a watch.Interface of the objects of the Steve type, backed by a subscription on the Steve subscribe
+// websocket. Its events carry *SteveAPIObject objects, so it can be used with wait.WatchWait.
+//
+// As with the kube API, if opts.ResourceVersion is empty the existing objects are sent as Added events first. The
+// label selector, the metadata.name and metadata.namespace field selectors and the timeout of opts are supported.
+func (c *SteveClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return c.watch("", opts)
+}
+
+// Watch returns a watch.Interface of the objects of the Steve type in the namespace of the client, see
+// SteveClient.Watch.
+func (c *NamespacedSteveClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return c.watch(c.namespace, opts)
+}
+
+func (c *SteveClient) watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	request := subscribeRequest{
+		ResourceType:    c.steveType,
+		ResourceVersion: opts.ResourceVersion,
+		Namespace:       namespace,
+		Selector:        opts.LabelSelector,
+	}
+
+	var name string
+	if opts.FieldSelector != "" {
+		selector, err := fields.ParseSelector(opts.FieldSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, requirement := range selector.Requirements() {
+			if requirement.Operator != selection.Equals && requirement.Operator != selection.DoubleEquals {
+				return nil, fmt.Errorf("unsupported field selector operator %s for Steve watches", requirement.Operator)
+			}
+
+			switch requirement.Field {
+			case "metadata.name":
+				name = requirement.Value
+			case "metadata.namespace":
+				request.Namespace = requirement.Value
+			default:
+				return nil, fmt.Errorf("unsupported field selector %s for Steve watches", requirement.Field)
+			}
+		}
+
+		request.ID = name
+		if name != "" && request.Namespace != "" {
+			request.ID = request.Namespace + "/" + name
+		}
+	}
+
+	var initial []SteveAPIObject
+	if opts.ResourceVersion == "" {
+		query := url.Values{}
+		if opts.LabelSelector != "" {
+			query.Set("labelSelector", opts.LabelSelector)
+		}
+		if name != "" {
+			query.Set("filter", "metadata.name="+name)
+		}
+
+		collection, err := c.listForWatch(request.Namespace, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range collection.Data {
+			if request.ID == "" || object.ID == request.ID {
+				initial = append(initial, object)
+			}
+		}
+		request.ResourceVersion = collection.Revision
+	}
+
+	subscribeURL := websocketURL(c.apiClient.Opts.URL) + "/subscribe"
+	conn, resp, err := c.apiClient.Websocket(subscribeURL, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %s: %w", subscribeURL, resp.Status, err)
+		}
+		return nil, err
+	}
+
+	err = conn.WriteJSON(request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	w := &steveWatcher{
+		conn:    conn,
+		request: request,
+		result:  make(chan watch.Event),
+		done:    make(chan struct{}),
+	}
+
+	if opts.TimeoutSeconds != nil {
+		timer := time.NewTimer(time.Duration(*opts.TimeoutSeconds) * time.Second)
+		go func() {
+			select {
+			case <-timer.C:
+				w.Stop()
+			case <-w.done:
+				timer.Stop()
+			}
+		}()
+	}
+
+	if ctx := c.apiClient.Opts.Context; ctx != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				w.Stop()
+			case <-w.done:
+			}
+		}()
+	}
+
+	go w.run(initial)
+
+	return w, nil
+}
+
+// websocketURL returns the websocket URL of the http or https URL.
+func websocketURL(httpURL string) string {
+	if rest, ok := strings.CutPrefix(httpURL, "https://"); ok {
+		return "wss://" + rest
+	}
+	if rest, ok := strings.CutPrefix(httpURL, "http://"); ok {
+		return "ws://" + rest
+	}
+
+	return httpURL
+}
+
+// listForWatch lists the objects a watch starts from, in namespace if it is set.
+func (c *SteveClient) listForWatch(namespace string, query url.Values) (*SteveCollection, error) {
+	if namespace != "" {
+		return c.NamespacedSteveClient(namespace).ListAll(query)
+	}
+
+	return c.ListAll(query)
+}
+
+// steveWatcher is a watch.Interface reading the events of a Steve subscription.
+type steveWatcher struct {
+	conn    *websocket.Conn
+	request subscribeRequest
+	result  chan watch.Event
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Stop implements watch.Interface.
+func (w *steveWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.conn.Close()
+	})
+}
+
+// ResultChan implements watch.Interface.
+func (w *steveWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *steveWatcher) run(initial []SteveAPIObject) {
+	defer close(w.result)
+	defer w.Stop()
+
+	for i := range initial {
+		object := initial[i]
+		if !w.send(watch.Event{Type: watch.Added, Object: &object}) {
+			return
+		}
+	}
+
+	for {
+		var event subscribeEvent
+		err := w.conn.ReadJSON(&event)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				logrus.Debugf("Steve watch of %s closed: %v", w.request.ResourceType, err)
+			}
+			return
+		}
+
+		var eventType watch.EventType
+		switch event.Name {
+		case createEvent:
+			eventType = watch.Added
+		case changeEvent:
+			eventType = watch.Modified
+		case removeEvent:
+			eventType = watch.Deleted
+		case errorEvent:
+			var data struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(event.Data, &data)
+			status := apierrors.NewInternalError(fmt.Errorf("%s", data.Error)).ErrStatus
+			w.send(watch.Event{Type: watch.Error, Object: &status})
+			return
+		case stopEvent:
+			// Steve ends subscriptions on its own, e.g. when the resource version expired, the watch ends with it
+			return
+		default:
+			// start events and pings carry no object
+			continue
+		}
+
+		if event.ResourceType != "" && event.ResourceType != w.request.ResourceType {
+			continue
+		}
+
+		object := &SteveAPIObject{}
+		err = json.Unmarshal(event.Data, &object.JSONResp)
+		if err == nil {
+			err = ConvertToK8sType(object.JSONResp, object)
+		}
+		if err != nil {
+			logrus.Errorf("failed to decode Steve watch event of %s: %v", w.request.ResourceType, err)
+			continue
+		}
+
+		if w.request.ID != "" && object.ID != w.request.ID {
+			continue
+		}
+
+		if !w.send(watch.Event{Type: eventType, Object: object}) {
+			return
+		}
+	}
+}
+
+func (w *steveWatcher) send(event watch.Event) bool {
+	select {
+	case <-w.done:
+		return false
+	case w.result <- event:
+		return true
+	}
+}
@@ -0,0 +1,17 @@
+package v1
+
+import "testing"
+
+func Test_WebsocketURL(t *testing.T) {
+	tests := map[string]string{
+		"https://rancher.example.com/v1": "wss://rancher.example.com/v1",
+		"http://127.0.0.1:8080/v1":       "ws://127.0.0.1:8080/v1",
+		"wss://rancher.example.com/v1":   "wss://rancher.example.com/v1",
+	}
+
+	for httpURL, want := range tests {
+		if got := websocketURL(httpURL); got != want {
+			t.Errorf("websocketURL(%s) = %s, want %s", httpURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package v1_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher/fakeserver"
+	"github.com/rancher/shepherd/pkg/session"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_WatchFieldSelector(t *testing.T) {
+	server := fakeserver.New()
+	defer server.Close()
+
+	client, err := server.NewClient(session.NewSession())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		wantErr  bool
+	}{
+		{selector: "metadata.name=default"},
+		{selector: "metadata.name==default"},
+		{selector: "metadata.name!=default", wantErr: true},
+		{selector: "metadata.namespace!=default", wantErr: true},
+		{selector: "spec.finalizers=kubernetes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			watcher, err := client.Steve.SteveType("namespace").Watch(metav1.ListOptions{FieldSelector: tt.selector})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Watch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if watcher != nil {
+				watcher.Stop()
+			}
+		})
+	}
+}
+
+func Test_WatchTimeout(t *testing.T) {
+	server := fakeserver.New()
+	defer server.Close()
+
+	client, err := server.NewClient(session.NewSession())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	timeout := int64(1)
+	watcher, err := client.Steve.SteveType("configmap").Watch(metav1.ListOptions{TimeoutSeconds: &timeout})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	select {
+	case _, ok := <-watcher.ResultChan():
+		if ok {
+			t.Error("watch sent an event, want it closed by its timeout")
+		}
+	case <-time.After(5 * time.Second):
+		watcher.Stop()
+		t.Error("watch is still open after its timeout")
+	}
+}
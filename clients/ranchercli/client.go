@@ -75,9 +75,14 @@ func NewClient(session *session.Session, token, host string, client *management.
 		Session: session,
 	}
 
+	projectName, err := namegen.AppendRandomString("project")
+	if err != nil {
+		return nil, err
+	}
+
 	projectConfig := &management.Project{
 		ClusterID: "local",
-		Name:      namegen.AppendRandomString("project"),
+		Name:      projectName,
 	}
 
 	testProject, err := client.Project.Create(projectConfig)
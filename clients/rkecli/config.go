@@ -1,5 +1,7 @@
 package rkecli
 
+import "github.com/rancher/shepherd/pkg/config"
+
 const ConfigurationFileKey = "rke"
 
 // RKE configuration required to run rkecli and up
@@ -7,3 +9,7 @@ type Config struct {
 	SSHKey  string `json:"sshKey,omitempty" yaml:"sshKey,omitempty" default:""`
 	SSHPath string `json:"sshPath,omitempty" yaml:"sshPath,omitempty" default:""`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
@@ -14,21 +14,28 @@ import (
 	v1 "github.com/rancher/shepherd/clients/rancher/v1"
 	"github.com/rancher/shepherd/pkg/config"
 	"github.com/rancher/shepherd/pkg/file"
+	"github.com/rancher/shepherd/pkg/workspace"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 )
 
-const filePrefix = "cluster"
-const dirName = "rke-cattle-test-dir"
+const workspaceName = "rke"
 
-// NewRKEConfigs creates a new dir.
-// In that dir, it generates state and cluster files from the state configmap.
+const (
+	clusterFileName = "cluster.yml"
+	stateFileName   = "cluster.rkestate"
+	// kubeconfigFileName is the kubeconfig written by rke up next to the cluster file.
+	kubeconfigFileName = "kube_config_cluster.yml"
+)
+
+// NewRKEConfigs creates a new workspace of the client session.
+// In that workspace, it generates state and cluster files from the state configmap.
 // Returns generated state and cluster files' paths.
 func NewRKEConfigs(client *rancher.Client) (stateFilePath, clusterFilePath string, err error) {
 	rkeConfig := new(Config)
 	config.LoadConfig(ConfigurationFileKey, rkeConfig)
 
-	err = file.NewDir(dirName)
+	ws, err := workspace.New(client.Session, workspaceName)
 	if err != nil {
 		return
 	}
@@ -38,16 +45,19 @@ func NewRKEConfigs(client *rancher.Client) (stateFilePath, clusterFilePath strin
 		return
 	}
 
-	clusterFilePath, err = NewClusterFile(state, dirName, rkeConfig)
+	clusterFilePath, err = WriteClusterFile(state, ws, rkeConfig)
 	if err != nil {
 		return
 	}
 
-	stateFilePath, err = NewStateFile(state, dirName)
+	stateFilePath, err = WriteStateFile(state, ws)
 	if err != nil {
 		return
 	}
 
+	// the kubeconfig of the cluster is written by rke up
+	ws.Track(kubeconfigFileName, true)
+
 	return
 }
 
@@ -103,15 +113,41 @@ func UpdateKubernetesVersion(kubernetesVersion, clusterFilePath string) error {
 		return err
 	}
 
-	return os.WriteFile(clusterFilePath, byteConfig, 0644)
+	info, err := os.Stat(clusterFilePath)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(clusterFilePath, byteConfig, info.Mode().Perm())
 }
 
-// NewClusterFile is a function that generates new cluster.yml file from the full state.
+// WriteClusterFile is a function that generates new cluster.yml file from the full state in the workspace, as a
+// secret since it holds the SSH keys of the nodes.
 // Returns the generated file's path.
+func WriteClusterFile(state *cluster.FullState, ws *workspace.Workspace, config *Config) (clusterFilePath string, err error) {
+	marshaled, err := clusterFileData(state, config)
+	if err != nil {
+		return
+	}
+
+	return ws.WriteSecret(clusterFileName, marshaled)
+}
+
+// NewClusterFile is a function that generates new cluster.yml file from the full state in the dirName directory.
+// Returns the generated file's path.
+//
+// Deprecated: use WriteClusterFile, which writes the file in a workspace only readable by the owner.
 func NewClusterFile(state *cluster.FullState, dirName string, config *Config) (clusterFilePath string, err error) {
-	extension := "yml"
-	rkeConfigFileName := fmt.Sprintf("%v/%v.%v", dirName, filePrefix, extension)
+	marshaled, err := clusterFileData(state, config)
+	if err != nil {
+		return
+	}
 
+	return file.Name(fmt.Sprintf("%v/%v", dirName, clusterFileName)).NewFile(marshaled)
+}
+
+// clusterFileData returns the cluster.yml of the full state, with the SSH configuration of config.
+func clusterFileData(state *cluster.FullState, config *Config) ([]byte, error) {
 	rkeConfig := rketypes.RancherKubernetesEngineConfig{}
 	currentRkeConfig := state.CurrentState.RancherKubernetesEngineConfig.DeepCopy()
 
@@ -128,41 +164,35 @@ func NewClusterFile(state *cluster.FullState, dirName string, config *Config) (c
 			rkeConfig.Nodes[i].SSHKeyPath = appendSSHPath(rkeConfig.Nodes[i].SSHKeyPath, config.SSHPath)
 		}
 	} else {
-		return "", errors.New("missing SSH configuration")
-	}
-
-	marshaled, err := yaml.Marshal(rkeConfig)
-	if err != nil {
-		return
+		return nil, errors.New("missing SSH configuration")
 	}
 
-	fileName := file.Name(rkeConfigFileName)
+	return yaml.Marshal(rkeConfig)
+}
 
-	clusterFilePath, err = fileName.NewFile(marshaled)
+// WriteStateFile is a function that generates new cluster.rkestate file from the full state in the workspace, as a
+// secret since it holds the certificates of the cluster.
+// Returns the generated file's path.
+func WriteStateFile(state *cluster.FullState, ws *workspace.Workspace) (stateFilePath string, err error) {
+	marshaled, err := json.Marshal(state)
 	if err != nil {
 		return
 	}
 
-	return
+	return ws.WriteSecret(stateFileName, marshaled)
 }
 
-// NewStateFile is a function that generates new cluster.rkestate file from the full state.
+// NewStateFile is a function that generates new cluster.rkestate file from the full state in the dirName directory.
 // Returns the generated file's path.
+//
+// Deprecated: use WriteStateFile, which writes the file in a workspace only readable by the owner.
 func NewStateFile(state *cluster.FullState, dirName string) (stateFilePath string, err error) {
-	extension := "rkestate"
-	rkeStateFileName := fmt.Sprintf("%v/%v.%v", dirName, filePrefix, extension)
-
 	marshaled, err := json.Marshal(state)
 	if err != nil {
 		return
 	}
 
-	stateFilePath, err = file.Name(rkeStateFileName).NewFile(marshaled)
-	if err != nil {
-		return
-	}
-
-	return
+	return file.Name(fmt.Sprintf("%v/%v", dirName, stateFileName)).NewFile(marshaled)
 }
 
 // GetFullState is a function that gets RKE full state from "full-cluster-state" secret.
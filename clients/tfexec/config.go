@@ -29,3 +29,7 @@ func TerraformConfig() *Config {
 	config.LoadConfig(ConfigurationFileKey, &tfConfig)
 	return &tfConfig
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
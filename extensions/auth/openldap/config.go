@@ -1,5 +1,7 @@
 package openldap
 
+import "github.com/rancher/shepherd/pkg/config"
+
 const (
 	ConfigurationFileKey = "openLDAP"
 )
@@ -35,3 +37,7 @@ type Users struct {
 	SearchBase string          `json:"searchBase" yaml:"searchBase"`
 	Others     map[string]User `json:"others" yaml:"others"`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
@@ -21,7 +21,11 @@ const (
 
 // CreateAWSCloudCredentials is a helper function that creates V1 cloud credentials and waits for them to become active.
 func CreateAWSCloudCredentials(client *rancher.Client, credentials cloudcredentials.CloudCredential) (*v1.SteveAPIObject, error) {
-	secretName := namegenerator.AppendRandomString(providers.AWS)
+	secretName, err := namegenerator.AppendRandomString(providers.AWS)
+	if err != nil {
+		return nil, err
+	}
+
 	spec := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: cloudcredentials.GeneratedName,
@@ -106,3 +106,14 @@ func LoadCloudCredential(provider string) CloudCredential {
 		panic(fmt.Sprintf("Provider:%v not found", provider))
 	}
 }
+
+func init() {
+	config.Register(AmazonEC2CredentialConfigurationFileKey, AmazonEC2CredentialConfig{})
+	config.Register(AzureCredentialConfigurationFileKey, AzureCredentialConfig{})
+	config.Register(DigitalOceanCredentialConfigurationFileKey, DigitalOceanCredentialConfig{})
+	config.Register(LinodeCredentialConfigurationFileKey, LinodeCredentialConfig{})
+	config.Register(HarvesterCredentialConfigurationFileKey, HarvesterCredentialConfig{})
+	config.Register(GoogleCredentialConfigurationFileKey, GoogleCredentialConfig{})
+	config.Register(VmwarevsphereCredentialConfigurationFileKey, VmwarevsphereCredentialConfig{})
+	config.Register(AlibabaCredentialConfigurationFileKey, AlibabaCredentialConfig{})
+}
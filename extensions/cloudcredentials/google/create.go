@@ -22,7 +22,11 @@ const (
 
 // CreateGoogleCloudCredentials is a helper function that creates V1 cloud credentials and waits for them to become active.
 func CreateGoogleCloudCredentials(client *rancher.Client, credentials cloudcredentials.CloudCredential) (*v1.SteveAPIObject, error) {
-	secretName := namegenerator.AppendRandomString(providers.Google)
+	secretName, err := namegenerator.AppendRandomString(providers.Google)
+	if err != nil {
+		return nil, err
+	}
+
 	spec := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: cloudcredentials.GeneratedName,
@@ -21,7 +21,11 @@ const (
 
 // CreateHarvesterCloudCredentials is a helper function that creates V1 cloud credentials and waits for them to become active.
 func CreateHarvesterCloudCredentials(client *rancher.Client, credentials cloudcredentials.CloudCredential) (*v1.SteveAPIObject, error) {
-	secretName := namegenerator.AppendRandomString(providers.Harvester)
+	secretName, err := namegenerator.AppendRandomString(providers.Harvester)
+	if err != nil {
+		return nil, err
+	}
+
 	spec := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: cloudcredentials.GeneratedName,
@@ -2,6 +2,7 @@ package aks
 
 import (
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
 )
 
 const (
@@ -134,3 +135,7 @@ func HostClusterConfig(displayName, cloudCredentialID string, aksClusterConfig C
 		VirtualNetworkResourceGroup: aksClusterConfig.VirtualNetworkResourceGroup,
 	}
 }
+
+func init() {
+	config.Register(AKSClusterConfigConfigurationFileKey, ClusterConfig{})
+}
@@ -2,6 +2,7 @@ package alibaba
 
 import (
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
 )
 
 const (
@@ -153,3 +154,7 @@ func MapAliDataDisks(disks []DataDisk) []management.AliDisk {
 func int64Ptr(i int64) *int64 {
 	return &i
 }
+
+func init() {
+	config.Register(ALIClusterConfigConfigurationFileKey, ClusterConfig{})
+}
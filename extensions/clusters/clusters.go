@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -90,9 +91,13 @@ func GetClusterNameByID(client *rancher.Client, clusterID string) (string, error
 }
 
 // IsProvisioningClusterReady is basic check function that would be used for the wait.WatchWait func in pkg/wait.
-// This functions just waits until a cluster becomes ready.
+// This functions just waits until a cluster becomes ready. It records the provisioning timeline of the cluster: the
+// timeline.Diagnosis is logged whenever the cluster changes while it is not ready, and the duration of each
+// provisioning phase once it is.
 func IsProvisioningClusterReady(event watch.Event) (ready bool, err error) {
 	cluster := event.Object.(*apisV1.Cluster)
+	recorder, changed := observeReadyTimeline(event, cluster)
+
 	var updated bool
 	ready = cluster.Status.Ready
 	for _, condition := range cluster.Status.Conditions {
@@ -102,7 +107,16 @@ func IsProvisioningClusterReady(event watch.Event) (ready bool, err error) {
 		}
 	}
 
-	return ready && updated, nil
+	if ready && updated {
+		forgetReadyTimeline(cluster.Namespace, cluster.Name)
+		_ = reportTimeline(cluster.Namespace+"/"+cluster.Name, recorder, nil)
+		return true, nil
+	}
+	if changed {
+		logrus.Infof("[%s/%s] Cluster %s", cluster.Namespace, cluster.Name, recorder.Diagnose())
+	}
+
+	return false, nil
 }
 
 // IsHostedProvisioningClusterReady is basic check function that would be used for the wait.WatchWait func in pkg/wait.
@@ -557,9 +571,25 @@ func logClusterInfoWithChanges(clusterID, clusterInfo string, summary summary.Su
 }
 
 // WatchAndWaitForCluster is function that waits for a cluster to go unactive before checking its active state.
-func WatchAndWaitForCluster(client *rancher.Client, steveID string) error {
+// It records the provisioning timeline of the cluster: the returned error is wrapped with its timeline.Diagnosis and
+// the duration of each provisioning phase is logged once the cluster is ready.
+func WatchAndWaitForCluster(client *rancher.Client, steveID string) (err error) {
+	namespace, name, _ := strings.Cut(steveID, "/")
+	dynamicClient, err := client.GetRancherDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := RecordProvisioningTimeline(ctx, dynamicClient, namespace, name)
+	defer func() {
+		err = reportTimeline(steveID, recorder, err)
+	}()
+
 	var clusterResp *v1.SteveAPIObject
-	err := kwait.PollUntilContextTimeout(context.TODO(), 1*time.Second, defaults.TenMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+	err = kwait.PollUntilContextTimeout(context.TODO(), 1*time.Second, defaults.TenMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
 		clusterResp, err = client.Steve.SteveType(stevetypes.Provisioning).ByID(steveID)
 		if err != nil {
 			return false, err
@@ -609,9 +639,23 @@ func GetProvisioningClusterByName(client *rancher.Client, clusterName string, na
 }
 
 // WaitForActiveCluster is a "helper" function that waits for the cluster to reach the active state.
-// The function accepts a Rancher client and a cluster ID as parameters.
-func WaitForActiveRKE1Cluster(client *rancher.Client, clusterID string) error {
-	err := kwait.Poll(500*time.Millisecond, 30*time.Minute, func() (done bool, err error) {
+// The function accepts a Rancher client and a cluster ID as parameters. It records the timeline of the cluster: the
+// returned error is wrapped with its timeline.Diagnosis and the duration of each phase is logged once it is active.
+func WaitForActiveRKE1Cluster(client *rancher.Client, clusterID string) (err error) {
+	dynamicClient, err := client.GetRancherDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := RecordRKE1Timeline(ctx, dynamicClient, clusterID)
+	defer func() {
+		err = reportTimeline(clusterID, recorder, err)
+	}()
+
+	err = kwait.Poll(500*time.Millisecond, 30*time.Minute, func() (done bool, err error) {
 		client, err = client.ReLogin()
 		if err != nil {
 			return false, err
@@ -0,0 +1,149 @@
+package clusterspec
+
+import (
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const machineConfigSteveTypePrefix = "rke-machine-config.cattle.io."
+
+// Apply validates the spec, creates its cluster or updates the live cluster where it differs from the spec, and
+// returns the Plan it carried out. It does not wait for the cluster to be ready, see clusters.WatchAndWaitForCluster.
+// The created resources are deleted when the client session is cleaned up.
+func Apply(client *rancher.Client, spec *Spec) (*Plan, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Provider == clusters.KubernetesProviderRKE {
+		return applyRKE1(client, spec)
+	}
+
+	return applyProvisioning(client, spec)
+}
+
+func applyProvisioning(client *rancher.Client, spec *Spec) (*Plan, error) {
+	state, err := diffProvisioning(client, spec)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("%s", state.plan)
+	if state.plan.Empty() {
+		return state.plan, nil
+	}
+
+	for i := range state.desired.Spec.RKEConfig.MachinePools {
+		machinePool := &state.desired.Spec.RKEConfig.MachinePools[i]
+		if machinePool.NodeConfig.Name != "" {
+			continue
+		}
+
+		machinePool.NodeConfig.Name, err = createNodeConfig(client, spec, spec.pool(machinePool.Name))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if state.plan.Create {
+		_, err = clusters.CreateK3SRKE2Cluster(client, state.desired)
+	} else {
+		_, err = clusters.UpdateK3SRKE2Cluster(client, state.live, state.desired)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return state.plan, nil
+}
+
+// createNodeConfig creates the node config of the machine pool from its fields and returns its name.
+func createNodeConfig(client *rancher.Client, spec *Spec, pool *MachinePool) (string, error) {
+	nodeConfig := map[string]interface{}{}
+	for key, value := range pool.NodeConfig.Fields {
+		nodeConfig[key] = value
+	}
+	nodeConfig["apiVersion"] = machineConfigAPIVersion
+	nodeConfig["kind"] = pool.NodeConfig.Kind
+	nodeConfig["metadata"] = map[string]interface{}{
+		"generateName": "nc-" + spec.Name + "-" + pool.Name + "-",
+		"namespace":    spec.Namespace,
+	}
+
+	created, err := client.Steve.SteveType(machineConfigSteveTypePrefix + strings.ToLower(pool.NodeConfig.Kind)).Create(nodeConfig)
+	if err != nil {
+		return "", err
+	}
+
+	return created.ObjectMeta.Name, nil
+}
+
+func applyRKE1(client *rancher.Client, spec *Spec) (*Plan, error) {
+	state, err := diffRKE1(client, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("%s", state.plan)
+	if state.plan.Empty() {
+		return state.plan, nil
+	}
+
+	if state.plan.Create {
+		state.live, err = clusters.CreateRKE1Cluster(client, state.desired)
+		if err != nil {
+			return nil, err
+		}
+	} else if state.clusterChanged {
+		_, err = clusters.UpdateRKE1Cluster(client, state.live, &management.Cluster{
+			Name:                          state.live.Name,
+			Labels:                        state.desired.Labels,
+			RancherKubernetesEngineConfig: state.desired.RancherKubernetesEngineConfig,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pool := range spec.MachinePools {
+		nodePool, ok := state.nodePools[pool.Name]
+		if !ok {
+			newNodePool := management.NodePool{
+				ClusterID:      state.live.ID,
+				HostnamePrefix: spec.hostnamePrefix(pool.Name),
+			}
+			pool.applyToNodePool(&newNodePool)
+
+			_, err = client.Management.NodePool.Create(&newNodePool)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		updatedNodePool := *nodePool
+		pool.applyToNodePool(&updatedNodePool)
+		if equality.Semantic.DeepEqual(*nodePool, updatedNodePool) {
+			continue
+		}
+
+		_, err = client.Management.NodePool.Update(nodePool, &updatedNodePool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range state.obsoleteNodePools {
+		err = client.Management.NodePool.Delete(&state.obsoleteNodePools[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return state.plan, nil
+}
@@ -0,0 +1,293 @@
+package clusterspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/norman/types"
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/pkg/clientbase"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Action is what a Change does to a field of the live cluster.
+type Action string
+
+const (
+	ActionAdd    Action = "add"
+	ActionUpdate Action = "update"
+	ActionRemove Action = "remove"
+)
+
+// Change is a difference between the spec and the live cluster, at Path, e.g. machinePools[pool1].quantity.
+type Change struct {
+	Path   string      `json:"path"`
+	Action Action      `json:"action"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Action {
+	case ActionAdd:
+		return fmt.Sprintf("+ %s: %v", c.Path, c.To)
+	case ActionRemove:
+		return fmt.Sprintf("- %s: %v", c.Path, c.From)
+	default:
+		return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.From, c.To)
+	}
+}
+
+// Plan is what Apply does to make the live cluster match the spec: create the cluster, or carry out the changes.
+type Plan struct {
+	Cluster string   `json:"cluster"`
+	Create  bool     `json:"create,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Empty returns whether the live cluster already matches the spec.
+func (p *Plan) Empty() bool {
+	return !p.Create && len(p.Changes) == 0
+}
+
+func (p *Plan) String() string {
+	switch {
+	case p.Create:
+		return fmt.Sprintf("cluster %s will be created", p.Cluster)
+	case p.Empty():
+		return fmt.Sprintf("cluster %s is up to date", p.Cluster)
+	}
+
+	lines := []string{fmt.Sprintf("cluster %s will be updated:", p.Cluster)}
+	for _, change := range p.Changes {
+		lines = append(lines, "  "+change.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// compare adds a change of path to the plan when from and to differ, nil and empty values being equal.
+func (p *Plan) compare(path string, from, to interface{}) {
+	if equality.Semantic.DeepEqual(from, to) {
+		return
+	}
+
+	p.Changes = append(p.Changes, Change{Path: path, Action: ActionUpdate, From: from, To: to})
+}
+
+// Diff validates the spec and returns the Plan Apply carries out to make the live cluster match it.
+func Diff(client *rancher.Client, spec *Spec) (*Plan, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Provider == clusters.KubernetesProviderRKE {
+		state, err := diffRKE1(client, spec)
+		if err != nil {
+			return nil, err
+		}
+
+		return state.plan, nil
+	}
+
+	state, err := diffProvisioning(client, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.plan, nil
+}
+
+// provisioningState is the live and the desired provisioning cluster of a spec.
+type provisioningState struct {
+	plan    *Plan
+	live    *v1.SteveAPIObject
+	desired *apisV1.Cluster
+}
+
+func diffProvisioning(client *rancher.Client, spec *Spec) (*provisioningState, error) {
+	state := &provisioningState{plan: &Plan{Cluster: spec.Namespace + "/" + spec.Name}}
+
+	live, steveObject, err := clusters.GetProvisioningClusterByName(client, spec.Name, spec.Namespace)
+	if clientbase.IsNotFound(err) {
+		state.plan.Create = true
+		state.desired, err = spec.RenderProvisioningCluster()
+		return state, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.live = steveObject
+	state.desired = live.DeepCopy()
+	spec.applyToProvisioningCluster(state.desired)
+
+	plan := state.plan
+	for key := range spec.Labels {
+		plan.compare("labels["+key+"]", live.Labels[key], state.desired.Labels[key])
+	}
+	plan.compare("kubernetesVersion", live.Spec.KubernetesVersion, state.desired.Spec.KubernetesVersion)
+	plan.compare("cloudCredential", live.Spec.CloudCredentialSecretName, state.desired.Spec.CloudCredentialSecretName)
+
+	liveConfig := live.Spec.RKEConfig
+	if liveConfig == nil {
+		liveConfig = new(apisV1.RKEConfig)
+	}
+	desiredConfig := state.desired.Spec.RKEConfig
+
+	plan.compare("cni", liveConfig.MachineGlobalConfig.Data[cniKey], desiredConfig.MachineGlobalConfig.Data[cniKey])
+	plan.compare("registries", liveConfig.Registries, desiredConfig.Registries)
+	plan.compare("registries.systemDefaultRegistry", systemDefaultRegistry(liveConfig), systemDefaultRegistry(desiredConfig))
+
+	livePools := map[string]apisV1.RKEMachinePool{}
+	for _, pool := range liveConfig.MachinePools {
+		livePools[pool.Name] = pool
+	}
+
+	for _, desiredPool := range desiredConfig.MachinePools {
+		path := "machinePools[" + desiredPool.Name + "]"
+		livePool, ok := livePools[desiredPool.Name]
+		delete(livePools, desiredPool.Name)
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{Path: path, Action: ActionAdd, To: *desiredPool.Quantity})
+			continue
+		}
+
+		plan.compare(path+".quantity", quantity(livePool), *desiredPool.Quantity)
+		plan.compare(path+".etcd", livePool.EtcdRole, desiredPool.EtcdRole)
+		plan.compare(path+".controlPlane", livePool.ControlPlaneRole, desiredPool.ControlPlaneRole)
+		plan.compare(path+".worker", livePool.WorkerRole, desiredPool.WorkerRole)
+		plan.compare(path+".labels", livePool.Labels, desiredPool.Labels)
+		plan.compare(path+".taints", livePool.Taints, desiredPool.Taints)
+		plan.compare(path+".nodeConfig", livePool.NodeConfig, desiredPool.NodeConfig)
+	}
+
+	for _, pool := range liveConfig.MachinePools {
+		if _, ok := livePools[pool.Name]; ok {
+			plan.Changes = append(plan.Changes, Change{Path: "machinePools[" + pool.Name + "]", Action: ActionRemove, From: quantity(pool)})
+		}
+	}
+
+	return state, nil
+}
+
+// quantity returns the quantity of the machine pool, 0 if unset.
+func quantity(pool apisV1.RKEMachinePool) int32 {
+	if pool.Quantity == nil {
+		return 0
+	}
+
+	return *pool.Quantity
+}
+
+// rke1State is the live and the desired RKE1 cluster of a spec, and their node pools.
+type rke1State struct {
+	plan           *Plan
+	clusterChanged bool
+
+	live    *management.Cluster
+	desired *management.Cluster
+	// nodePools are the live node pools by machine pool name, obsoleteNodePools the ones the spec does not define.
+	nodePools         map[string]*management.NodePool
+	obsoleteNodePools []management.NodePool
+}
+
+func diffRKE1(client *rancher.Client, spec *Spec) (*rke1State, error) {
+	state := &rke1State{
+		plan:      &Plan{Cluster: spec.Name},
+		nodePools: map[string]*management.NodePool{},
+	}
+
+	clusterID, err := clusters.GetClusterIDByName(client, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	if clusterID == "" {
+		state.plan.Create = true
+		state.desired, _, err = spec.RenderRKE1Cluster()
+		return state, err
+	}
+
+	state.live, err = client.Management.Cluster.ByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := *state.live
+	liveConfig := &management.RancherKubernetesEngineConfig{}
+	if state.live.RancherKubernetesEngineConfig != nil {
+		liveConfig = state.live.RancherKubernetesEngineConfig
+	}
+	desiredConfig := *liveConfig
+	if liveConfig.Network != nil {
+		network := *liveConfig.Network
+		desiredConfig.Network = &network
+	}
+	desired.RancherKubernetesEngineConfig = &desiredConfig
+	spec.applyToRKE1Cluster(&desired)
+	state.desired = &desired
+
+	plan := state.plan
+	for key := range spec.Labels {
+		plan.compare("labels["+key+"]", state.live.Labels[key], desired.Labels[key])
+	}
+	plan.compare("kubernetesVersion", liveConfig.Version, desiredConfig.Version)
+	plan.compare("cni", networkPlugin(liveConfig), networkPlugin(&desiredConfig))
+	plan.compare("registries", liveConfig.PrivateRegistries, desiredConfig.PrivateRegistries)
+	state.clusterChanged = len(plan.Changes) > 0
+
+	nodePools, err := client.Management.NodePool.ListAll(&types.ListOpts{
+		Filters: map[string]interface{}{
+			management.NodePoolFieldClusterID: clusterID,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range nodePools.Data {
+		nodePool := &nodePools.Data[i]
+		pool := spec.pool(strings.TrimSuffix(strings.TrimPrefix(nodePool.HostnamePrefix, spec.Name+"-"), "-"))
+		if pool == nil || nodePool.HostnamePrefix != spec.hostnamePrefix(pool.Name) {
+			state.obsoleteNodePools = append(state.obsoleteNodePools, *nodePool)
+			plan.Changes = append(plan.Changes, Change{Path: "machinePools[" + nodePool.HostnamePrefix + "]", Action: ActionRemove, From: nodePool.Quantity})
+			continue
+		}
+		state.nodePools[pool.Name] = nodePool
+	}
+
+	for _, pool := range spec.MachinePools {
+		path := "machinePools[" + pool.Name + "]"
+		nodePool, ok := state.nodePools[pool.Name]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{Path: path, Action: ActionAdd, To: pool.Quantity})
+			continue
+		}
+
+		desiredPool := *nodePool
+		pool.applyToNodePool(&desiredPool)
+		plan.compare(path+".quantity", nodePool.Quantity, desiredPool.Quantity)
+		plan.compare(path+".etcd", nodePool.Etcd, desiredPool.Etcd)
+		plan.compare(path+".controlPlane", nodePool.ControlPlane, desiredPool.ControlPlane)
+		plan.compare(path+".worker", nodePool.Worker, desiredPool.Worker)
+		plan.compare(path+".labels", nodePool.NodeLabels, desiredPool.NodeLabels)
+		plan.compare(path+".taints", nodePool.NodeTaints, desiredPool.NodeTaints)
+		plan.compare(path+".nodeTemplate", nodePool.NodeTemplateID, desiredPool.NodeTemplateID)
+	}
+
+	return state, nil
+}
+
+// networkPlugin returns the CNI of the RKE1 config.
+func networkPlugin(rkeConfig *management.RancherKubernetesEngineConfig) string {
+	if rkeConfig.Network == nil {
+		return ""
+	}
+
+	return rkeConfig.Network.Plugin
+}
@@ -0,0 +1,159 @@
+package clusterspec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/fakeserver"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/pkg/session"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newClient(t *testing.T) (*fakeserver.Server, *rancher.Client) {
+	server := fakeserver.New()
+	t.Cleanup(server.Close)
+
+	client, err := server.NewClient(session.NewSession())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	return server, client
+}
+
+func Test_DiffCreate(t *testing.T) {
+	_, client := newClient(t)
+
+	plan, err := Diff(client, newSpec(clusters.KubernetesProviderRKE2))
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !plan.Create || plan.Cluster != namespaces.FleetDefault+"/spec" {
+		t.Errorf("plan = %+v, want the creation of %s/spec", plan, namespaces.FleetDefault)
+	}
+}
+
+func Test_DiffProvisioning(t *testing.T) {
+	server, client := newClient(t)
+
+	err := server.AddObjects(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "provisioning.cattle.io/v1",
+		"kind":       "Cluster",
+		"metadata": map[string]any{
+			"name":      "spec",
+			"namespace": namespaces.FleetDefault,
+			"labels":    map[string]any{"team": "qa"},
+		},
+		"spec": map[string]any{
+			"kubernetesVersion": "v1.29.6+rke2r1",
+			"rkeConfig": map[string]any{
+				"machineGlobalConfig": map[string]any{cniKey: "calico"},
+				"machineSelectorConfig": []any{
+					map[string]any{"config": map[string]any{systemDefaultRegistryKey: "registry.example.com"}},
+				},
+				"registries": map[string]any{
+					"configs": map[string]any{"registry.example.com": map[string]any{"insecureSkipVerify": true}},
+				},
+				"machinePools": []any{
+					map[string]any{
+						"name": "pool1", "quantity": int64(1), "etcdRole": true, "controlPlaneRole": true, "workerRole": true,
+						"machineConfigRef": map[string]any{"kind": "Amazonec2Config", "name": "nc-pool1", "apiVersion": machineConfigAPIVersion},
+					},
+					map[string]any{
+						"name": "old", "quantity": int64(2), "workerRole": true,
+						"machineConfigRef": map[string]any{"kind": "Amazonec2Config", "name": "nc-old", "apiVersion": machineConfigAPIVersion},
+					},
+				},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("AddObjects() error = %v", err)
+	}
+
+	spec := newSpec(clusters.KubernetesProviderRKE2)
+	spec.MachinePools[0].Quantity = 3
+
+	plan, err := Diff(client, spec)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	want := []Change{
+		{Path: "kubernetesVersion", Action: ActionUpdate, From: "v1.29.6+rke2r1", To: "v1.30.2+rke2r1"},
+		{Path: "machinePools[pool1].quantity", Action: ActionUpdate, From: int32(1), To: int32(3)},
+		{Path: "machinePools[pool2]", Action: ActionAdd, To: int32(2)},
+		{Path: "machinePools[old]", Action: ActionRemove, From: int32(2)},
+	}
+	if plan.Create || !reflect.DeepEqual(plan.Changes, want) {
+		t.Errorf("Diff() changes = %+v, want %+v", plan.Changes, want)
+	}
+	if got := plan.Changes[3].String(); got != "- machinePools[old]: 2" {
+		t.Errorf("removal = %q, want the removed quantity", got)
+	}
+}
+
+func Test_DiffRKE1(t *testing.T) {
+	server, client := newClient(t)
+
+	server.AddNormanResource("cluster", "c-spec", map[string]any{
+		"name": "spec",
+		"rancherKubernetesEngineConfig": map[string]any{
+			"kubernetesVersion": "v1.30.2+rke2r1",
+			"network":           map[string]any{"plugin": "canal"},
+		},
+	})
+	server.AddNormanResource("nodePool", "c-spec:pool1", map[string]any{
+		"clusterId":      "c-spec",
+		"hostnamePrefix": "spec-pool1-",
+		"quantity":       1,
+		"etcd":           true,
+		"controlPlane":   true,
+		"worker":         true,
+		"nodeTemplateId": "cattle-global-nt:nt-pool1",
+	})
+	server.AddNormanResource("nodePool", "c-spec:old", map[string]any{
+		"clusterId":      "c-spec",
+		"hostnamePrefix": "spec-old-",
+		"quantity":       4,
+		"worker":         true,
+	})
+
+	spec := newSpec(clusters.KubernetesProviderRKE)
+	spec.Labels = nil
+	spec.Registries = nil
+
+	plan, err := Diff(client, spec)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	want := []Change{
+		{Path: "cni", Action: ActionUpdate, From: "canal", To: "calico"},
+		{Path: "machinePools[spec-old-]", Action: ActionRemove, From: int64(4)},
+		{Path: "machinePools[pool2]", Action: ActionAdd, To: int32(2)},
+	}
+	if plan.Create || !reflect.DeepEqual(plan.Changes, want) {
+		t.Errorf("Diff() changes = %+v, want %+v", plan.Changes, want)
+	}
+}
+
+func Test_DiffInvalid(t *testing.T) {
+	_, client := newClient(t)
+
+	spec := newSpec(clusters.KubernetesProviderRKE2)
+	spec.MachinePools[1].NodeConfig = nil
+
+	_, err := Diff(client, spec)
+	if err == nil {
+		t.Error("Diff() error = nil, want the missing node config")
+	}
+
+	_, err = Apply(client, spec)
+	if err == nil {
+		t.Error("Apply() error = nil, want the missing node config")
+	}
+}
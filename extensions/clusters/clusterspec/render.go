@@ -0,0 +1,295 @@
+package clusterspec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	cniKey                   = "cni"
+	systemDefaultRegistryKey = "system-default-registry"
+)
+
+// RenderProvisioningCluster validates the spec and renders its RKE2 or K3s provisioning cluster. The machine pools
+// whose node config is created from fields reference a node config of the right kind with no name, Apply creates it.
+func (s *Spec) RenderProvisioningCluster() (*apisV1.Cluster, error) {
+	if s.Provider == clusters.KubernetesProviderRKE {
+		return nil, fmt.Errorf("cluster %s is an RKE1 cluster, render it with RenderRKE1Cluster", s.Name)
+	}
+
+	err := s.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := &apisV1.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Cluster",
+			APIVersion: "provisioning.cattle.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+		},
+	}
+	s.applyToProvisioningCluster(cluster)
+
+	return cluster, nil
+}
+
+// applyToProvisioningCluster sets the fields of cluster the spec defines, leaving the others as they are. The
+// machine pools of cluster keep their node config unless the spec names another one, the new machine pools whose
+// node config the spec creates from fields reference a node config with no name yet.
+func (s *Spec) applyToProvisioningCluster(cluster *apisV1.Cluster) {
+	if len(s.Labels) > 0 {
+		if cluster.Labels == nil {
+			cluster.Labels = map[string]string{}
+		}
+		for key, value := range s.Labels {
+			cluster.Labels[key] = value
+		}
+	}
+
+	if s.KubernetesVersion != "" {
+		cluster.Spec.KubernetesVersion = s.KubernetesVersion
+	}
+	if s.CloudCredential != "" {
+		cluster.Spec.CloudCredentialSecretName = s.CloudCredential
+	}
+
+	if cluster.Spec.RKEConfig == nil {
+		cluster.Spec.RKEConfig = new(apisV1.RKEConfig)
+	}
+	rkeConfig := cluster.Spec.RKEConfig
+
+	if s.CNI != "" {
+		if rkeConfig.MachineGlobalConfig.Data == nil {
+			rkeConfig.MachineGlobalConfig.Data = map[string]interface{}{}
+		}
+		rkeConfig.MachineGlobalConfig.Data[cniKey] = s.CNI
+	}
+
+	if s.Registries != nil {
+		rkeConfig.Registries = s.Registries.rkeRegistry()
+
+		if s.Registries.SystemDefaultRegistry != "" {
+			setSystemDefaultRegistry(rkeConfig, s.Registries.SystemDefaultRegistry)
+		}
+	}
+
+	machinePools := make([]apisV1.RKEMachinePool, 0, len(s.MachinePools))
+	for _, pool := range s.MachinePools {
+		var machinePool apisV1.RKEMachinePool
+		for _, existing := range rkeConfig.MachinePools {
+			if existing.Name == pool.Name {
+				machinePool = existing
+			}
+		}
+
+		quantity := pool.Quantity
+		machinePool.Name = pool.Name
+		machinePool.Quantity = &quantity
+		machinePool.EtcdRole = pool.Etcd
+		machinePool.ControlPlaneRole = pool.ControlPlane
+		machinePool.WorkerRole = pool.Worker
+		machinePool.Labels = pool.Labels
+		machinePool.Taints = pool.Taints
+
+		switch {
+		case pool.NodeConfig.Name != "":
+			machinePool.NodeConfig = nodeConfigReference(pool.NodeConfig.Kind, pool.NodeConfig.Name)
+		case machinePool.NodeConfig != nil:
+		default:
+			machinePool.NodeConfig = nodeConfigReference(pool.NodeConfig.Kind, "")
+		}
+
+		machinePools = append(machinePools, machinePool)
+	}
+	rkeConfig.MachinePools = machinePools
+}
+
+// rkeRegistry returns the RKE2 and K3s registries config, nil if there are neither mirrors nor configs.
+func (r *Registries) rkeRegistry() *rkev1.Registry {
+	if len(r.Mirrors) == 0 && len(r.Configs) == 0 {
+		return nil
+	}
+
+	registry := &rkev1.Registry{}
+	if len(r.Mirrors) > 0 {
+		registry.Mirrors = map[string]rkev1.Mirror{}
+		for host, endpoints := range r.Mirrors {
+			registry.Mirrors[host] = rkev1.Mirror{Endpoints: endpoints}
+		}
+	}
+	if len(r.Configs) > 0 {
+		registry.Configs = map[string]rkev1.RegistryConfig{}
+		for host, config := range r.Configs {
+			registry.Configs[host] = rkev1.RegistryConfig{
+				AuthConfigSecretName: config.AuthConfigSecretName,
+				TLSSecretName:        config.TLSSecretName,
+				CABundle:             []byte(config.CABundle),
+				InsecureSkipVerify:   config.InsecureSkipVerify,
+			}
+		}
+	}
+
+	return registry
+}
+
+// setSystemDefaultRegistry sets the system default registry in the machine selector config matching every machine,
+// where Rancher sets it.
+func setSystemDefaultRegistry(rkeConfig *apisV1.RKEConfig, registry string) {
+	for i := range rkeConfig.MachineSelectorConfig {
+		selectorConfig := &rkeConfig.MachineSelectorConfig[i]
+		if selectorConfig.MachineLabelSelector != nil {
+			continue
+		}
+
+		if selectorConfig.Config.Data == nil {
+			selectorConfig.Config.Data = map[string]interface{}{}
+		}
+		selectorConfig.Config.Data[systemDefaultRegistryKey] = registry
+		return
+	}
+
+	rkeConfig.MachineSelectorConfig = append(rkeConfig.MachineSelectorConfig, rkev1.RKESystemConfig{
+		Config: rkev1.GenericMap{Data: map[string]interface{}{systemDefaultRegistryKey: registry}},
+	})
+}
+
+// systemDefaultRegistry returns the system default registry of the provisioning cluster.
+func systemDefaultRegistry(rkeConfig *apisV1.RKEConfig) interface{} {
+	for _, selectorConfig := range rkeConfig.MachineSelectorConfig {
+		if selectorConfig.MachineLabelSelector == nil {
+			return selectorConfig.Config.Data[systemDefaultRegistryKey]
+		}
+	}
+
+	return nil
+}
+
+func nodeConfigReference(kind, name string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:       kind,
+		Name:       name,
+		APIVersion: machineConfigAPIVersion,
+	}
+}
+
+// RenderRKE1Cluster validates the spec and renders its RKE1 cluster and its node pools, to create once the cluster is
+// created.
+func (s *Spec) RenderRKE1Cluster() (*management.Cluster, []management.NodePool, error) {
+	if s.Provider != clusters.KubernetesProviderRKE {
+		return nil, nil, fmt.Errorf("cluster %s is not an RKE1 cluster, render it with RenderProvisioningCluster", s.Name)
+	}
+
+	err := s.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cluster := &management.Cluster{Name: s.Name}
+	s.applyToRKE1Cluster(cluster)
+
+	nodePools := make([]management.NodePool, 0, len(s.MachinePools))
+	for _, pool := range s.MachinePools {
+		nodePool := management.NodePool{HostnamePrefix: s.hostnamePrefix(pool.Name)}
+		pool.applyToNodePool(&nodePool)
+		nodePools = append(nodePools, nodePool)
+	}
+
+	return cluster, nodePools, nil
+}
+
+// applyToRKE1Cluster sets the fields of cluster and of its RKE config the spec defines, leaving the others as they
+// are.
+func (s *Spec) applyToRKE1Cluster(cluster *management.Cluster) {
+	if len(s.Labels) > 0 {
+		labels := map[string]string{}
+		for key, value := range cluster.Labels {
+			labels[key] = value
+		}
+		for key, value := range s.Labels {
+			labels[key] = value
+		}
+		cluster.Labels = labels
+	}
+
+	if cluster.RancherKubernetesEngineConfig == nil {
+		cluster.RancherKubernetesEngineConfig = new(management.RancherKubernetesEngineConfig)
+	}
+	rkeConfig := cluster.RancherKubernetesEngineConfig
+
+	if s.KubernetesVersion != "" {
+		rkeConfig.Version = s.KubernetesVersion
+	}
+
+	if s.CNI != "" {
+		if rkeConfig.Network == nil {
+			rkeConfig.Network = new(management.NetworkConfig)
+		}
+		rkeConfig.Network.Plugin = s.CNI
+	}
+
+	if s.Registries != nil {
+		rkeConfig.PrivateRegistries = s.Registries.privateRegistries(rkeConfig.PrivateRegistries)
+	}
+}
+
+// privateRegistries returns the RKE1 private registries, keeping the credentials of the existing ones.
+func (r *Registries) privateRegistries(existing []management.PrivateRegistry) []management.PrivateRegistry {
+	var registries []management.PrivateRegistry
+	add := func(url string, isDefault bool) {
+		registry := management.PrivateRegistry{URL: url}
+		for _, existingRegistry := range existing {
+			if existingRegistry.URL == url {
+				registry = existingRegistry
+			}
+		}
+		registry.IsDefault = isDefault
+		registries = append(registries, registry)
+	}
+
+	if r.SystemDefaultRegistry != "" {
+		add(r.SystemDefaultRegistry, true)
+	}
+	for _, host := range slices.Sorted(maps.Keys(r.Configs)) {
+		if host != r.SystemDefaultRegistry {
+			add(host, false)
+		}
+	}
+
+	return registries
+}
+
+// hostnamePrefix returns the hostname prefix of the RKE1 node pool of the machine pool named pool.
+func (s *Spec) hostnamePrefix(pool string) string {
+	return s.Name + "-" + pool + "-"
+}
+
+// applyToNodePool sets the fields of the RKE1 node pool the machine pool defines.
+func (p *MachinePool) applyToNodePool(nodePool *management.NodePool) {
+	nodePool.Quantity = int64(p.Quantity)
+	nodePool.Etcd = p.Etcd
+	nodePool.ControlPlane = p.ControlPlane
+	nodePool.Worker = p.Worker
+	nodePool.NodeLabels = p.Labels
+	nodePool.NodeTemplateID = p.NodeTemplate
+
+	nodePool.NodeTaints = nil
+	for _, taint := range p.Taints {
+		nodePool.NodeTaints = append(nodePool.NodeTaints, management.Taint{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: string(taint.Effect),
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package clusterspec
+
+import (
+	"testing"
+
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+)
+
+func newSpec(provider clusters.KubernetesProvider) *Spec {
+	spec := &Spec{
+		Name:              "spec",
+		Provider:          provider,
+		KubernetesVersion: "v1.30.2+rke2r1",
+		CNI:               "calico",
+		Labels:            map[string]string{"team": "qa"},
+		Registries: &Registries{
+			SystemDefaultRegistry: "registry.example.com",
+			Configs:               map[string]RegistryConfig{"registry.example.com": {InsecureSkipVerify: true}},
+		},
+		MachinePools: []MachinePool{
+			{Name: "pool1", Quantity: 1, Etcd: true, ControlPlane: true, Worker: true},
+			{Name: "pool2", Quantity: 2, Worker: true},
+		},
+	}
+
+	for i := range spec.MachinePools {
+		pool := &spec.MachinePools[i]
+		if provider == clusters.KubernetesProviderRKE {
+			pool.NodeTemplate = "cattle-global-nt:nt-" + pool.Name
+			continue
+		}
+		pool.NodeConfig = &NodeConfig{Kind: "Amazonec2Config", Fields: map[string]interface{}{"region": "us-east-2"}}
+	}
+	spec.MachinePools[1].NodeConfig = &NodeConfig{Kind: "Amazonec2Config", Name: "nc-existing"}
+	if provider == clusters.KubernetesProviderRKE {
+		spec.MachinePools[1].NodeConfig = nil
+	}
+
+	return spec
+}
+
+func Test_RenderProvisioningCluster(t *testing.T) {
+	cluster, err := newSpec(clusters.KubernetesProviderRKE2).RenderProvisioningCluster()
+	if err != nil {
+		t.Fatalf("RenderProvisioningCluster() error = %v", err)
+	}
+
+	if cluster.Namespace != namespaces.FleetDefault {
+		t.Errorf("namespace = %q, want the default %q", cluster.Namespace, namespaces.FleetDefault)
+	}
+	if cluster.Labels["team"] != "qa" || cluster.Spec.KubernetesVersion != "v1.30.2+rke2r1" {
+		t.Errorf("cluster = %+v, want the labels and the Kubernetes version of the spec", cluster)
+	}
+
+	rkeConfig := cluster.Spec.RKEConfig
+	if rkeConfig.MachineGlobalConfig.Data[cniKey] != "calico" {
+		t.Errorf("cni = %v, want calico", rkeConfig.MachineGlobalConfig.Data[cniKey])
+	}
+	if registry := systemDefaultRegistry(rkeConfig); registry != "registry.example.com" {
+		t.Errorf("system default registry = %v, want registry.example.com", registry)
+	}
+	if rkeConfig.Registries == nil || !rkeConfig.Registries.Configs["registry.example.com"].InsecureSkipVerify {
+		t.Errorf("registries = %+v, want the registry config of the spec", rkeConfig.Registries)
+	}
+
+	pools := rkeConfig.MachinePools
+	if len(pools) != 2 {
+		t.Fatalf("machine pools = %+v, want 2", pools)
+	}
+	if *pools[0].Quantity != 1 || !pools[0].EtcdRole || pools[0].NodeConfig.Kind != "Amazonec2Config" || pools[0].NodeConfig.Name != "" {
+		t.Errorf("pool1 = %+v, want a node config to create", pools[0])
+	}
+	if *pools[1].Quantity != 2 || pools[1].EtcdRole || !pools[1].WorkerRole || pools[1].NodeConfig.Name != "nc-existing" {
+		t.Errorf("pool2 = %+v, want the existing node config", pools[1])
+	}
+}
+
+func Test_RenderInvalid(t *testing.T) {
+	spec := newSpec(clusters.KubernetesProviderRKE2)
+	spec.MachinePools[0].NodeConfig = nil
+
+	_, err := spec.RenderProvisioningCluster()
+	if err == nil {
+		t.Error("RenderProvisioningCluster() error = nil, want the missing node config")
+	}
+
+	spec = newSpec(clusters.KubernetesProviderRKE)
+	spec.MachinePools[0].NodeTemplate = ""
+
+	_, _, err = spec.RenderRKE1Cluster()
+	if err == nil {
+		t.Error("RenderRKE1Cluster() error = nil, want the missing node template")
+	}
+}
+
+func Test_RenderRKE1Cluster(t *testing.T) {
+	cluster, nodePools, err := newSpec(clusters.KubernetesProviderRKE).RenderRKE1Cluster()
+	if err != nil {
+		t.Fatalf("RenderRKE1Cluster() error = %v", err)
+	}
+
+	rkeConfig := cluster.RancherKubernetesEngineConfig
+	if rkeConfig.Version != "v1.30.2+rke2r1" || rkeConfig.Network.Plugin != "calico" {
+		t.Errorf("RKE config = %+v, want the version and the CNI of the spec", rkeConfig)
+	}
+	if len(rkeConfig.PrivateRegistries) != 1 || !rkeConfig.PrivateRegistries[0].IsDefault {
+		t.Errorf("private registries = %+v, want the default registry", rkeConfig.PrivateRegistries)
+	}
+
+	if len(nodePools) != 2 {
+		t.Fatalf("node pools = %+v, want 2", nodePools)
+	}
+	if nodePools[1].HostnamePrefix != "spec-pool2-" || nodePools[1].Quantity != 2 || nodePools[1].NodeTemplateID != "cattle-global-nt:nt-pool2" {
+		t.Errorf("pool2 = %+v, want the node pool of the spec", nodePools[1])
+	}
+}
+
+func Test_RenderWrongProvider(t *testing.T) {
+	_, err := newSpec(clusters.KubernetesProviderRKE).RenderProvisioningCluster()
+	if err == nil {
+		t.Error("RenderProvisioningCluster() of an RKE1 spec error = nil, want an error")
+	}
+
+	_, _, err = newSpec(clusters.KubernetesProviderK3S).RenderRKE1Cluster()
+	if err == nil {
+		t.Error("RenderRKE1Cluster() of a K3s spec error = nil, want an error")
+	}
+}
@@ -0,0 +1,188 @@
+package clusterspec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const machineConfigAPIVersion = "rke-machine-config.cattle.io/v1"
+
+// Spec is the declarative definition of a node driver cluster: the machine pools, node configs, cloud credential,
+// CNI, Kubernetes version and registries of an RKE2 or K3s provisioning cluster, or of an RKE1 cluster and its node
+// pools. Load one from a YAML file with Load, then Render it, Diff it against the live cluster or Apply it.
+type Spec struct {
+	Name string `json:"name"`
+	// Namespace of the provisioning cluster, fleet-default by default. Unused for RKE1.
+	Namespace string `json:"namespace,omitempty"`
+	// Provider is rke2, k3s or rke.
+	Provider          clusters.KubernetesProvider `json:"provider"`
+	KubernetesVersion string                      `json:"kubernetesVersion,omitempty"`
+	CNI               string                      `json:"cni,omitempty"`
+	// CloudCredential is the cloud credential secret of the node configs, namespace:name. Unused for RKE1.
+	CloudCredential string            `json:"cloudCredential,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Registries      *Registries       `json:"registries,omitempty"`
+	MachinePools    []MachinePool     `json:"machinePools"`
+}
+
+// MachinePool is a machine pool of an RKE2 or K3s cluster, or a node pool of an RKE1 cluster.
+type MachinePool struct {
+	Name         string            `json:"name"`
+	Quantity     int32             `json:"quantity"`
+	Etcd         bool              `json:"etcd,omitempty"`
+	ControlPlane bool              `json:"controlPlane,omitempty"`
+	Worker       bool              `json:"worker,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Taints       []corev1.Taint    `json:"taints,omitempty"`
+	// NodeConfig is the machine config of the RKE2 or K3s machine pool.
+	NodeConfig *NodeConfig `json:"nodeConfig,omitempty"`
+	// NodeTemplate is the ID of the node template of the RKE1 node pool.
+	NodeTemplate string `json:"nodeTemplate,omitempty"`
+}
+
+// NodeConfig is a machine config, e.g. an Amazonec2Config, either an existing one referenced by Name or one created
+// from Fields when its machine pool is created.
+type NodeConfig struct {
+	Kind   string                 `json:"kind"`
+	Name   string                 `json:"name,omitempty"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Registries are the registries the cluster pulls its images from.
+type Registries struct {
+	// SystemDefaultRegistry is the registry of the system images, the default private registry for RKE1.
+	SystemDefaultRegistry string `json:"systemDefaultRegistry,omitempty"`
+	// Mirrors are the endpoints of each mirrored registry. Unused for RKE1.
+	Mirrors map[string][]string `json:"mirrors,omitempty"`
+	// Configs are the configs of each registry host. RKE1 only takes the hosts, as private registries.
+	Configs map[string]RegistryConfig `json:"configs,omitempty"`
+}
+
+// RegistryConfig is the authentication and TLS config of a registry.
+type RegistryConfig struct {
+	AuthConfigSecretName string `json:"authConfigSecretName,omitempty"`
+	TLSSecretName        string `json:"tlsSecretName,omitempty"`
+	CABundle             string `json:"caBundle,omitempty"`
+	InsecureSkipVerify   bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// Parse parses the YAML spec in data, failing on unknown fields, and validates it.
+func Parse(data []byte) (*Spec, error) {
+	spec := new(Spec)
+	err := yaml.UnmarshalStrict(data, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	err = spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// Load parses and validates the YAML spec in the file at path.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cluster spec %s: %w", path, err)
+	}
+
+	return spec, nil
+}
+
+// Validate returns every error of the spec, and sets its default namespace.
+func (s *Spec) Validate() error {
+	var errs []error
+
+	if s.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if s.Namespace == "" {
+		s.Namespace = namespaces.FleetDefault
+	}
+
+	isRKE1 := s.Provider == clusters.KubernetesProviderRKE
+	switch s.Provider {
+	case clusters.KubernetesProviderRKE, clusters.KubernetesProviderRKE2, clusters.KubernetesProviderK3S:
+	default:
+		errs = append(errs, fmt.Errorf("provider %q is not one of %s, %s or %s", s.Provider,
+			clusters.KubernetesProviderRKE, clusters.KubernetesProviderRKE2, clusters.KubernetesProviderK3S))
+	}
+
+	if s.CloudCredential != "" && !strings.Contains(s.CloudCredential, ":") {
+		errs = append(errs, fmt.Errorf("cloud credential %q is not namespace:name", s.CloudCredential))
+	}
+
+	if len(s.MachinePools) == 0 {
+		errs = append(errs, errors.New("at least one machine pool is required"))
+	}
+
+	var etcd, controlPlane, worker bool
+	pools := map[string]bool{}
+	for i, pool := range s.MachinePools {
+		if pool.Name == "" {
+			errs = append(errs, fmt.Errorf("machine pool %d has no name", i))
+		} else if pools[pool.Name] {
+			errs = append(errs, fmt.Errorf("machine pool %s is defined more than once", pool.Name))
+		}
+		pools[pool.Name] = true
+
+		if pool.Quantity < 0 {
+			errs = append(errs, fmt.Errorf("machine pool %s has a negative quantity", pool.Name))
+		}
+		if !pool.Etcd && !pool.ControlPlane && !pool.Worker {
+			errs = append(errs, fmt.Errorf("machine pool %s has no role", pool.Name))
+		}
+		etcd = etcd || (pool.Etcd && pool.Quantity > 0)
+		controlPlane = controlPlane || (pool.ControlPlane && pool.Quantity > 0)
+		worker = worker || (pool.Worker && pool.Quantity > 0)
+
+		switch {
+		case isRKE1 && pool.NodeTemplate == "":
+			errs = append(errs, fmt.Errorf("RKE1 node pool %s has no node template", pool.Name))
+		case isRKE1 && pool.NodeConfig != nil:
+			errs = append(errs, fmt.Errorf("RKE1 node pool %s takes a node template, not a node config", pool.Name))
+		case !isRKE1 && pool.NodeConfig == nil:
+			errs = append(errs, fmt.Errorf("machine pool %s has no node config", pool.Name))
+		case !isRKE1 && pool.NodeConfig.Kind == "":
+			errs = append(errs, fmt.Errorf("node config of machine pool %s has no kind", pool.Name))
+		case !isRKE1 && (pool.NodeConfig.Name == "") == (pool.NodeConfig.Fields == nil):
+			errs = append(errs, fmt.Errorf("node config of machine pool %s needs either a name or fields", pool.Name))
+		}
+	}
+
+	if len(s.MachinePools) > 0 && (!etcd || !controlPlane || !worker) {
+		errs = append(errs, errors.New("the machine pools need at least one etcd, control plane and worker machine"))
+	}
+
+	if s.Registries != nil && isRKE1 && len(s.Registries.Mirrors) > 0 {
+		errs = append(errs, errors.New("RKE1 clusters do not support registry mirrors"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// pool returns the machine pool of the spec named name, nil if there is none.
+func (s *Spec) pool(name string) *MachinePool {
+	for i := range s.MachinePools {
+		if s.MachinePools[i].Name == name {
+			return &s.MachinePools[i]
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package eks
 
 import (
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
 )
 
 const (
@@ -126,3 +127,7 @@ func eksHostClusterConfig(displayName, cloudCredentialID string, eksClusterConfi
 		Tags:                   &eksClusterConfig.Tags,
 	}
 }
+
+func init() {
+	config.Register(EKSClusterConfigConfigurationFileKey, ClusterConfig{})
+}
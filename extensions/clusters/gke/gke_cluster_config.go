@@ -2,6 +2,7 @@ package gke
 
 import (
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
 )
 
 const (
@@ -290,3 +291,7 @@ func gkeHostClusterConfig(clusterName, cloudCredentialID string, gkeClusterConfi
 		Zone:                           gkeClusterConfig.Zone,
 	}
 }
+
+func init() {
+	config.Register(GKEClusterConfigConfigurationFileKey, ClusterConfig{})
+}
@@ -0,0 +1,156 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/sirupsen/logrus"
+)
+
+// hostedLifecycle manages an AKS, EKS or GKE cluster through the management API.
+type hostedLifecycle struct {
+	client *rancher.Client
+	meta   *clusters.ClusterMeta
+}
+
+func (l *hostedLifecycle) Meta() *clusters.ClusterMeta {
+	return l.meta
+}
+
+func (l *hostedLifecycle) Create(cluster any) error {
+	hostedCluster, ok := cluster.(*management.Cluster)
+	if !ok {
+		return fmt.Errorf("%s clusters are created from a *management.Cluster, got %T", l.meta.Provider, cluster)
+	}
+
+	created, err := l.client.Management.Cluster.Create(hostedCluster)
+	if err != nil {
+		return err
+	}
+
+	l.meta.ID = created.ID
+	l.meta.Name = created.Name
+
+	return nil
+}
+
+func (l *hostedLifecycle) WaitReady(ctx context.Context) error {
+	return waitManagementClusterActive(ctx, l.client, l.meta.ID)
+}
+
+// update applies mutate to the cluster and waits until the update is rolled out, see waitManagementClusterRollout.
+func (l *hostedLifecycle) update(ctx context.Context, mutate func(*management.Cluster) error, rolledOut func(*management.Cluster) bool) error {
+	cluster, err := l.client.Management.Cluster.ByID(l.meta.ID)
+	if err != nil {
+		return err
+	}
+
+	err = mutate(cluster)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.client.Management.Cluster.Update(cluster, &management.Cluster{
+		Name:      cluster.Name,
+		AKSConfig: cluster.AKSConfig,
+		EKSConfig: cluster.EKSConfig,
+		GKEConfig: cluster.GKEConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	return waitManagementClusterRollout(ctx, l.client, l.meta.ID, rolledOut)
+}
+
+// Scale also waits until the cluster has as many nodes as its node pools add up to, as the helpers of the hosted
+// providers do, since the cluster may be active again before its nodes are.
+func (l *hostedLifecycle) Scale(ctx context.Context, pool string, quantity int64) error {
+	var nodes int64
+	return l.update(ctx, func(cluster *management.Cluster) error {
+		found := false
+		switch {
+		case l.meta.Provider == clusters.KubernetesProviderAKS && cluster.AKSConfig != nil && cluster.AKSConfig.NodePools != nil:
+			for i, nodePool := range *cluster.AKSConfig.NodePools {
+				if nodePool.Name != nil && *nodePool.Name == pool {
+					(*cluster.AKSConfig.NodePools)[i].Count = &quantity
+					found = true
+				}
+				nodes += count((*cluster.AKSConfig.NodePools)[i].Count)
+			}
+		case l.meta.Provider == clusters.KubernetesProviderEKS && cluster.EKSConfig != nil && cluster.EKSConfig.NodeGroups != nil:
+			for i, nodeGroup := range *cluster.EKSConfig.NodeGroups {
+				if nodeGroup.NodegroupName != nil && *nodeGroup.NodegroupName == pool {
+					(*cluster.EKSConfig.NodeGroups)[i].DesiredSize = &quantity
+					found = true
+				}
+				nodes += count((*cluster.EKSConfig.NodeGroups)[i].DesiredSize)
+			}
+		case l.meta.Provider == clusters.KubernetesProviderGKE && cluster.GKEConfig != nil && cluster.GKEConfig.NodePools != nil:
+			for i, nodePool := range *cluster.GKEConfig.NodePools {
+				if nodePool.Name != nil && *nodePool.Name == pool {
+					(*cluster.GKEConfig.NodePools)[i].InitialNodeCount = &quantity
+					found = true
+				}
+				nodes += count((*cluster.GKEConfig.NodePools)[i].InitialNodeCount)
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%s cluster %s has no node pool %s", l.meta.Provider, l.meta.Name, pool)
+		}
+
+		logrus.Infof("Scaling node pool %s of cluster %s to %d nodes", pool, l.meta.Name, quantity)
+		return nil
+	}, func(cluster *management.Cluster) bool {
+		return cluster.NodeCount == nodes
+	})
+}
+
+// count returns the node count of a node pool, 0 when unset.
+func count(nodes *int64) int64 {
+	if nodes == nil {
+		return 0
+	}
+
+	return *nodes
+}
+
+func (l *hostedLifecycle) Upgrade(ctx context.Context, kubernetesVersion string) error {
+	return l.update(ctx, func(cluster *management.Cluster) error {
+		switch {
+		case l.meta.Provider == clusters.KubernetesProviderAKS && cluster.AKSConfig != nil:
+			cluster.AKSConfig.KubernetesVersion = &kubernetesVersion
+		case l.meta.Provider == clusters.KubernetesProviderEKS && cluster.EKSConfig != nil:
+			cluster.EKSConfig.KubernetesVersion = &kubernetesVersion
+		case l.meta.Provider == clusters.KubernetesProviderGKE && cluster.GKEConfig != nil:
+			cluster.GKEConfig.KubernetesVersion = &kubernetesVersion
+		default:
+			return fmt.Errorf("%s cluster %s has no %s config", l.meta.Provider, l.meta.Name, l.meta.Provider)
+		}
+
+		logrus.Infof("Upgrading the control plane of cluster %s to %s", l.meta.Name, kubernetesVersion)
+		return nil
+	}, nil)
+}
+
+func (l *hostedLifecycle) Snapshot() (string, error) {
+	return "", fmt.Errorf("%w: snapshots of %s clusters", ErrNotSupported, l.meta.Provider)
+}
+
+func (l *hostedLifecycle) Restore(context.Context, string) error {
+	return fmt.Errorf("%w: restoring %s clusters", ErrNotSupported, l.meta.Provider)
+}
+
+func (l *hostedLifecycle) Delete() error {
+	cluster, err := l.client.Management.Cluster.ByID(l.meta.ID)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Deleting cluster %s...", cluster.Name)
+	return l.client.Management.Cluster.Delete(cluster)
+}
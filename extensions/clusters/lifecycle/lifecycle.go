@@ -0,0 +1,126 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const active = "active"
+
+// pollInterval is the interval the clusters are polled at while waiting for them.
+var pollInterval = 5 * time.Second
+
+// ErrNotSupported is returned by the operations a provider does not support, e.g. snapshots of hosted clusters.
+var ErrNotSupported = errors.New("not supported")
+
+// ClusterLifecycle manages a downstream cluster the same way whatever its KubernetesProvider, so that a scenario can
+// run against every provider. Get one with New to create a cluster, or with ForCluster for an existing cluster.
+type ClusterLifecycle interface {
+	// Meta returns the ClusterMeta of the cluster, its ID may only be known once the cluster is ready.
+	Meta() *clusters.ClusterMeta
+	// Create creates the cluster from its provider specific definition, a *apisV1.Cluster for RKE2 and K3s, a
+	// *management.Cluster for RKE1 and the hosted providers. It does not wait for the cluster, see WaitReady. The
+	// cluster is deleted when the client session is cleaned up.
+	Create(cluster any) error
+	// WaitReady waits until the cluster is active or ctx is done, within 30 minutes when ctx has no deadline.
+	WaitReady(ctx context.Context) error
+	// Scale sets the quantity of nodes of the machine pool, node pool or node group named pool and waits until the
+	// nodes are rolled out and the cluster is ready again.
+	Scale(ctx context.Context, pool string, quantity int64) error
+	// Upgrade upgrades the Kubernetes version of the cluster, the control plane only for the hosted providers, and
+	// waits until the upgrade is rolled out and the cluster is ready again.
+	Upgrade(ctx context.Context, kubernetesVersion string) error
+	// Snapshot takes an etcd snapshot and returns the identifier Restore takes, its name for RKE2 and K3s and its
+	// ID for RKE1.
+	Snapshot() (string, error)
+	// Restore restores the etcd snapshot identified by snapshot and waits until the restore is rolled out and the
+	// cluster is ready again.
+	Restore(ctx context.Context, snapshot string) error
+	// Delete deletes the cluster.
+	Delete() error
+}
+
+// New returns the ClusterLifecycle of a cluster of provider that is not created yet, see ClusterLifecycle.Create.
+func New(client *rancher.Client, provider clusters.KubernetesProvider) (ClusterLifecycle, error) {
+	return ForCluster(client, &clusters.ClusterMeta{
+		Provider: provider,
+		IsHosted: clusters.IsHostedProvider(provider),
+	})
+}
+
+// ForCluster returns the ClusterLifecycle of the existing cluster described by meta, see clusters.NewClusterMeta.
+func ForCluster(client *rancher.Client, meta *clusters.ClusterMeta) (ClusterLifecycle, error) {
+	if meta.IsLocal {
+		return nil, fmt.Errorf("%w: managing the lifecycle of the local cluster", ErrNotSupported)
+	}
+
+	switch meta.Provider {
+	case clusters.KubernetesProviderRKE:
+		return &rke1Lifecycle{client: client, meta: meta}, nil
+	case clusters.KubernetesProviderRKE2, clusters.KubernetesProviderK3S:
+		return &rke2Lifecycle{client: client, meta: meta}, nil
+	case clusters.KubernetesProviderAKS, clusters.KubernetesProviderEKS, clusters.KubernetesProviderGKE:
+		return &hostedLifecycle{client: client, meta: meta}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown cluster provider %q", ErrNotSupported, meta.Provider)
+	}
+}
+
+// poll calls condition every pollInterval until it is done or ctx is done, within 30 minutes when ctx has no
+// deadline.
+func poll(ctx context.Context, condition kwait.ConditionWithContextFunc) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaults.ThirtyMinuteTimeout)
+		defer cancel()
+	}
+
+	return kwait.PollUntilContextCancel(ctx, pollInterval, true, condition)
+}
+
+// waitManagementClusterActive waits until the management cluster clusterID is active.
+func waitManagementClusterActive(ctx context.Context, client *rancher.Client, clusterID string) error {
+	return waitManagementCluster(ctx, client, clusterID, func(cluster *management.Cluster) bool {
+		return cluster.State == active
+	})
+}
+
+// waitManagementClusterRollout waits until the management cluster clusterID leaves the active state, as it does
+// once an update is rolled out, then until it is active again and rolledOut, when not nil, reports the update done.
+func waitManagementClusterRollout(ctx context.Context, client *rancher.Client, clusterID string, rolledOut func(*management.Cluster) bool) error {
+	err := waitManagementCluster(ctx, client, clusterID, func(cluster *management.Cluster) bool {
+		return cluster.State != active
+	})
+	if err != nil {
+		return err
+	}
+
+	return waitManagementCluster(ctx, client, clusterID, func(cluster *management.Cluster) bool {
+		return cluster.State == active && (rolledOut == nil || rolledOut(cluster))
+	})
+}
+
+// waitManagementCluster waits until done reports the management cluster clusterID done.
+func waitManagementCluster(ctx context.Context, client *rancher.Client, clusterID string, done func(*management.Cluster) bool) error {
+	client, err := client.WithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return poll(ctx, func(context.Context) (bool, error) {
+		cluster, err := client.Management.Cluster.ByID(clusterID)
+		if err != nil {
+			return false, nil
+		}
+
+		return done(cluster), nil
+	})
+}
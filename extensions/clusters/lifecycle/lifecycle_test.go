@@ -0,0 +1,359 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/fakeserver"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/machinepools"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func init() {
+	pollInterval = 10 * time.Millisecond
+}
+
+// newClient returns a client of a fake server, whose configuration is written to CATTLE_TEST_CONFIG as the updates of
+// the provisioning clusters log in again.
+func newClient(t *testing.T) (*fakeserver.Server, *rancher.Client) {
+	server := fakeserver.New()
+	t.Cleanup(server.Close)
+
+	t.Setenv(config.ConfigEnvironmentKey, filepath.Join(t.TempDir(), "config.yaml"))
+	err := server.WriteConfig()
+	if err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+
+	client, err := server.NewClient(session.NewSession())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	return server, client
+}
+
+// rollOutManagementCluster emulates Rancher rolling out the update of the management cluster clusterID once updated
+// reports it, unless ctx is done first: the cluster is updating for a while then active again with nodeCount nodes.
+func rollOutManagementCluster(ctx context.Context, client *rancher.Client, clusterID string, updated func() bool, nodeCount int64) {
+	for ctx.Err() == nil && !updated() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cluster, err := client.Management.Cluster.ByID(clusterID)
+	if err != nil {
+		return
+	}
+	_, _ = client.Management.Cluster.Update(cluster, map[string]any{"state": "updating"})
+
+	time.Sleep(50 * time.Millisecond)
+	_, _ = client.Management.Cluster.Update(cluster, map[string]any{"state": active, "nodeCount": nodeCount})
+}
+
+// rollOutProvisioningCluster emulates Rancher rolling out the updates of the provisioning cluster name, observing the
+// generation of its spec a while after it changes, and CAPI creating the machines of its first machine pool pool, with
+// their downstream nodes, until ctx is done.
+func rollOutProvisioningCluster(ctx context.Context, t *testing.T, server *fakeserver.Server, client *rancher.Client, name, pool string) {
+	for ; ctx.Err() == nil; time.Sleep(10 * time.Millisecond) {
+		cluster, steveObject, err := clusters.GetProvisioningClusterByName(client, name, namespaces.FleetDefault)
+		if err != nil {
+			continue
+		}
+
+		machines, err := machinepools.ListMachines(client, name, pool)
+		if err != nil {
+			continue
+		}
+		for i := len(machines); i < int(*cluster.Spec.RKEConfig.MachinePools[0].Quantity); i++ {
+			machine := pool + "-" + strconv.Itoa(i)
+			err = server.AddObjects(&unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "cluster.x-k8s.io/v1beta1",
+				"kind":       "Machine",
+				"metadata": map[string]any{
+					"name":      machine,
+					"namespace": namespaces.FleetDefault,
+					"labels": map[string]any{
+						"cluster.x-k8s.io/cluster-name":       name,
+						"rke.cattle.io/rke-machine-pool-name": pool,
+					},
+				},
+				"spec": map[string]any{"clusterName": name},
+			}}, &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Node",
+				"metadata":   map[string]any{"name": machine},
+			}})
+			if err != nil {
+				t.Errorf("AddObjects() error = %v", err)
+			}
+		}
+
+		if cluster.Status.ObservedGeneration < cluster.Generation {
+			time.Sleep(50 * time.Millisecond)
+			cluster.Status.ObservedGeneration = cluster.Generation
+			_, _ = client.Steve.SteveType(clusters.ProvisioningSteveResourceType).Update(steveObject, cluster)
+		}
+	}
+}
+
+func Test_ForCluster(t *testing.T) {
+	tests := []struct {
+		meta    clusters.ClusterMeta
+		want    ClusterLifecycle
+		wantErr bool
+	}{
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderRKE}, want: &rke1Lifecycle{}},
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderRKE2}, want: &rke2Lifecycle{}},
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderK3S}, want: &rke2Lifecycle{}},
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderAKS, IsHosted: true}, want: &hostedLifecycle{}},
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderEKS, IsHosted: true}, want: &hostedLifecycle{}},
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderGKE, IsHosted: true}, want: &hostedLifecycle{}},
+		{meta: clusters.ClusterMeta{Provider: clusters.KubernetesProviderRKE2, IsLocal: true}, wantErr: true},
+		{meta: clusters.ClusterMeta{Provider: "unknown"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		meta := tt.meta
+		got, err := ForCluster(nil, &meta)
+		if tt.wantErr {
+			if !errors.Is(err, ErrNotSupported) {
+				t.Errorf("ForCluster(%+v) error = %v, want %v", meta, err, ErrNotSupported)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ForCluster(%+v) error = %v", meta, err)
+		}
+
+		switch tt.want.(type) {
+		case *rke1Lifecycle:
+			_, ok := got.(*rke1Lifecycle)
+			if !ok {
+				t.Errorf("ForCluster(%+v) = %T, want %T", meta, got, tt.want)
+			}
+		case *rke2Lifecycle:
+			_, ok := got.(*rke2Lifecycle)
+			if !ok {
+				t.Errorf("ForCluster(%+v) = %T, want %T", meta, got, tt.want)
+			}
+		case *hostedLifecycle:
+			_, ok := got.(*hostedLifecycle)
+			if !ok {
+				t.Errorf("ForCluster(%+v) = %T, want %T", meta, got, tt.want)
+			}
+		}
+		if got.Meta() != &meta {
+			t.Errorf("ForCluster(%+v).Meta() is not the given meta", meta)
+		}
+	}
+}
+
+func Test_HostedCreateScale(t *testing.T) {
+	_, client := newClient(t)
+
+	lifecycle, err := New(client, clusters.KubernetesProviderAKS)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pool, count := "pool", int64(1)
+	err = lifecycle.Create(&management.Cluster{
+		Name: "aks",
+		AKSConfig: &management.AKSClusterConfigSpec{
+			NodePools: &[]management.AKSNodePool{{Name: &pool, Count: &count}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if lifecycle.Meta().ID == "" || lifecycle.Meta().Name != "aks" {
+		t.Fatalf("Meta() = %+v, want the ID and the name of the created cluster", lifecycle.Meta())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go rollOutManagementCluster(ctx, client, lifecycle.Meta().ID, func() bool {
+		cluster, err := client.Management.Cluster.ByID(lifecycle.Meta().ID)
+		return err == nil && *(*cluster.AKSConfig.NodePools)[0].Count == 3
+	}, 3)
+
+	err = lifecycle.Scale(ctx, pool, 3)
+	if err != nil {
+		t.Fatalf("Scale() error = %v", err)
+	}
+
+	cluster, err := client.Management.Cluster.ByID(lifecycle.Meta().ID)
+	if err != nil {
+		t.Fatalf("ByID() error = %v", err)
+	}
+	nodePools := *cluster.AKSConfig.NodePools
+	if len(nodePools) != 1 || nodePools[0].Count == nil || *nodePools[0].Count != 3 {
+		t.Errorf("node pools = %+v, want %s scaled to 3", nodePools, pool)
+	}
+	if cluster.State != active || cluster.NodeCount != 3 {
+		t.Errorf("cluster is %s with %d nodes, want Scale() to wait until it is active with 3 nodes", cluster.State, cluster.NodeCount)
+	}
+
+	if err := lifecycle.Scale(ctx, "missing", 3); err == nil {
+		t.Error("Scale() of a missing node pool succeeded")
+	}
+}
+
+func Test_RKE1ScaleUpgrade(t *testing.T) {
+	server, client := newClient(t)
+
+	server.AddNormanResource("cluster", "c-rke1", map[string]any{
+		"name":                          "rke1",
+		"rancherKubernetesEngineConfig": map[string]any{"kubernetesVersion": "v1.28.9-rancher1-1"},
+	})
+	server.AddNormanResource("nodePool", "c-rke1:pool", map[string]any{
+		"name":           "pool",
+		"clusterId":      "c-rke1",
+		"hostnamePrefix": "rke1-pool",
+		"quantity":       1,
+	})
+
+	lifecycle, err := ForCluster(client, &clusters.ClusterMeta{ID: "c-rke1", Name: "rke1", Provider: clusters.KubernetesProviderRKE})
+	if err != nil {
+		t.Fatalf("ForCluster() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go rollOutManagementCluster(ctx, client, "c-rke1", func() bool {
+		nodePool, err := client.Management.NodePool.ByID("c-rke1:pool")
+		return err == nil && nodePool.Quantity == 2
+	}, 2)
+
+	err = lifecycle.Scale(ctx, "rke1-pool", 2)
+	if err != nil {
+		t.Fatalf("Scale() error = %v", err)
+	}
+	cluster, err := client.Management.Cluster.ByID("c-rke1")
+	if err != nil {
+		t.Fatalf("ByID() error = %v", err)
+	}
+	if cluster.NodeCount != 2 {
+		t.Errorf("cluster has %d nodes, want Scale() to wait until the 2 nodes are rolled out", cluster.NodeCount)
+	}
+
+	nodePools, err := client.Management.NodePool.List(&types.ListOpts{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(nodePools.Data) != 1 || nodePools.Data[0].Quantity != 2 {
+		t.Errorf("node pools = %+v, want the pool scaled to 2", nodePools.Data)
+	}
+
+	go rollOutManagementCluster(ctx, client, "c-rke1", func() bool {
+		cluster, err := client.Management.Cluster.ByID("c-rke1")
+		return err == nil && cluster.RancherKubernetesEngineConfig.Version == "v1.29.4-rancher1-1"
+	}, 2)
+
+	err = lifecycle.Upgrade(ctx, "v1.29.4-rancher1-1")
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	cluster, err = client.Management.Cluster.ByID("c-rke1")
+	if err != nil {
+		t.Fatalf("ByID() error = %v", err)
+	}
+	if cluster.RancherKubernetesEngineConfig.Version != "v1.29.4-rancher1-1" {
+		t.Errorf("version = %s, want v1.29.4-rancher1-1", cluster.RancherKubernetesEngineConfig.Version)
+	}
+}
+
+func Test_RKE2Scale(t *testing.T) {
+	server, client := newClient(t)
+
+	err := server.AddObjects(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "provisioning.cattle.io/v1",
+		"kind":       "Cluster",
+		"metadata": map[string]any{
+			"name":      "rke2",
+			"namespace": namespaces.FleetDefault,
+		},
+		"spec": map[string]any{
+			"kubernetesVersion": "v1.30.2+rke2r1",
+			"rkeConfig": map[string]any{
+				"machinePools": []any{map[string]any{"name": "pool", "quantity": int64(1)}},
+			},
+		},
+		"status": map[string]any{"ready": true, "clusterName": "c-m-rke2", "observedGeneration": int64(1)},
+	}})
+	if err != nil {
+		t.Fatalf("AddObjects() error = %v", err)
+	}
+
+	lifecycle, err := ForCluster(client, &clusters.ClusterMeta{Name: "rke2", Provider: clusters.KubernetesProviderRKE2})
+	if err != nil {
+		t.Fatalf("ForCluster() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	go rollOutProvisioningCluster(ctx, t, server, client, "rke2", "pool")
+
+	err = lifecycle.Scale(ctx, "pool", 3)
+	if err != nil {
+		t.Fatalf("Scale() error = %v", err)
+	}
+	if lifecycle.Meta().ID != "c-m-rke2" {
+		t.Errorf("Meta().ID = %q, want the management cluster c-m-rke2", lifecycle.Meta().ID)
+	}
+
+	cluster, _, err := clusters.GetProvisioningClusterByName(client, "rke2", namespaces.FleetDefault)
+	if err != nil {
+		t.Fatalf("GetProvisioningClusterByName() error = %v", err)
+	}
+	machinePools := cluster.Spec.RKEConfig.MachinePools
+	if len(machinePools) != 1 || machinePools[0].Quantity == nil || *machinePools[0].Quantity != 3 {
+		t.Errorf("machine pools = %+v, want pool scaled to 3", machinePools)
+	}
+	if cluster.Status.ObservedGeneration != cluster.Generation {
+		t.Errorf("observed generation = %d, want Scale() to wait until generation %d is observed", cluster.Status.ObservedGeneration, cluster.Generation)
+	}
+}
+
+func Test_ScaleCancelledWithContext(t *testing.T) {
+	server, client := newClient(t)
+
+	pool, count := "pool", int64(1)
+	server.AddNormanResource("cluster", "c-provisioning", map[string]any{
+		"name":  "provisioning",
+		"state": "provisioning",
+		"aksConfig": map[string]any{
+			"nodePools": []any{map[string]any{"name": pool, "count": count}},
+		},
+	})
+
+	lifecycle, err := ForCluster(client, &clusters.ClusterMeta{
+		ID:       "c-provisioning",
+		Name:     "provisioning",
+		Provider: clusters.KubernetesProviderAKS,
+		IsHosted: true,
+	})
+	if err != nil {
+		t.Fatalf("ForCluster() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = lifecycle.Scale(ctx, pool, 2)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Scale() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,125 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/etcdsnapshot"
+	"github.com/sirupsen/logrus"
+)
+
+// rke1Lifecycle manages an RKE1 cluster through the management API.
+type rke1Lifecycle struct {
+	client *rancher.Client
+	meta   *clusters.ClusterMeta
+}
+
+func (l *rke1Lifecycle) Meta() *clusters.ClusterMeta {
+	return l.meta
+}
+
+func (l *rke1Lifecycle) Create(cluster any) error {
+	rke1Cluster, ok := cluster.(*management.Cluster)
+	if !ok {
+		return fmt.Errorf("RKE1 clusters are created from a *management.Cluster, got %T", cluster)
+	}
+
+	created, err := clusters.CreateRKE1Cluster(l.client, rke1Cluster)
+	if err != nil {
+		return err
+	}
+
+	l.meta.ID = created.ID
+	l.meta.Name = created.Name
+
+	return nil
+}
+
+func (l *rke1Lifecycle) WaitReady(ctx context.Context) error {
+	return waitManagementClusterActive(ctx, l.client, l.meta.ID)
+}
+
+func (l *rke1Lifecycle) Scale(ctx context.Context, pool string, quantity int64) error {
+	nodePools, err := l.client.Management.NodePool.List(&types.ListOpts{
+		Filters: map[string]interface{}{
+			management.NodePoolFieldClusterID: l.meta.ID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range nodePools.Data {
+		nodePool := &nodePools.Data[i]
+		if nodePool.Name != pool && nodePool.HostnamePrefix != pool {
+			continue
+		}
+
+		logrus.Infof("Scaling node pool %s of cluster %s to %d nodes", pool, l.meta.Name, quantity)
+		_, err = l.client.Management.NodePool.Update(nodePool, map[string]interface{}{
+			management.NodePoolFieldQuantity: quantity,
+		})
+		if err != nil {
+			return err
+		}
+
+		return waitManagementClusterRollout(ctx, l.client, l.meta.ID, nil)
+	}
+
+	return fmt.Errorf("cluster %s has no node pool %s", l.meta.Name, pool)
+}
+
+func (l *rke1Lifecycle) Upgrade(ctx context.Context, kubernetesVersion string) error {
+	cluster, err := l.client.Management.Cluster.ByID(l.meta.ID)
+	if err != nil {
+		return err
+	}
+	if cluster.RancherKubernetesEngineConfig == nil {
+		return fmt.Errorf("cluster %s has no RKE config", l.meta.Name)
+	}
+
+	rkeConfig := *cluster.RancherKubernetesEngineConfig
+	rkeConfig.Version = kubernetesVersion
+
+	logrus.Infof("Upgrading cluster %s to %s", l.meta.Name, kubernetesVersion)
+	_, err = l.client.Management.Cluster.Update(cluster, &management.Cluster{
+		Name:                          cluster.Name,
+		RancherKubernetesEngineConfig: &rkeConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	return waitManagementClusterRollout(ctx, l.client, l.meta.ID, nil)
+}
+
+func (l *rke1Lifecycle) Snapshot() (string, error) {
+	snapshots, err := etcdsnapshot.CreateRKE1Snapshot(l.client, l.meta.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no snapshot of cluster %s was created", l.meta.Name)
+	}
+
+	return snapshots[len(snapshots)-1].ID, nil
+}
+
+func (l *rke1Lifecycle) Restore(ctx context.Context, snapshot string) error {
+	err := etcdsnapshot.RestoreRKE1Snapshot(l.client, l.meta.Name, &management.RestoreFromEtcdBackupInput{
+		EtcdBackupID: snapshot,
+	})
+	if err != nil {
+		return err
+	}
+
+	return l.WaitReady(ctx)
+}
+
+func (l *rke1Lifecycle) Delete() error {
+	return clusters.DeleteRKE1Cluster(l.client, l.meta.ID)
+}
@@ -0,0 +1,161 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/etcdsnapshot"
+	"github.com/rancher/shepherd/extensions/machinepools"
+	"github.com/sirupsen/logrus"
+)
+
+// rke2Lifecycle manages an RKE2 or K3s cluster through its provisioning cluster.
+type rke2Lifecycle struct {
+	client *rancher.Client
+	meta   *clusters.ClusterMeta
+}
+
+func (l *rke2Lifecycle) Meta() *clusters.ClusterMeta {
+	return l.meta
+}
+
+func (l *rke2Lifecycle) steveID() string {
+	return namespaces.FleetDefault + "/" + l.meta.Name
+}
+
+func (l *rke2Lifecycle) Create(cluster any) error {
+	provisioningCluster, ok := cluster.(*apisV1.Cluster)
+	if !ok {
+		return fmt.Errorf("RKE2 and K3s clusters are created from a *apisV1.Cluster, got %T", cluster)
+	}
+	if provisioningCluster.Namespace == "" {
+		provisioningCluster.Namespace = namespaces.FleetDefault
+	}
+	if provisioningCluster.Namespace != namespaces.FleetDefault {
+		return fmt.Errorf("cluster %s must be created in %s", provisioningCluster.Name, namespaces.FleetDefault)
+	}
+
+	created, err := clusters.CreateK3SRKE2Cluster(l.client, provisioningCluster)
+	if err != nil {
+		return err
+	}
+
+	l.meta.Name = created.ObjectMeta.Name
+	if strings.Contains(provisioningCluster.Spec.KubernetesVersion, string(clusters.KubernetesProviderK3S)) {
+		l.meta.Provider = clusters.KubernetesProviderK3S
+	}
+
+	return nil
+}
+
+// WaitReady also waits until the cluster observed the generation of its spec, so that it returns once the updates are
+// rolled out, and records the ID of the management cluster in the ClusterMeta.
+func (l *rke2Lifecycle) WaitReady(ctx context.Context) error {
+	client, err := l.client.WithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return poll(ctx, func(context.Context) (bool, error) {
+		cluster, err := client.Steve.SteveType(clusters.ProvisioningSteveResourceType).ByID(l.steveID())
+		if err != nil {
+			return false, nil
+		}
+
+		status := &apisV1.ClusterStatus{}
+		err = v1.ConvertToK8sType(cluster.Status, status)
+		if err != nil {
+			return false, err
+		}
+
+		if status.ClusterName != "" {
+			l.meta.ID = status.ClusterName
+		}
+
+		observed := status.ObservedGeneration >= cluster.ObjectMeta.Generation
+
+		return observed && cluster.ObjectMeta.State != nil && cluster.ObjectMeta.State.Name == active && status.Ready, nil
+	})
+}
+
+// update applies mutate to the provisioning cluster and waits until the update is rolled out, see WaitReady.
+func (l *rke2Lifecycle) update(ctx context.Context, mutate func(*apisV1.Cluster) error) error {
+	cluster, steveObject, err := clusters.GetProvisioningClusterByName(l.client, l.meta.Name, namespaces.FleetDefault)
+	if err != nil {
+		return err
+	}
+
+	err = mutate(cluster)
+	if err != nil {
+		return err
+	}
+
+	_, err = clusters.UpdateK3SRKE2Cluster(l.client, steveObject, cluster)
+	if err != nil {
+		return err
+	}
+
+	return l.WaitReady(ctx)
+}
+
+func (l *rke2Lifecycle) Scale(ctx context.Context, pool string, quantity int64) error {
+	err := machinepools.ScaleMachinePool(l.client, l.meta.Name, pool, int32(quantity))
+	if err != nil {
+		return err
+	}
+
+	return l.WaitReady(ctx)
+}
+
+func (l *rke2Lifecycle) Upgrade(ctx context.Context, kubernetesVersion string) error {
+	return l.update(ctx, func(cluster *apisV1.Cluster) error {
+		logrus.Infof("Upgrading cluster %s to %s", l.meta.Name, kubernetesVersion)
+		cluster.Spec.KubernetesVersion = kubernetesVersion
+		return nil
+	})
+}
+
+func (l *rke2Lifecycle) Snapshot() (string, error) {
+	snapshots, err := etcdsnapshot.CreateRKE2K3SSnapshot(l.client, l.meta.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no snapshot of cluster %s was created", l.meta.Name)
+	}
+
+	return snapshots[len(snapshots)-1].ObjectMeta.Name, nil
+}
+
+func (l *rke2Lifecycle) Restore(ctx context.Context, snapshot string) error {
+	cluster, _, err := clusters.GetProvisioningClusterByName(l.client, l.meta.Name, namespaces.FleetDefault)
+	if err != nil {
+		return err
+	}
+
+	generation := 1
+	if cluster.Spec.RKEConfig != nil && cluster.Spec.RKEConfig.ETCDSnapshotRestore != nil {
+		generation = cluster.Spec.RKEConfig.ETCDSnapshotRestore.Generation + 1
+	}
+
+	err = etcdsnapshot.RestoreRKE2K3SSnapshot(l.client, &rkev1.ETCDSnapshotRestore{
+		Name:       snapshot,
+		Generation: generation,
+	}, l.meta.Name)
+	if err != nil {
+		return err
+	}
+
+	return l.WaitReady(ctx)
+}
+
+func (l *rke2Lifecycle) Delete() error {
+	return clusters.DeleteK3SRKE2Cluster(l.client, l.steveID())
+}
@@ -0,0 +1,183 @@
+package clusters
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/shepherd/pkg/timeline"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+const capiClusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+// watchBackoff is the backoff of the timeline watches that fail, until the context of the timeline is done.
+var watchBackoff = kwait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      time.Minute,
+}
+
+var (
+	provisioningClusterGK  = schema.GroupKind{Group: "provisioning.cattle.io", Kind: "Cluster"}
+	managementClusterGK    = schema.GroupKind{Group: "management.cattle.io", Kind: "Cluster"}
+	provisioningClusterGVR = schema.GroupVersionResource{Group: "provisioning.cattle.io", Version: "v1", Resource: "clusters"}
+	rkeControlPlaneGVR     = schema.GroupVersionResource{Group: "rke.cattle.io", Version: "v1", Resource: "rkecontrolplanes"}
+	capiClusterGVR         = schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"}
+	capiMachineGVR         = schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "machines"}
+	managementClusterGVR   = schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "clusters"}
+	managementNodeGVR      = schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "nodes"}
+)
+
+// readyTimelines are the timelines of the provisioning clusters checked by IsProvisioningClusterReady, by
+// namespace/name.
+var readyTimelines = struct {
+	lock      sync.Mutex
+	recorders map[string]*timeline.Recorder
+}{
+	recorders: map[string]*timeline.Recorder{},
+}
+
+// RecordProvisioningTimeline records, until ctx is done, the transitions of the provisioning cluster namespace/name,
+// of its RKEControlPlane, of its CAPI cluster and of its machines. The phases of the returned Recorder are the
+// conditions of the provisioning cluster, started at its creation.
+func RecordProvisioningTimeline(ctx context.Context, client dynamic.Interface, namespace, name string) *timeline.Recorder {
+	recorder := timeline.NewRecorder(provisioningClusterGK.Kind)
+	byName := metav1.ListOptions{FieldSelector: "metadata.name=" + name}
+	tracked := provisioningClusterGK
+
+	go recordTimeline(ctx, recorder, tracked, client.Resource(provisioningClusterGVR).Namespace(namespace), byName)
+	go recordTimeline(ctx, recorder, tracked, client.Resource(rkeControlPlaneGVR).Namespace(namespace), byName)
+	go recordTimeline(ctx, recorder, tracked, client.Resource(capiClusterGVR).Namespace(namespace), byName)
+	go recordTimeline(ctx, recorder, tracked, client.Resource(capiMachineGVR).Namespace(namespace), metav1.ListOptions{
+		LabelSelector: capiClusterNameLabel + "=" + name,
+	})
+
+	return recorder
+}
+
+// RecordRKE1Timeline records, until ctx is done, the transitions of the RKE1 cluster clusterID and of its nodes. The
+// phases of the returned Recorder are the conditions of the cluster, started at its creation.
+func RecordRKE1Timeline(ctx context.Context, client dynamic.Interface, clusterID string) *timeline.Recorder {
+	recorder := timeline.NewRecorder(managementClusterGK.Kind)
+	tracked := managementClusterGK
+
+	go recordTimeline(ctx, recorder, tracked, client.Resource(managementClusterGVR), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + clusterID,
+	})
+	go recordTimeline(ctx, recorder, tracked, client.Resource(managementNodeGVR).Namespace(clusterID), metav1.ListOptions{})
+
+	return recorder
+}
+
+// recordTimeline has recorder observe the objects matching opts, watching them again whenever the watch closes and
+// retrying the watches that fail with watchBackoff, until ctx is done.
+func recordTimeline(ctx context.Context, recorder *timeline.Recorder, tracked schema.GroupKind, resource dynamic.ResourceInterface, opts metav1.ListOptions) {
+	initialBackoff := watchBackoff
+	backoff := initialBackoff
+	for ctx.Err() == nil {
+		result, err := resource.Watch(ctx, opts)
+		if err != nil {
+			delay := backoff.Step()
+			logrus.Debugf("Failed to watch the timeline of %s, retrying in %v: %v", tracked, delay, err)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			continue
+		}
+		backoff = initialBackoff
+
+		for event := range result.ResultChan() {
+			if event.Type == watch.Error {
+				continue
+			}
+			if obj, ok := event.Object.(*unstructured.Unstructured); ok {
+				observe(recorder, tracked, obj)
+			}
+		}
+
+		result.Stop()
+	}
+}
+
+// observe has recorder observe obj. The phases start at the creation of the tracked object, the one of the tracked
+// group and kind. The objects of the other groups, e.g. the CAPI cluster of a provisioning cluster, have their kinds
+// qualified by their group to keep the tracked object the only one of its kind.
+func observe(recorder *timeline.Recorder, tracked schema.GroupKind, obj *unstructured.Unstructured) {
+	groupKind := obj.GroupVersionKind().GroupKind()
+	if groupKind.Group != tracked.Group {
+		obj = obj.DeepCopy()
+		obj.SetKind(obj.GetKind() + "." + groupKind.Group)
+	} else if created := obj.GetCreationTimestamp(); groupKind == tracked && !created.IsZero() {
+		recorder.Start(created.Time)
+	}
+
+	recorder.Observe(obj)
+}
+
+// reportTimeline wraps err, the error waiting for the cluster name, with the timeline.Diagnosis of recorder, or logs
+// the duration of each phase when err is nil.
+func reportTimeline(name string, recorder *timeline.Recorder, err error) error {
+	if err != nil {
+		return errors.Wrapf(err, "cluster %s %s", name, recorder.Diagnose())
+	}
+
+	for _, phase := range recorder.Phases() {
+		logrus.Infof("Cluster %s phase %s took %v", name, phase.Name, phase.Duration.Round(time.Second))
+	}
+
+	return nil
+}
+
+// observeReadyTimeline records the provisioning cluster of event in its timeline. It returns the timeline, and
+// whether the event was a transition of the cluster or of its conditions.
+func observeReadyTimeline(event watch.Event, cluster *apisV1.Cluster) (*timeline.Recorder, bool) {
+	key := cluster.Namespace + "/" + cluster.Name
+
+	readyTimelines.lock.Lock()
+	recorder, ok := readyTimelines.recorders[key]
+	if !ok {
+		recorder = timeline.NewRecorder(provisioningClusterGK.Kind)
+		readyTimelines.recorders[key] = recorder
+	}
+	if event.Type == watch.Deleted {
+		delete(readyTimelines.recorders, key)
+	}
+	readyTimelines.lock.Unlock()
+
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cluster)
+	if err != nil {
+		logrus.Debugf("Failed to record the provisioning timeline of cluster %s: %v", key, err)
+		return recorder, false
+	}
+
+	obj := &unstructured.Unstructured{Object: data}
+	obj.SetGroupVersionKind(apisV1.SchemeGroupVersion.WithKind(provisioningClusterGK.Kind))
+
+	observed := len(recorder.Events())
+	observe(recorder, provisioningClusterGK, obj)
+
+	return recorder, len(recorder.Events()) > observed
+}
+
+// forgetReadyTimeline drops the timeline of the ready provisioning cluster namespace/name.
+func forgetReadyTimeline(namespace, name string) {
+	readyTimelines.lock.Lock()
+	defer readyTimelines.lock.Unlock()
+
+	delete(readyTimelines.recorders, namespace+"/"+name)
+}
@@ -0,0 +1,234 @@
+package clusters
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/shepherd/pkg/timeline"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var created = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTimelineClient returns a fake dynamic client whose watches fail failures times, then count their starts in
+// watches once they are registered, as the objects created before are not sent to the watches.
+func newTimelineClient(failures int32, watches *atomic.Int32) *fake.FakeDynamicClient {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		provisioningClusterGVR: "ClusterList",
+		rkeControlPlaneGVR:     "RKEControlPlaneList",
+		capiClusterGVR:         "ClusterList",
+		capiMachineGVR:         "MachineList",
+		managementClusterGVR:   "ClusterList",
+		managementNodeGVR:      "NodeList",
+	})
+
+	var failed atomic.Int32
+	client.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		if failed.Add(1) <= failures {
+			return true, nil, errors.New("watch failed")
+		}
+
+		result, err := client.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		watches.Add(1)
+		return true, result, err
+	})
+
+	return client
+}
+
+// newObject returns the object name of gvr and kind, created at created, with the Ready condition status.
+func newObject(gvr schema.GroupVersionResource, kind, namespace, name string, created time.Time, status string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"status": map[string]any{
+			"conditions": []any{map[string]any{"type": "Ready", "status": status}},
+		},
+	}}
+	obj.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind))
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetCreationTimestamp(metav1.NewTime(created))
+
+	return obj
+}
+
+// waitForEvents waits until recorder recorded an event of each of kinds.
+func waitForEvents(t *testing.T, recorder *timeline.Recorder, kinds ...string) {
+	err := kwait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		observed := map[string]bool{}
+		for _, event := range recorder.Events() {
+			observed[event.Kind] = true
+		}
+		for _, kind := range kinds {
+			if !observed[kind] {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("events = %v, want events of %v", recorder.Events(), kinds)
+	}
+}
+
+// waitForWatches waits until count watches are started.
+func waitForWatches(t *testing.T, watches *atomic.Int32, count int32) {
+	err := kwait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		return watches.Load() >= count, nil
+	})
+	if err != nil {
+		t.Fatalf("%d watches started, want %d", watches.Load(), count)
+	}
+}
+
+func create(t *testing.T, client *fake.FakeDynamicClient, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	_, err := client.Resource(gvr).Namespace(obj.GetNamespace()).Create(context.Background(), obj, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+}
+
+func Test_RecordProvisioningTimeline(t *testing.T) {
+	var watches atomic.Int32
+	client := newTimelineClient(0, &watches)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := RecordProvisioningTimeline(ctx, client, "fleet-default", "test")
+	waitForWatches(t, &watches, 4)
+
+	machine := newObject(capiMachineGVR, "Machine", "fleet-default", "test-pool-1", created.Add(-time.Hour), "False")
+	machine.SetLabels(map[string]string{capiClusterNameLabel: "test"})
+	create(t, client, capiMachineGVR, machine)
+	create(t, client, capiClusterGVR, newObject(capiClusterGVR, "Cluster", "fleet-default", "test", created.Add(-time.Hour), "True"))
+	create(t, client, provisioningClusterGVR, newObject(provisioningClusterGVR, "Cluster", "fleet-default", "test", created, "True"))
+
+	waitForEvents(t, recorder, "Cluster", "Cluster.cluster.x-k8s.io", "Machine.cluster.x-k8s.io")
+
+	if started := recorder.Report().Started; !started.Equal(created) {
+		t.Errorf("Started = %v, want the creation of the provisioning cluster %v", started, created)
+	}
+	if phases := recorder.Phases(); len(phases) != 1 || phases[0].Name != "Ready" {
+		t.Errorf("Phases() = %+v, want the Ready condition of the provisioning cluster", phases)
+	}
+	if diagnosis := recorder.Diagnose(); diagnosis.FailingObject == nil || diagnosis.FailingObject.Name != "fleet-default/test-pool-1" {
+		t.Errorf("FailingObject = %v, want the machine test-pool-1", diagnosis.FailingObject)
+	}
+}
+
+func Test_RecordRKE1Timeline(t *testing.T) {
+	var watches atomic.Int32
+	client := newTimelineClient(0, &watches)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := RecordRKE1Timeline(ctx, client, "c-test")
+	waitForWatches(t, &watches, 2)
+
+	create(t, client, managementClusterGVR, newObject(managementClusterGVR, "Cluster", "", "c-test", created, "True"))
+	waitForEvents(t, recorder, "Cluster")
+	// the nodes share the group of the cluster, but must not start its phases
+	create(t, client, managementNodeGVR, newObject(managementNodeGVR, "Node", "c-test", "m-node", created.Add(time.Hour), "True"))
+	waitForEvents(t, recorder, "Node")
+
+	if started := recorder.Report().Started; !started.Equal(created) {
+		t.Errorf("Started = %v, want the creation of the cluster %v", started, created)
+	}
+	if phases := recorder.Phases(); len(phases) != 1 || phases[0].Name != "Ready" {
+		t.Errorf("Phases() = %+v, want the Ready condition of the cluster", phases)
+	}
+}
+
+func Test_RecordTimelineRetriesWatch(t *testing.T) {
+	backoff := watchBackoff
+	watchBackoff.Duration = 10 * time.Millisecond
+	defer func() {
+		watchBackoff = backoff
+	}()
+
+	var watches atomic.Int32
+	client := newTimelineClient(3, &watches)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := RecordRKE1Timeline(ctx, client, "c-test")
+	waitForWatches(t, &watches, 2)
+
+	create(t, client, managementClusterGVR, newObject(managementClusterGVR, "Cluster", "", "c-test", created, "True"))
+	waitForEvents(t, recorder, "Cluster")
+}
+
+func Test_ObserveReadyTimeline(t *testing.T) {
+	cluster := &apisV1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "fleet-default", CreationTimestamp: metav1.NewTime(created)},
+		Status: apisV1.ClusterStatus{
+			Conditions: []genericcondition.GenericCondition{{Type: "Ready", Status: "False"}},
+		},
+	}
+
+	recorder, transitioned := observeReadyTimeline(watch.Event{Type: watch.Added}, cluster)
+	if !transitioned {
+		t.Error("observeReadyTimeline() of a new cluster did not report a transition")
+	}
+	if started := recorder.Report().Started; !started.Equal(created) {
+		t.Errorf("Started = %v, want the creation of the cluster %v", started, created)
+	}
+
+	again, transitioned := observeReadyTimeline(watch.Event{Type: watch.Modified}, cluster)
+	if again != recorder {
+		t.Error("observeReadyTimeline() of the same cluster returned another timeline")
+	}
+	if transitioned {
+		t.Error("observeReadyTimeline() of an unchanged cluster reported a transition")
+	}
+
+	cluster.Status.Conditions[0].Status = "True"
+	_, transitioned = observeReadyTimeline(watch.Event{Type: watch.Modified}, cluster)
+	if !transitioned {
+		t.Error("observeReadyTimeline() of a ready cluster did not report a transition")
+	}
+	if phases := recorder.Phases(); len(phases) != 1 || phases[0].Name != "Ready" {
+		t.Errorf("Phases() = %+v, want the Ready condition", phases)
+	}
+
+	_, _ = observeReadyTimeline(watch.Event{Type: watch.Deleted}, cluster)
+	readyTimelines.lock.Lock()
+	_, ok := readyTimelines.recorders["fleet-default/ready"]
+	readyTimelines.lock.Unlock()
+	if ok {
+		t.Error("the timeline of a deleted cluster is kept")
+	}
+}
+
+func Test_ReportTimeline(t *testing.T) {
+	recorder := timeline.NewRecorder("Cluster")
+
+	if err := reportTimeline("test", recorder, nil); err != nil {
+		t.Errorf("reportTimeline() of a ready cluster error = %v", err)
+	}
+
+	waitErr := errors.New("timed out")
+	err := reportTimeline("test", recorder, waitErr)
+	if !errors.Is(err, waitErr) {
+		t.Errorf("reportTimeline() error = %v, want %v", err, waitErr)
+	}
+	if err != nil && !strings.Contains(err.Error(), "cluster test") {
+		t.Errorf("reportTimeline() error = %v, want it to name the cluster", err)
+	}
+}
@@ -0,0 +1,325 @@
+package customcluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/machinepools"
+	extnodes "github.com/rancher/shepherd/extensions/nodes"
+	"github.com/rancher/shepherd/extensions/tokenregistration"
+	"github.com/rancher/shepherd/pkg/nodes"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// NodeRegistration is a node to register to a custom RKE2 or K3s cluster, and the roles it registers with.
+type NodeRegistration struct {
+	Node         *nodes.Node
+	Etcd         bool
+	ControlPlane bool
+	Worker       bool
+	// Windows registers the node as a Windows worker, with the Windows node command.
+	Windows bool
+	// NodeName overrides the hostname the node registers with.
+	NodeName string
+	// Address and InternalAddress override the addresses the node registers with, its public and private IP
+	// addresses by default.
+	Address         string
+	InternalAddress string
+	Labels          map[string]string
+	Taints          []corev1.Taint
+}
+
+// Options are the options of RegisterNodes.
+type Options struct {
+	// Insecure registers the nodes with the insecure node commands, which skip the verification of the Rancher CA.
+	Insecure bool
+	// Output receives the output of the registration commands, each line prefixed with the public IP address of its
+	// node. The output is logged at debug level when it is nil.
+	Output io.Writer
+	// Timeout is how long to wait for the machines to be ready, thirty minutes by default.
+	Timeout time.Duration
+}
+
+func (r *NodeRegistration) validate() error {
+	switch {
+	case r.Node == nil:
+		return errors.New("node registration has no node")
+	case !r.Etcd && !r.ControlPlane && !r.Worker:
+		return fmt.Errorf("node %s has no role", r.Node.PublicIPAddress)
+	case r.Windows && (r.Etcd || r.ControlPlane || !r.Worker):
+		return fmt.Errorf("windows node %s can only be a worker", r.Node.PublicIPAddress)
+	}
+
+	return nil
+}
+
+// RegistrationCommand renders the command registering the node to the cluster of the registration token with its
+// roles, labels, taints and address overrides.
+func RegistrationCommand(token *management.ClusterRegistrationToken, registration *NodeRegistration, insecure bool) (string, error) {
+	err := registration.validate()
+	if err != nil {
+		return "", err
+	}
+
+	address := registration.Address
+	if address == "" {
+		address = registration.Node.PublicIPAddress
+	}
+	internalAddress := registration.InternalAddress
+	if internalAddress == "" {
+		internalAddress = registration.Node.PrivateIPAddress
+	}
+
+	if registration.Windows {
+		command := token.WindowsNodeCommand
+		if insecure {
+			command = token.InsecureWindowsNodeCommand
+		}
+		if command == "" {
+			return "", fmt.Errorf("registration token of cluster %s has no windows node command", token.ClusterID)
+		}
+
+		// the windows node command registers workers already
+		args := []string{command}
+		args = appendArg(args, "-NodeName", registration.NodeName, powershellQuote)
+		args = appendArg(args, "-Address", address, powershellQuote)
+		args = appendArg(args, "-InternalAddress", internalAddress, powershellQuote)
+		for _, label := range labelArgs(registration.Labels) {
+			args = appendArg(args, "-Label", label, powershellQuote)
+		}
+		for _, taint := range registration.Taints {
+			args = appendArg(args, "-Taint", taint.ToString(), powershellQuote)
+		}
+
+		return "powershell.exe -NonInteractive -Command \"" + strings.Join(args, " ") + "\"", nil
+	}
+
+	command := token.NodeCommand
+	if insecure {
+		command = token.InsecureNodeCommand
+	}
+	if command == "" {
+		return "", fmt.Errorf("registration token of cluster %s has no node command", token.ClusterID)
+	}
+
+	args := []string{command}
+	if registration.Etcd {
+		args = append(args, "--etcd")
+	}
+	if registration.ControlPlane {
+		args = append(args, "--controlplane")
+	}
+	if registration.Worker {
+		args = append(args, "--worker")
+	}
+	args = appendArg(args, "--node-name", registration.NodeName, shellQuote)
+	args = appendArg(args, "--address", address, shellQuote)
+	args = appendArg(args, "--internal-address", internalAddress, shellQuote)
+	for _, label := range labelArgs(registration.Labels) {
+		args = appendArg(args, "--label", label, shellQuote)
+	}
+	for _, taint := range registration.Taints {
+		args = appendArg(args, "--taints", taint.ToString(), shellQuote)
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// RegisterNodes registers the nodes to the custom RKE2 or K3s cluster clusterName, in fleet-default, then waits
+// until their machines are ready. The Linux nodes are registered in parallel first, the Windows workers in parallel
+// once the Linux machines are ready, since they need a ready control plane.
+func RegisterNodes(client *rancher.Client, clusterName string, registrations []NodeRegistration, opts Options) error {
+	var linux, windows []NodeRegistration
+	for _, registration := range registrations {
+		err := registration.validate()
+		if err != nil {
+			return err
+		}
+
+		if registration.Windows {
+			windows = append(windows, registration)
+		} else {
+			linux = append(linux, registration)
+		}
+	}
+
+	cluster, _, err := clusters.GetProvisioningClusterByName(client, clusterName, namespaces.FleetDefault)
+	if err != nil {
+		return err
+	}
+	if cluster.Status.ClusterName == "" {
+		return fmt.Errorf("cluster %s has no management cluster yet", clusterName)
+	}
+
+	token, err := tokenregistration.GetRegistrationToken(client, cluster.Status.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	// the cluster may have machines already, the registered nodes add to them
+	machines, err := machinepools.ListMachines(client, clusterName, "")
+	if err != nil {
+		return err
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaults.ThirtyMinuteTimeout
+	}
+	start := time.Now()
+
+	registered := len(machines)
+	for _, group := range [][]NodeRegistration{linux, windows} {
+		if len(group) == 0 {
+			continue
+		}
+
+		err = runRegistrations(token, group, opts)
+		if err != nil {
+			return err
+		}
+
+		registered += len(group)
+		err = waitForMachines(client, clusterName, cluster.Status.ClusterName, registered, timeout-time.Since(start))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runRegistrations runs the registration commands of the nodes in parallel, and returns the errors of all of them.
+func runRegistrations(token *management.ClusterRegistrationToken, registrations []NodeRegistration, opts Options) error {
+	var wait sync.WaitGroup
+	var lock sync.Mutex
+	var errs []error
+
+	for _, registration := range registrations {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			err := runRegistration(token, &registration, opts, &lock)
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("registering node %s: %w", registration.Node.PublicIPAddress, err))
+				lock.Unlock()
+			}
+		}()
+	}
+	wait.Wait()
+
+	return errors.Join(errs...)
+}
+
+func runRegistration(token *management.ClusterRegistrationToken, registration *NodeRegistration, opts Options, lock *sync.Mutex) error {
+	command, err := RegistrationCommand(token, registration, opts.Insecure)
+	if err != nil {
+		return err
+	}
+
+	prefix := "[" + registration.Node.PublicIPAddress + "] "
+	output := &lineWriter{
+		write: func(line string) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if opts.Output == nil {
+				logrus.Debugf("%s%s", prefix, line)
+				return
+			}
+			fmt.Fprintln(opts.Output, prefix+line)
+		},
+	}
+	defer output.Flush()
+
+	logrus.Infof("Registering node %s to cluster %s", registration.Node.PublicIPAddress, token.ClusterID)
+
+	return registration.Node.ExecuteCommandStream(command, output, output)
+}
+
+// waitForMachines waits until the custom cluster has at least count machines, then until all of them are ready.
+func waitForMachines(client *rancher.Client, clusterName, clusterID string, count int, timeout time.Duration) error {
+	start := time.Now()
+	err := kwait.PollUntilContextTimeout(context.TODO(), extnodes.PollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		machines, err := machinepools.ListMachines(client, clusterName, "")
+		if err != nil {
+			return false, nil
+		}
+
+		return len(machines) >= count, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %d machines in cluster %s: %w", count, clusterName, err)
+	}
+
+	return extnodes.AllMachineReady(client, clusterID, timeout-time.Since(start))
+}
+
+// lineWriter calls write with each complete line written to it.
+type lineWriter struct {
+	write  func(string)
+	buffer bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+	for {
+		line, err := w.buffer.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			w.buffer.WriteString(line)
+			return len(p), nil
+		}
+		w.write(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush writes the last line, when it does not end with a newline.
+func (w *lineWriter) Flush() {
+	if w.buffer.Len() > 0 {
+		w.write(w.buffer.String())
+		w.buffer.Reset()
+	}
+}
+
+func appendArg(args []string, flag, value string, quote func(string) string) []string {
+	if value == "" {
+		return args
+	}
+
+	return append(args, flag, quote(value))
+}
+
+func labelArgs(labels map[string]string) []string {
+	args := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		args = append(args, key+"="+labels[key])
+	}
+
+	return args
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func powershellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
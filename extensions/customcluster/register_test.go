@@ -0,0 +1,123 @@
+package customcluster
+
+import (
+	"testing"
+
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/nodes"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_RegistrationCommand(t *testing.T) {
+	token := &management.ClusterRegistrationToken{
+		ClusterID:                  "c-m-custom",
+		NodeCommand:                "curl -fL https://rancher/system-agent-install.sh | sudo sh -s - --server https://rancher",
+		InsecureNodeCommand:        "curl --insecure -fL https://rancher/system-agent-install.sh | sudo sh -s - --server https://rancher",
+		WindowsNodeCommand:         "Invoke-WebRequest https://rancher/install.ps1 -OutFile install.ps1; ./install.ps1 -Server https://rancher",
+		InsecureWindowsNodeCommand: "Invoke-WebRequest -SkipCertificateCheck https://rancher/install.ps1 -OutFile install.ps1; ./install.ps1 -Server https://rancher",
+	}
+	node := &nodes.Node{PublicIPAddress: "1.2.3.4", PrivateIPAddress: "10.0.0.4"}
+
+	tests := []struct {
+		name         string
+		token        *management.ClusterRegistrationToken
+		registration NodeRegistration
+		insecure     bool
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:         "all roles",
+			registration: NodeRegistration{Node: node, Etcd: true, ControlPlane: true, Worker: true},
+			want:         token.NodeCommand + " --etcd --controlplane --worker --address '1.2.3.4' --internal-address '10.0.0.4'",
+		},
+		{
+			name:         "etcd only",
+			registration: NodeRegistration{Node: node, Etcd: true},
+			want:         token.NodeCommand + " --etcd --address '1.2.3.4' --internal-address '10.0.0.4'",
+		},
+		{
+			name:         "control plane and worker",
+			registration: NodeRegistration{Node: node, ControlPlane: true, Worker: true},
+			want:         token.NodeCommand + " --controlplane --worker --address '1.2.3.4' --internal-address '10.0.0.4'",
+		},
+		{
+			name:         "insecure",
+			registration: NodeRegistration{Node: node, Worker: true},
+			insecure:     true,
+			want:         token.InsecureNodeCommand + " --worker --address '1.2.3.4' --internal-address '10.0.0.4'",
+		},
+		{
+			name: "overrides, labels and taints quoted",
+			registration: NodeRegistration{
+				Node:            node,
+				Worker:          true,
+				NodeName:        "node's name",
+				Address:         "5.6.7.8",
+				InternalAddress: "10.0.0.8",
+				Labels:          map[string]string{"zone": "a b", "app": "web"},
+				Taints:          []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			want: token.NodeCommand + ` --worker --node-name 'node'\''s name' --address '5.6.7.8' --internal-address '10.0.0.8'` +
+				` --label 'app=web' --label 'zone=a b' --taints 'dedicated=gpu:NoSchedule'`,
+		},
+		{
+			name: "windows worker",
+			registration: NodeRegistration{
+				Node:     node,
+				Worker:   true,
+				Windows:  true,
+				NodeName: "node's name",
+				Labels:   map[string]string{"os": "windows"},
+				Taints:   []corev1.Taint{{Key: "os", Value: "windows", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			want: `powershell.exe -NonInteractive -Command "` + token.WindowsNodeCommand +
+				` -NodeName 'node''s name' -Address '1.2.3.4' -InternalAddress '10.0.0.4' -Label 'os=windows' -Taint 'os=windows:NoSchedule'"`,
+		},
+		{
+			name:         "insecure windows worker",
+			registration: NodeRegistration{Node: node, Worker: true, Windows: true},
+			insecure:     true,
+			want: `powershell.exe -NonInteractive -Command "` + token.InsecureWindowsNodeCommand +
+				` -Address '1.2.3.4' -InternalAddress '10.0.0.4'"`,
+		},
+		{
+			name:         "windows control plane",
+			registration: NodeRegistration{Node: node, ControlPlane: true, Worker: true, Windows: true},
+			wantErr:      true,
+		},
+		{
+			name:         "no role",
+			registration: NodeRegistration{Node: node},
+			wantErr:      true,
+		},
+		{
+			name:         "no node",
+			registration: NodeRegistration{Worker: true},
+			wantErr:      true,
+		},
+		{
+			name:         "no windows node command",
+			token:        &management.ClusterRegistrationToken{ClusterID: "c-m-custom", NodeCommand: token.NodeCommand},
+			registration: NodeRegistration{Node: node, Worker: true, Windows: true},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registrationToken := token
+			if tt.token != nil {
+				registrationToken = tt.token
+			}
+
+			got, err := RegistrationCommand(registrationToken, &tt.registration, tt.insecure)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RegistrationCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RegistrationCommand() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
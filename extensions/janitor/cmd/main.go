@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/janitor"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/sirupsen/logrus"
+)
+
+// janitor deletes the resources left behind by a test run, using the rancher client configured by CATTLE_TEST_CONFIG.
+//
+//	go run ./extensions/janitor/cmd -journal /tmp/session.journal
+//	go run ./extensions/janitor/cmd -manifest /tmp/leaks.yaml
+func main() {
+	journalPath := flag.String("journal", "", "path of a session journal written by a persistent session")
+	manifestPath := flag.String("manifest", "", "path of a leak manifest written by a session cleanup")
+	flag.Parse()
+
+	if *journalPath == "" && *manifestPath == "" {
+		logrus.Fatal("one of -journal or -manifest must be set")
+	}
+
+	if err := run(*journalPath, *manifestPath); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func run(journalPath, manifestPath string) error {
+	client, err := rancher.NewClient("", session.NewSession())
+	if err != nil {
+		return err
+	}
+
+	var report *session.CleanupReport
+	if journalPath != "" {
+		report, err = janitor.CleanJournal(client, journalPath)
+	} else {
+		report, err = janitor.CleanLeakManifest(client, manifestPath)
+	}
+	if report != nil {
+		logrus.Infof("deleted %d resources, failed to delete %d resources", len(report.Cleaned), len(report.Failed))
+	}
+
+	return err
+}
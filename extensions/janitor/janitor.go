@@ -0,0 +1,93 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/pkg/clientbase"
+	"github.com/rancher/shepherd/pkg/session"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DeleteResource deletes a resource recorded by a session journal or leak manifest. Norman resources are deleted
+// through their self link, kubernetes resources through the dynamic client of the cluster they were created in.
+func DeleteResource(client *rancher.Client, resource session.Resource) error {
+	if resource.URL != "" {
+		err := client.Management.Ops.DoDelete(resource.URL)
+		if err != nil && !clientbase.IsNotFound(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	if resource.Resource == "" || resource.Name == "" {
+		return fmt.Errorf("unable to delete %s: resource is missing its kind or name", resource.String())
+	}
+
+	// resources recorded before cluster IDs were, or by clients that do not know their cluster, could be in any cluster
+	if resource.ClusterID == "" {
+		return fmt.Errorf("unable to delete %s: resource is missing its cluster ID", resource.String())
+	}
+
+	var dynamicClient dynamic.Interface
+	var err error
+	if resource.ClusterID == session.LocalClusterID {
+		dynamicClient, err = client.GetRancherDynamicClient()
+	} else {
+		dynamicClient, err = client.GetDownStreamClusterClient(resource.ClusterID)
+	}
+	if err != nil {
+		return err
+	}
+
+	groupVersionResource := schema.GroupVersionResource{
+		Group:    resource.Group,
+		Version:  resource.Version,
+		Resource: resource.Resource,
+	}
+
+	err = dynamicClient.Resource(groupVersionResource).Namespace(resource.Namespace).Delete(context.TODO(), resource.Name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// CleanJournal deletes every resource of the session journal at path that was not cleaned up by the test that
+// created it, and records them as cleaned in the journal.
+func CleanJournal(client *rancher.Client, path string) (*session.CleanupReport, error) {
+	ts, err := session.Resume(path, func(resource session.Resource) error {
+		return DeleteResource(client, resource)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.CleanupWithReport()
+}
+
+// CleanLeakManifest deletes every resource listed in the leak manifest at path, in the reverse order they are listed.
+func CleanLeakManifest(client *rancher.Client, path string) (*session.CleanupReport, error) {
+	manifest, err := session.ReadLeakManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := session.NewSession()
+	for _, resource := range manifest.Resources {
+		ts.RegisterCleanupEntry(session.CleanupEntry{
+			Resource: &resource,
+			Cleanup: func() error {
+				return DeleteResource(client, resource)
+			},
+		})
+	}
+
+	return ts.CleanupWithReport()
+}
@@ -56,9 +56,14 @@ func CreateJobAndRunKubectlCommands(clusterID, jobname string, job *batchv1.Job,
 		return err
 	}
 
+	saName, err := namegen.AppendRandomString("kubectl-command")
+	if err != nil {
+		return err
+	}
+
 	sa := &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: namegen.AppendRandomString("kubectl-command"),
+			Name: saName,
 		},
 	}
 	_, err = downClient.Resource(corev1.SchemeGroupVersion.WithResource("serviceaccounts")).Namespace(Namespace).Create(context.TODO(), unstructured.MustToUnstructured(sa), metav1.CreateOptions{})
@@ -66,9 +71,14 @@ func CreateJobAndRunKubectlCommands(clusterID, jobname string, job *batchv1.Job,
 		return err
 	}
 
+	rbName, err := namegen.AppendRandomString("rancher-install-cluster-admin")
+	if err != nil {
+		return err
+	}
+
 	rb := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: namegen.AppendRandomString("rancher-install-cluster-admin"),
+			Name: rbName,
 		},
 		Subjects: []rbacv1.Subject{
 			{
@@ -0,0 +1,295 @@
+package machinepools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/extensions/nodes"
+	"github.com/rancher/shepherd/pkg/clientbase"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	machinePoolLabel        = "rke.cattle.io/rke-machine-pool-name"
+	clusterNameLabel        = "cluster.x-k8s.io/cluster-name"
+	deleteMachineAnnotation = "cluster.x-k8s.io/delete-machine"
+)
+
+var nodeGroupVersionResource = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+
+// pollInterval is the interval the machines are polled at while waiting for them.
+var pollInterval = nodes.PollInterval
+
+// ScaleMachinePool sets the quantity of machines of the machine pool pool of the RKE2 or K3s cluster clusterName, in
+// fleet-default, then waits until its machines and the downstream nodes converge, see WaitForMachinePools.
+func ScaleMachinePool(client *rancher.Client, clusterName, pool string, quantity int32) error {
+	cluster, steveObject, err := clusters.GetProvisioningClusterByName(client, clusterName, namespaces.FleetDefault)
+	if err != nil {
+		return err
+	}
+
+	machinePool := findMachinePool(cluster, pool)
+	if machinePool == nil {
+		return fmt.Errorf("cluster %s has no machine pool %s", clusterName, pool)
+	}
+
+	logrus.Infof("Scaling machine pool %s of cluster %s to %d machines", pool, clusterName, quantity)
+	machinePool.Quantity = &quantity
+
+	_, err = clusters.UpdateK3SRKE2Cluster(client, steveObject, cluster)
+	if err != nil {
+		return err
+	}
+
+	return WaitForMachinePools(client, clusterName, defaults.ThirtyMinuteTimeout)
+}
+
+// WaitForMachinePools waits until the RKE2 or K3s cluster clusterName, in fleet-default, has as many machines and
+// downstream nodes as the quantities of its machine pools add up to, and all its machines are ready, see
+// nodes.AllMachineReady.
+func WaitForMachinePools(client *rancher.Client, clusterName string, timeout time.Duration) error {
+	start := time.Now()
+
+	var clusterID string
+	err := kwait.PollUntilContextTimeout(context.TODO(), pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		cluster, _, err := clusters.GetProvisioningClusterByName(client, clusterName, namespaces.FleetDefault)
+		if err != nil {
+			return false, err
+		}
+
+		clusterID = cluster.Status.ClusterName
+		if clusterID == "" || cluster.Spec.RKEConfig == nil {
+			return false, nil
+		}
+
+		expected := 0
+		for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+			if machinePool.Quantity != nil {
+				expected += int(*machinePool.Quantity)
+			}
+		}
+
+		machines, err := ListMachines(client, clusterName, "")
+		if err != nil {
+			return false, nil
+		}
+
+		dynamicClient, err := client.GetDownStreamClusterClient(clusterID)
+		if err != nil {
+			return false, err
+		}
+
+		downstreamNodes, err := dynamicClient.Resource(nodeGroupVersionResource).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+
+		logrus.Debugf("Cluster %s has %d machines and %d nodes, expecting %d", clusterName, len(machines), len(downstreamNodes.Items), expected)
+
+		return len(machines) == expected && len(downstreamNodes.Items) == expected, nil
+	})
+	if err != nil {
+		return fmt.Errorf("machine pools of cluster %s did not converge: %w", clusterName, err)
+	}
+
+	return nodes.AllMachineReady(client, clusterID, timeout-time.Since(start))
+}
+
+// ListMachines returns the CAPI machines of the RKE2 or K3s cluster clusterName, in fleet-default, only the ones of
+// the machine pool pool unless it is empty.
+func ListMachines(client *rancher.Client, clusterName, pool string) ([]v1.SteveAPIObject, error) {
+	selector := clusterNameLabel + "=" + clusterName
+	if pool != "" {
+		selector += "," + machinePoolLabel + "=" + pool
+	}
+
+	machines, err := client.Steve.SteveType(stevetypes.Machine).NamespacedSteveClient(namespaces.FleetDefault).List(url.Values{
+		"labelSelector": {selector},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return machines.Data, nil
+}
+
+// NodeName returns the name of the downstream node of the machine, empty until the node joined the cluster.
+func NodeName(machine *v1.SteveAPIObject) (string, error) {
+	status := &capi.MachineStatus{}
+	err := v1.ConvertToK8sType(machine.Status, status)
+	if err != nil {
+		return "", err
+	}
+	if status.NodeRef == nil {
+		return "", nil
+	}
+
+	return status.NodeRef.Name, nil
+}
+
+// DrainMachine cordons and drains the downstream node of the machine machineName of the RKE2 or K3s cluster
+// clusterName, in fleet-default, see nodes.DrainNode.
+func DrainMachine(client *rancher.Client, clusterName, machineName string, opts rkev1.DrainOptions) error {
+	cluster, _, err := clusters.GetProvisioningClusterByName(client, clusterName, namespaces.FleetDefault)
+	if err != nil {
+		return err
+	}
+
+	machine, err := getMachine(client, machineName)
+	if err != nil {
+		return err
+	}
+
+	nodeName, err := NodeName(machine)
+	if err != nil {
+		return err
+	}
+	if nodeName == "" {
+		return fmt.Errorf("machine %s has no node to drain", machineName)
+	}
+
+	return nodes.DrainNode(client, cluster.Status.ClusterName, nodeName, opts)
+}
+
+// DeleteMachine removes the machine machineName from its machine pool of the RKE2 or K3s cluster clusterName, in
+// fleet-default, scaling the pool down by one, then waits until the machine pools converge. The node of the machine
+// is drained first when drainOptions is not nil.
+func DeleteMachine(client *rancher.Client, clusterName, machineName string, drainOptions *rkev1.DrainOptions) error {
+	machine, err := prepareMachineRemoval(client, clusterName, machineName, drainOptions)
+	if err != nil {
+		return err
+	}
+
+	pool := machine.Labels[machinePoolLabel]
+	cluster, _, err := clusters.GetProvisioningClusterByName(client, clusterName, namespaces.FleetDefault)
+	if err != nil {
+		return err
+	}
+
+	machinePool := findMachinePool(cluster, pool)
+	if machinePool == nil || machinePool.Quantity == nil || *machinePool.Quantity == 0 {
+		return fmt.Errorf("machine %s is not in a machine pool of cluster %s", machineName, clusterName)
+	}
+
+	// the annotation has the machine set remove this machine rather than another one when the pool is scaled down
+	machine, err = getMachine(client, machineName)
+	if err != nil {
+		return err
+	}
+
+	metadata, _ := machine.JSONResp["metadata"].(map[string]any)
+	if metadata == nil {
+		return fmt.Errorf("machine %s has no metadata", machineName)
+	}
+	metadata["annotations"] = withAnnotation(machine.Annotations, deleteMachineAnnotation, "yes")
+
+	_, err = client.Steve.SteveType(stevetypes.Machine).Update(machine, machine.JSONResp)
+	if err != nil {
+		return err
+	}
+
+	err = ScaleMachinePool(client, clusterName, pool, *machinePool.Quantity-1)
+	if err != nil {
+		return err
+	}
+
+	return waitForMachineDeleted(client, machine.ID)
+}
+
+// ReplaceMachine deletes the machine machineName of the RKE2 or K3s cluster clusterName, in fleet-default, for its
+// machine pool to create a new one, and waits until the machine pools converge. The node of the machine is drained
+// first when drainOptions is not nil.
+func ReplaceMachine(client *rancher.Client, clusterName, machineName string, drainOptions *rkev1.DrainOptions) error {
+	machine, err := prepareMachineRemoval(client, clusterName, machineName, drainOptions)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Replacing machine %s of cluster %s", machineName, clusterName)
+	err = client.Steve.SteveType(stevetypes.Machine).Delete(machine)
+	if err != nil {
+		return err
+	}
+
+	err = waitForMachineDeleted(client, machine.ID)
+	if err != nil {
+		return err
+	}
+
+	return WaitForMachinePools(client, clusterName, defaults.ThirtyMinuteTimeout)
+}
+
+// prepareMachineRemoval returns the machine machineName of the cluster, once its node is drained when drainOptions is
+// not nil.
+func prepareMachineRemoval(client *rancher.Client, clusterName, machineName string, drainOptions *rkev1.DrainOptions) (*v1.SteveAPIObject, error) {
+	machine, err := getMachine(client, machineName)
+	if err != nil {
+		return nil, err
+	}
+	if machine.Labels[clusterNameLabel] != clusterName {
+		return nil, fmt.Errorf("machine %s is not a machine of cluster %s", machineName, clusterName)
+	}
+
+	if drainOptions != nil {
+		err = DrainMachine(client, clusterName, machineName, *drainOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return machine, nil
+}
+
+func getMachine(client *rancher.Client, machineName string) (*v1.SteveAPIObject, error) {
+	return client.Steve.SteveType(stevetypes.Machine).ByID(namespaces.FleetDefault + "/" + machineName)
+}
+
+func waitForMachineDeleted(client *rancher.Client, machineID string) error {
+	return kwait.PollUntilContextTimeout(context.TODO(), pollInterval, defaults.FifteenMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := client.Steve.SteveType(stevetypes.Machine).ByID(machineID)
+		if clientbase.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, nil
+	})
+}
+
+func findMachinePool(cluster *apisV1.Cluster, pool string) *apisV1.RKEMachinePool {
+	if cluster.Spec.RKEConfig == nil {
+		return nil
+	}
+
+	for i := range cluster.Spec.RKEConfig.MachinePools {
+		if cluster.Spec.RKEConfig.MachinePools[i].Name == pool {
+			return &cluster.Spec.RKEConfig.MachinePools[i]
+		}
+	}
+
+	return nil
+}
+
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	updated := map[string]string{key: value}
+	for k, v := range annotations {
+		if k != key {
+			updated[k] = v
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,255 @@
+package machinepools
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/fakeserver"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/pkg/clientbase"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	clusterName = "rke2"
+	poolName    = "pool"
+)
+
+func init() {
+	pollInterval = 10 * time.Millisecond
+}
+
+// newClient returns a client of a fake server holding the cluster rke2, whose machine pool has quantity machines,
+// their CAPI machines machine0, machine1... and their downstream nodes. The configuration of the fake server is written
+// to CATTLE_TEST_CONFIG, as the updates of the cluster log in again.
+func newClient(t *testing.T, quantity int) (*fakeserver.Server, *rancher.Client) {
+	server := fakeserver.New()
+	t.Cleanup(server.Close)
+
+	t.Setenv(config.ConfigEnvironmentKey, filepath.Join(t.TempDir(), "config.yaml"))
+	err := server.WriteConfig()
+	if err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+
+	err = server.AddObjects(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "provisioning.cattle.io/v1",
+		"kind":       "Cluster",
+		"metadata":   map[string]any{"name": clusterName, "namespace": namespaces.FleetDefault},
+		"spec": map[string]any{
+			"rkeConfig": map[string]any{
+				"machinePools": []any{map[string]any{"name": poolName, "quantity": int64(quantity), "workerRole": true}},
+			},
+		},
+		"status": map[string]any{"clusterName": "c-m-rke2"},
+	}})
+	if err != nil {
+		t.Fatalf("AddObjects() error = %v", err)
+	}
+
+	for i := range quantity {
+		addMachine(t, server, "machine"+strconv.Itoa(i))
+	}
+
+	client, err := server.NewClient(session.NewSession())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	return server, client
+}
+
+// addMachine adds the CAPI machine name of the machine pool and its downstream node, named after it.
+func addMachine(t *testing.T, server *fakeserver.Server, name string) {
+	err := server.AddObjects(&unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "cluster.x-k8s.io/v1beta1",
+		"kind":       "Machine",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": namespaces.FleetDefault,
+			"labels":    map[string]any{clusterNameLabel: clusterName, machinePoolLabel: poolName},
+		},
+		"spec":   map[string]any{"clusterName": clusterName},
+		"status": map[string]any{"nodeRef": map[string]any{"kind": "Node", "name": name}},
+	}}, &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   map[string]any{"name": name},
+	}})
+	if err != nil {
+		t.Errorf("AddObjects() error = %v", err)
+	}
+}
+
+// deleteMachine deletes the CAPI machine name and its downstream node.
+func deleteMachine(client *rancher.Client, name string) error {
+	machine, err := getMachine(client, name)
+	if err != nil {
+		return err
+	}
+
+	err = client.Steve.SteveType(stevetypes.Machine).Delete(machine)
+	if err != nil {
+		return err
+	}
+
+	return deleteNode(client, name)
+}
+
+// deleteNode deletes the downstream node name, as CAPI does once its machine is deleted.
+func deleteNode(client *rancher.Client, name string) error {
+	node, err := client.Steve.SteveType("node").ByID(name)
+	if err != nil {
+		return err
+	}
+
+	return client.Steve.SteveType("node").Delete(node)
+}
+
+func poolQuantity(t *testing.T, client *rancher.Client) int32 {
+	cluster, _, err := clusters.GetProvisioningClusterByName(client, clusterName, namespaces.FleetDefault)
+	if err != nil {
+		t.Fatalf("GetProvisioningClusterByName() error = %v", err)
+	}
+
+	return *findMachinePool(cluster, poolName).Quantity
+}
+
+func machineNames(t *testing.T, client *rancher.Client) []string {
+	machines, err := ListMachines(client, clusterName, poolName)
+	if err != nil {
+		t.Fatalf("ListMachines() error = %v", err)
+	}
+
+	var names []string
+	for _, machine := range machines {
+		names = append(names, machine.Name)
+	}
+
+	return names
+}
+
+func Test_ScaleMachinePool(t *testing.T) {
+	server, client := newClient(t, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// emulates the machine set creating the machine of the scaled up pool
+	go func() {
+		for ctx.Err() == nil {
+			if len(machineNames(t, client)) == 1 && poolQuantity(t, client) == 2 {
+				addMachine(t, server, "machine1")
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	err := ScaleMachinePool(client, clusterName, poolName, 2)
+	if err != nil {
+		t.Fatalf("ScaleMachinePool() error = %v", err)
+	}
+
+	if quantity := poolQuantity(t, client); quantity != 2 {
+		t.Errorf("quantity = %d, want 2", quantity)
+	}
+	if names := machineNames(t, client); len(names) != 2 {
+		t.Errorf("machines = %v, want 2", names)
+	}
+}
+
+func Test_ScaleMachinePoolUnknown(t *testing.T) {
+	_, client := newClient(t, 1)
+
+	err := ScaleMachinePool(client, clusterName, "unknown", 2)
+	if err == nil {
+		t.Error("ScaleMachinePool() error = nil, want the unknown pool")
+	}
+}
+
+func Test_ReplaceMachine(t *testing.T) {
+	server, client := newClient(t, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// emulates the machine set replacing the deleted machine
+	go func() {
+		for ctx.Err() == nil {
+			_, err := getMachine(client, "machine0")
+			if clientbase.IsNotFound(err) {
+				err = deleteNode(client, "machine0")
+				if err != nil {
+					t.Errorf("deleteNode() error = %v", err)
+				}
+				addMachine(t, server, "machine1")
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	err := ReplaceMachine(client, clusterName, "machine0", nil)
+	if err != nil {
+		t.Fatalf("ReplaceMachine() error = %v", err)
+	}
+
+	if names := machineNames(t, client); len(names) != 1 || names[0] != "machine1" {
+		t.Errorf("machines = %v, want the replacement machine1", names)
+	}
+	if quantity := poolQuantity(t, client); quantity != 1 {
+		t.Errorf("quantity = %d, want 1", quantity)
+	}
+}
+
+func Test_DeleteMachine(t *testing.T) {
+	_, client := newClient(t, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// emulates the machine set removing the machine annotated for deletion once the pool is scaled down
+	go func() {
+		for ctx.Err() == nil {
+			machine, err := getMachine(client, "machine0")
+			if err == nil && machine.Annotations[deleteMachineAnnotation] == "yes" && poolQuantity(t, client) == 1 {
+				err = deleteMachine(client, "machine0")
+				if err != nil {
+					t.Errorf("deleteMachine() error = %v", err)
+				}
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	err := DeleteMachine(client, clusterName, "machine0", nil)
+	if err != nil {
+		t.Fatalf("DeleteMachine() error = %v", err)
+	}
+
+	if names := machineNames(t, client); len(names) != 1 || names[0] != "machine1" {
+		t.Errorf("machines = %v, want machine1 left", names)
+	}
+	if quantity := poolQuantity(t, client); quantity != 1 {
+		t.Errorf("quantity = %d, want 1", quantity)
+	}
+}
+
+func Test_DeleteMachineOfAnotherCluster(t *testing.T) {
+	_, client := newClient(t, 1)
+
+	err := DeleteMachine(client, "other", "machine0", nil)
+	if err == nil {
+		t.Error("DeleteMachine() error = nil, want the machine of another cluster rejected")
+	}
+}
@@ -0,0 +1,226 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+var (
+	nodeGroupVersionResource = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	podGroupVersionResource  = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+)
+
+// CordonNode marks the node nodeName of the downstream cluster clusterID unschedulable, or schedulable again when
+// unschedulable is false.
+func CordonNode(client *rancher.Client, clusterID, nodeName string, unschedulable bool) error {
+	dynamicClient, err := client.GetDownStreamClusterClient(clusterID)
+	if err != nil {
+		return err
+	}
+
+	return cordonNode(dynamicClient, nodeName, unschedulable)
+}
+
+func cordonNode(dynamicClient dynamic.Interface, nodeName string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := dynamicClient.Resource(nodeGroupVersionResource).Patch(context.TODO(), nodeName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+
+	return err
+}
+
+// DrainNode cordons the node nodeName of the downstream cluster clusterID then evicts, or deletes when
+// opts.DisableEviction is set, its pods the way kubectl drain does: DaemonSet pods are left unless opts.IgnoreDaemonSets
+// is false, which fails, and pods not managed by a controller or using emptyDir volumes fail the drain unless
+// opts.Force and opts.DeleteEmptyDirData are set. It waits for the pods to be gone, for opts.Timeout seconds or five
+// minutes when it is not set. The pods are given opts.GracePeriod seconds to terminate, their own termination grace
+// period when it is not positive: a zero grace period, which would kill the pods, is treated as unset.
+func DrainNode(client *rancher.Client, clusterID, nodeName string, opts rkev1.DrainOptions) error {
+	dynamicClient, err := client.GetDownStreamClusterClient(clusterID)
+	if err != nil {
+		return err
+	}
+
+	return drainNode(dynamicClient, nodeName, opts)
+}
+
+func drainNode(dynamicClient dynamic.Interface, nodeName string, opts rkev1.DrainOptions) error {
+	err := cordonNode(dynamicClient, nodeName, true)
+	if err != nil {
+		return err
+	}
+
+	pods, err := podsToDrain(dynamicClient, nodeName, opts)
+	if err != nil {
+		return err
+	}
+
+	timeout := defaults.FiveMinuteTimeout
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+
+	var gracePeriod *int64
+	if opts.GracePeriod > 0 {
+		seconds := int64(opts.GracePeriod)
+		gracePeriod = &seconds
+	}
+
+	logrus.Infof("Draining %d pods from node %s...", len(pods), nodeName)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, pod := range pods {
+		err = wait.PollUntilContextCancel(ctx, defaults.FiveSecondTimeout, true, func(ctx context.Context) (bool, error) {
+			err := removePod(ctx, dynamicClient, pod, gracePeriod, opts.DisableEviction)
+			switch {
+			case err == nil, apierrors.IsNotFound(err):
+				return true, nil
+			case apierrors.IsTooManyRequests(err):
+				// a pod disruption budget does not allow the eviction yet
+				logrus.Debugf("Waiting to evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+				return false, nil
+			default:
+				return false, err
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("removing pod %s/%s from node %s: %w", pod.Namespace, pod.Name, nodeName, err)
+		}
+	}
+
+	for _, pod := range pods {
+		err = wait.PollUntilContextCancel(ctx, defaults.FiveSecondTimeout, true, func(ctx context.Context) (bool, error) {
+			current, err := dynamicClient.Resource(podGroupVersionResource).Namespace(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			if err != nil {
+				return false, err
+			}
+
+			if opts.SkipWaitForDeleteTimeoutSeconds > 0 && current.GetDeletionTimestamp() != nil {
+				deleted := current.GetDeletionTimestamp().Time
+				if time.Since(deleted) > time.Duration(opts.SkipWaitForDeleteTimeoutSeconds)*time.Second {
+					return true, nil
+				}
+			}
+
+			return current.GetUID() != pod.UID, nil
+		})
+		if err != nil {
+			return fmt.Errorf("waiting for pod %s/%s to leave node %s: %w", pod.Namespace, pod.Name, nodeName, err)
+		}
+	}
+
+	logrus.Infof("Node %s is drained", nodeName)
+
+	return nil
+}
+
+// podsToDrain returns the pods of the node to remove, or an error listing the pods that prevent the drain.
+func podsToDrain(dynamicClient dynamic.Interface, nodeName string, opts rkev1.DrainOptions) ([]corev1.Pod, error) {
+	podList, err := dynamicClient.Resource(podGroupVersionResource).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + nodeName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreDaemonSets := opts.IgnoreDaemonSets == nil || *opts.IgnoreDaemonSets
+
+	var pods []corev1.Pod
+	var errs []error
+	for _, item := range podList.Items {
+		pod := corev1.Pod{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &pod)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+
+		finished := pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+		controller := metav1.GetControllerOf(&pod)
+		switch {
+		case finished:
+		case controller != nil && controller.Kind == "DaemonSet":
+			if !ignoreDaemonSets {
+				errs = append(errs, fmt.Errorf("pod %s/%s is managed by a DaemonSet", pod.Namespace, pod.Name))
+			}
+			continue
+		case controller == nil && !opts.Force:
+			errs = append(errs, fmt.Errorf("pod %s/%s is not managed by a controller", pod.Namespace, pod.Name))
+			continue
+		}
+
+		if !finished && !opts.DeleteEmptyDirData {
+			for _, volume := range pod.Spec.Volumes {
+				if volume.EmptyDir != nil {
+					errs = append(errs, fmt.Errorf("pod %s/%s uses emptyDir volume %s", pod.Namespace, pod.Name, volume.Name))
+					break
+				}
+			}
+		}
+
+		pods = append(pods, pod)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("cannot drain node %s: %w", nodeName, errors.Join(errs...))
+	}
+
+	return pods, nil
+}
+
+// removePod evicts the pod, or deletes it when disableEviction is set.
+func removePod(ctx context.Context, dynamicClient dynamic.Interface, pod corev1.Pod, gracePeriod *int64, disableEviction bool) error {
+	pods := dynamicClient.Resource(podGroupVersionResource).Namespace(pod.Namespace)
+	deleteOptions := metav1.DeleteOptions{
+		GracePeriodSeconds: gracePeriod,
+		Preconditions:      &metav1.Preconditions{UID: &pod.UID},
+	}
+
+	if disableEviction {
+		return pods.Delete(ctx, pod.Name, deleteOptions)
+	}
+
+	eviction, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&policyv1.Eviction{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyv1.SchemeGroupVersion.String(),
+			Kind:       "Eviction",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &deleteOptions,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pods.Create(ctx, &unstructured.Unstructured{Object: eviction}, metav1.CreateOptions{}, "eviction")
+
+	return err
+}
@@ -0,0 +1,248 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const nodeName = "node1"
+
+// newPod returns a running pod of the node, managed by a controller of kind unless it is empty.
+func newPod(name, kind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if kind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name + "-owner", Controller: &controller}}
+	}
+
+	return pod
+}
+
+func withEmptyDir(pod *corev1.Pod) *corev1.Pod {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name:         "cache",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	return pod
+}
+
+func withPhase(pod *corev1.Pod, phase corev1.PodPhase) *corev1.Pod {
+	pod.Status.Phase = phase
+
+	return pod
+}
+
+func mirror(pod *corev1.Pod) *corev1.Pod {
+	pod.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+
+	return pod
+}
+
+// newDynamicClient returns a fake dynamic client holding the node and the pods, evicting pods the way the
+// apiserver does. The grace periods of the evictions and deletions are recorded in gracePeriods, by pod name.
+func newDynamicClient(t *testing.T, gracePeriods map[string]*int64, pods ...*corev1.Pod) *fake.FakeDynamicClient {
+	objects := []runtime.Object{toUnstructured(t, &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+	})}
+	for _, pod := range pods {
+		objects = append(objects, toUnstructured(t, pod))
+	}
+
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if create.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+
+		eviction := &policyv1.Eviction{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(create.GetObject().(*unstructured.Unstructured).Object, eviction)
+		if err != nil {
+			return true, nil, err
+		}
+		gracePeriods[eviction.Name] = eviction.DeleteOptions.GracePeriodSeconds
+
+		return true, nil, client.Tracker().Delete(podGroupVersionResource, eviction.Namespace, eviction.Name)
+	})
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleteAction := action.(k8stesting.DeleteActionImpl)
+		gracePeriods[deleteAction.Name] = deleteAction.DeleteOptions.GracePeriodSeconds
+
+		return false, nil, nil
+	})
+
+	return client
+}
+
+func toUnstructured(t *testing.T, obj runtime.Object) *unstructured.Unstructured {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatalf("ToUnstructured() error = %v", err)
+	}
+
+	return &unstructured.Unstructured{Object: data}
+}
+
+func Test_PodsToDrain(t *testing.T) {
+	ignoreDaemonSets := false
+
+	tests := []struct {
+		name    string
+		pods    []*corev1.Pod
+		opts    rkev1.DrainOptions
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "managed pods",
+			pods: []*corev1.Pod{newPod("web", "ReplicaSet"), newPod("db", "StatefulSet")},
+			want: []string{"db", "web"},
+		},
+		{
+			name: "DaemonSet pods are left",
+			pods: []*corev1.Pod{newPod("web", "ReplicaSet"), newPod("agent", "DaemonSet")},
+			want: []string{"web"},
+		},
+		{
+			name:    "DaemonSet pods fail unless ignored",
+			pods:    []*corev1.Pod{newPod("agent", "DaemonSet")},
+			opts:    rkev1.DrainOptions{IgnoreDaemonSets: &ignoreDaemonSets},
+			wantErr: "pod default/agent is managed by a DaemonSet",
+		},
+		{
+			name:    "unmanaged pods fail",
+			pods:    []*corev1.Pod{newPod("web", "ReplicaSet"), newPod("bare", "")},
+			wantErr: "pod default/bare is not managed by a controller",
+		},
+		{
+			name: "unmanaged pods are removed when forced",
+			pods: []*corev1.Pod{newPod("bare", "")},
+			opts: rkev1.DrainOptions{Force: true},
+			want: []string{"bare"},
+		},
+		{
+			name: "finished unmanaged pods are removed",
+			pods: []*corev1.Pod{withPhase(newPod("job", ""), corev1.PodSucceeded)},
+			want: []string{"job"},
+		},
+		{
+			name:    "emptyDir pods fail",
+			pods:    []*corev1.Pod{withEmptyDir(newPod("web", "ReplicaSet"))},
+			wantErr: "pod default/web uses emptyDir volume cache",
+		},
+		{
+			name: "emptyDir pods are removed with their data",
+			pods: []*corev1.Pod{withEmptyDir(newPod("web", "ReplicaSet"))},
+			opts: rkev1.DrainOptions{DeleteEmptyDirData: true},
+			want: []string{"web"},
+		},
+		{
+			name: "finished emptyDir pods are removed",
+			pods: []*corev1.Pod{withPhase(withEmptyDir(newPod("web", "ReplicaSet")), corev1.PodFailed)},
+			want: []string{"web"},
+		},
+		{
+			name: "mirror pods are left",
+			pods: []*corev1.Pod{mirror(newPod("kube-apiserver", "")), newPod("web", "ReplicaSet")},
+			want: []string{"web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newDynamicClient(t, map[string]*int64{}, tt.pods...)
+
+			pods, err := podsToDrain(client, nodeName, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("podsToDrain() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("podsToDrain() error = %v", err)
+			}
+
+			var names []string
+			for _, pod := range pods {
+				names = append(names, pod.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("podsToDrain() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func Test_DrainNode(t *testing.T) {
+	gracePeriod := int64(30)
+
+	tests := []struct {
+		name            string
+		opts            rkev1.DrainOptions
+		wantGracePeriod *int64
+	}{
+		{name: "eviction, zero grace period is unset"},
+		{name: "deletion", opts: rkev1.DrainOptions{DisableEviction: true}},
+		{name: "negative grace period is unset", opts: rkev1.DrainOptions{GracePeriod: -1}},
+		{name: "grace period", opts: rkev1.DrainOptions{GracePeriod: 30}, wantGracePeriod: &gracePeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gracePeriods := map[string]*int64{}
+			client := newDynamicClient(t, gracePeriods, newPod("web", "ReplicaSet"), newPod("agent", "DaemonSet"))
+
+			err := drainNode(client, nodeName, tt.opts)
+			if err != nil {
+				t.Fatalf("drainNode() error = %v", err)
+			}
+
+			node, err := client.Resource(nodeGroupVersionResource).Get(context.Background(), nodeName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if unschedulable, _, _ := unstructured.NestedBool(node.Object, "spec", "unschedulable"); !unschedulable {
+				t.Error("node is schedulable, want it cordoned")
+			}
+
+			pods, err := client.Resource(podGroupVersionResource).Namespace("default").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(pods.Items) != 1 || pods.Items[0].GetName() != "agent" {
+				t.Errorf("pods = %v, want only the DaemonSet pod left", pods.Items)
+			}
+
+			gracePeriod, ok := gracePeriods["web"]
+			if !ok {
+				t.Fatal("pod web was neither evicted nor deleted")
+			}
+			if (gracePeriod == nil) != (tt.wantGracePeriod == nil) || (gracePeriod != nil && *gracePeriod != *tt.wantGracePeriod) {
+				t.Errorf("grace period = %v, want %v", gracePeriod, tt.wantGracePeriod)
+			}
+		})
+	}
+}
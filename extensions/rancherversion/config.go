@@ -1,5 +1,7 @@
 package rancherversion
 
+import "github.com/rancher/shepherd/pkg/config"
+
 const (
 	ConfigurationFileKey = "prime"
 )
@@ -11,3 +13,7 @@ type Config struct {
 	RancherVersion string `json:"rancherVersion" yaml:"rancherVersion"`
 	Registry       string `json:"registry" yaml:"registry"`
 }
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+}
@@ -0,0 +1,70 @@
+package sweeper
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/go-multierror"
+	shepherdec2 "github.com/rancher/shepherd/clients/ec2"
+	"github.com/rancher/shepherd/pkg/session"
+)
+
+// ec2InstanceType is the session.Resource type of EC2 instances.
+const ec2InstanceType = "ec2Instance"
+
+// SweepEC2 terminates the EC2 instances, in the region of client, tagged with the run labels of a stale run. The
+// volumes created with the instances are deleted with them.
+func SweepEC2(client *shepherdec2.Client, opts Options) (*Report, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: aws.StringSlice([]string{session.RunIDLabel}),
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"}),
+			},
+		},
+	}
+
+	var stale []*ec2.Instance
+	err := client.SVC.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				labels := map[string]string{}
+				for _, tag := range instance.Tags {
+					labels[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+				}
+				if opts.Stale(labels) {
+					stale = append(stale, instance)
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the EC2 instances: %w", err)
+	}
+
+	report := &Report{}
+	var errs error
+	for _, instance := range stale {
+		resource := session.Resource{
+			Type: ec2InstanceType,
+			ID:   aws.StringValue(instance.InstanceId),
+		}
+		err := report.sweep(&opts, resource, func() error {
+			_, err := client.SVC.TerminateInstances(&ec2.TerminateInstancesInput{
+				InstanceIds: []*string{instance.InstanceId},
+			})
+			return err
+		})
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return report, errs
+}
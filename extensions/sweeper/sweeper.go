@@ -0,0 +1,149 @@
+package sweeper
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultResources are the resources created by the tests, in the order they are swept: the clusters before the
+// users and the projects, the namespaces last.
+var DefaultResources = []schema.GroupVersionResource{
+	{Group: "provisioning.cattle.io", Version: "v1", Resource: "clusters"},
+	{Group: "management.cattle.io", Version: "v3", Resource: "clusters"},
+	{Group: "management.cattle.io", Version: "v3", Resource: "users"},
+	{Group: "management.cattle.io", Version: "v3", Resource: "projects"},
+	{Group: "", Version: "v1", Resource: "secrets"},
+	{Group: "", Version: "v1", Resource: "namespaces"},
+}
+
+// DefaultMaxAge is the MaxAge of the Options that do not set a positive one, long enough for the runs of the same
+// day to be left alone.
+const DefaultMaxAge = 24 * time.Hour
+
+// Options selects the resources swept, the ones labelled with a session.RunIDLabel of a stale run.
+type Options struct {
+	// MaxAge is the age of the runs whose resources are swept, the age of a run is read from its
+	// session.RunStartedLabel. Resources without a valid start are never swept. DefaultMaxAge is used when it is not
+	// positive.
+	MaxAge time.Duration
+	// KeepRunIDs are the runs whose resources are never swept, the current run is always kept.
+	KeepRunIDs []string
+	// DryRun only reports the resources that would be swept.
+	DryRun bool
+	// Now returns the current time, time.Now by default.
+	Now func() time.Time
+}
+
+// Report lists the resources swept, or that would be swept on a dry run, and the ones that could not be.
+type Report struct {
+	Swept  []session.Resource      `json:"swept" yaml:"swept"`
+	Failed []session.CleanupResult `json:"failed,omitempty" yaml:"failed,omitempty"`
+}
+
+// Stale returns whether the labels, or EC2 tags, are the ones of a run started more than MaxAge ago that is not kept.
+func (o *Options) Stale(labels map[string]string) bool {
+	runID, ok := labels[session.RunIDLabel]
+	if !ok || runID == session.RunID() || slices.Contains(o.KeepRunIDs, runID) {
+		return false
+	}
+
+	started, err := session.ParseRunStarted(labels[session.RunStartedLabel])
+	if err != nil {
+		return false
+	}
+
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+
+	maxAge := o.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	return now().Sub(started) > maxAge
+}
+
+// sweep deletes resource with remove, unless it is a dry run, and records the outcome in the report.
+func (r *Report) sweep(opts *Options, resource session.Resource, remove func() error) error {
+	if opts.DryRun {
+		logrus.Infof("[sweeper] would delete %s", resource.String())
+		r.Swept = append(r.Swept, resource)
+		return nil
+	}
+
+	start := time.Now()
+	err := remove()
+	if err != nil {
+		r.Failed = append(r.Failed, session.CleanupResult{
+			Resource: &resource,
+			Attempts: 1,
+			Duration: time.Since(start),
+			Error:    err.Error(),
+		})
+		return fmt.Errorf("failed to sweep %s: %w", resource.String(), err)
+	}
+
+	logrus.Infof("[sweeper] deleted %s", resource.String())
+	r.Swept = append(r.Swept, resource)
+	return nil
+}
+
+// SweepKubernetes deletes the objects of resources, e.g. DefaultResources, carrying the run labels of a stale run
+// from the cluster of client, clusterID is only reported. It keeps sweeping when a deletion fails, and returns the
+// aggregated errors.
+func SweepKubernetes(ctx context.Context, client dynamic.Interface, clusterID string, resources []schema.GroupVersionResource, opts Options) (*Report, error) {
+	report := &Report{}
+	var errs error
+
+	for _, gvr := range resources {
+		list, err := client.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: session.RunIDLabel})
+		if apierrors.IsNotFound(err) {
+			// the resource is not served by this cluster, e.g. the provisioning clusters of a downstream cluster
+			continue
+		}
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to list %s: %w", gvr.String(), err))
+			continue
+		}
+
+		for _, obj := range list.Items {
+			if !opts.Stale(obj.GetLabels()) {
+				continue
+			}
+
+			resource := session.Resource{
+				Group:     gvr.Group,
+				Version:   gvr.Version,
+				Kind:      obj.GetKind(),
+				Resource:  gvr.Resource,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				ClusterID: clusterID,
+			}
+			err := report.sweep(&opts, resource, func() error {
+				err := client.Resource(gvr).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+				if apierrors.IsNotFound(err) {
+					return nil
+				}
+				return err
+			})
+			if err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+
+	return report, errs
+}
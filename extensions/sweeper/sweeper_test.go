@@ -0,0 +1,87 @@
+package sweeper
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/pkg/session"
+)
+
+func Test_Stale(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	startedAgo := func(age time.Duration) string {
+		return strconv.FormatInt(now.Add(-age).Unix(), 10)
+	}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		labels map[string]string
+		want   bool
+	}{
+		{
+			name:   "old run",
+			opts:   Options{MaxAge: time.Hour},
+			labels: map[string]string{session.RunIDLabel: "old", session.RunStartedLabel: startedAgo(2 * time.Hour)},
+			want:   true,
+		},
+		{
+			name:   "recent run",
+			opts:   Options{MaxAge: time.Hour},
+			labels: map[string]string{session.RunIDLabel: "recent", session.RunStartedLabel: startedAgo(time.Minute)},
+			want:   false,
+		},
+		{
+			name:   "kept run",
+			opts:   Options{MaxAge: time.Hour, KeepRunIDs: []string{"kept"}},
+			labels: map[string]string{session.RunIDLabel: "kept", session.RunStartedLabel: startedAgo(2 * time.Hour)},
+			want:   false,
+		},
+		{
+			name:   "current run",
+			opts:   Options{MaxAge: time.Hour},
+			labels: map[string]string{session.RunIDLabel: session.RunID(), session.RunStartedLabel: startedAgo(2 * time.Hour)},
+			want:   false,
+		},
+		{
+			name:   "no run ID",
+			opts:   Options{MaxAge: time.Hour},
+			labels: map[string]string{session.RunStartedLabel: startedAgo(2 * time.Hour)},
+			want:   false,
+		},
+		{
+			name:   "missing start",
+			opts:   Options{MaxAge: time.Hour},
+			labels: map[string]string{session.RunIDLabel: "old"},
+			want:   false,
+		},
+		{
+			name:   "invalid start",
+			opts:   Options{MaxAge: time.Hour},
+			labels: map[string]string{session.RunIDLabel: "old", session.RunStartedLabel: "yesterday"},
+			want:   false,
+		},
+		{
+			name:   "default max age keeps a recent run",
+			opts:   Options{},
+			labels: map[string]string{session.RunIDLabel: "recent", session.RunStartedLabel: startedAgo(time.Hour)},
+			want:   false,
+		},
+		{
+			name:   "default max age sweeps an old run",
+			opts:   Options{},
+			labels: map[string]string{session.RunIDLabel: "old", session.RunStartedLabel: startedAgo(2 * DefaultMaxAge)},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Now = func() time.Time { return now }
+			if got := tt.opts.Stale(tt.labels); got != tt.want {
+				t.Errorf("Stale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -35,9 +35,13 @@ const (
 var timeout = int64(60 * 3)
 
 // UserConfig sets and returns username and password of the user
-func UserConfig() (user *management.User) {
+func UserConfig() (user *management.User, err error) {
 	enabled := true
-	username := namegen.AppendRandomString("testuser-")
+	username, err := namegen.AppendRandomString("testuser-")
+	if err != nil {
+		return nil, err
+	}
+
 	testpassword := password.GenerateUserPassword("testpass-")
 	user = &management.User{
 		Username: username,
@@ -2,6 +2,7 @@ package clientbase
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -52,16 +53,22 @@ type APIBaseClient struct {
 }
 
 type ClientOpts struct {
-	URL        string
-	AccessKey  string
-	SecretKey  string
-	TokenKey   string
-	Timeout    time.Duration
-	Backoff    *wait.Backoff
-	HTTPClient *http.Client
-	WSDialer   *websocket.Dialer
-	CACerts    string
-	Insecure   bool
+	// Context is the root context of every request made by the client, unless a context is given to the operation.
+	Context   context.Context
+	URL       string
+	AccessKey string
+	SecretKey string
+	TokenKey  string
+	Timeout   time.Duration
+	Backoff   *wait.Backoff
+	// RetryPolicy configures the retries of requests failing with transient errors, requests are not retried if nil.
+	RetryPolicy *RetryPolicy
+	HTTPClient  *http.Client
+	// WrapTransport wraps the transport of the HTTP client once it is configured, e.g. to record the client traffic.
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+	WSDialer      *websocket.Dialer
+	CACerts       string
+	Insecure      bool
 }
 
 func (c *ClientOpts) getAuthHeader() string {
@@ -181,10 +188,15 @@ func NewAPIClient(opts *ClientOpts) (APIBaseClient, error) {
 		}
 	}
 
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	var baseClient APIBaseClient
 	var previousTypesLength int
-	err := wait.ExponentialBackoff(*opts.Backoff, func() (done bool, err error) {
-		baseClient, err = newAPIClientInternal(opts)
+	err := wait.ExponentialBackoffWithContext(ctx, *opts.Backoff, func(ctx context.Context) (done bool, err error) {
+		baseClient, err = newAPIClientInternal(ctx, opts)
 		if err != nil {
 			if apiErr, ok := err.(*APIError); ok {
 				if apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden {
@@ -210,7 +222,7 @@ func NewAPIClient(opts *ClientOpts) (APIBaseClient, error) {
 	return baseClient, nil
 }
 
-func newAPIClientInternal(opts *ClientOpts) (APIBaseClient, error) {
+func newAPIClientInternal(ctx context.Context, opts *ClientOpts) (APIBaseClient, error) {
 	var err error
 
 	result := APIBaseClient{
@@ -269,7 +281,16 @@ func newAPIClientInternal(opts *ClientOpts) (APIBaseClient, error) {
 		client.Transport = tr
 	}
 
-	req, err := http.NewRequest("GET", opts.URL, nil)
+	var tlsConfig *tls.Config
+	if ht, ok := client.Transport.(*http.Transport); ok {
+		tlsConfig = ht.TLSClientConfig
+	}
+
+	if opts.WrapTransport != nil {
+		client.Transport = opts.WrapTransport(client.Transport)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", opts.URL, nil)
 	if err != nil {
 		return result, err
 	}
@@ -293,7 +314,7 @@ func newAPIClientInternal(opts *ClientOpts) (APIBaseClient, error) {
 	}
 
 	if schemasURLs != opts.URL {
-		req, err = http.NewRequest("GET", schemasURLs, nil)
+		req, err = http.NewRequestWithContext(ctx, "GET", schemasURLs, nil)
 		if err != nil {
 			return result, err
 		}
@@ -347,9 +368,8 @@ func newAPIClientInternal(opts *ClientOpts) (APIBaseClient, error) {
 		result.Ops.Dialer = result.Opts.WSDialer
 	}
 
-	ht, ok := client.Transport.(*http.Transport)
-	if ok {
-		result.Ops.Dialer.TLSClientConfig = ht.TLSClientConfig
+	if tlsConfig != nil {
+		result.Ops.Dialer.TLSClientConfig = tlsConfig
 	}
 
 	return result, nil
@@ -363,7 +383,7 @@ func NewListOpts() *types.ListOpts {
 
 func (a *APIBaseClient) Websocket(url string, headers map[string][]string) (*websocket.Conn, *http.Response, error) {
 	httpHeaders := http.Header{}
-	for k, v := range httpHeaders {
+	for k, v := range headers {
 		httpHeaders[k] = v
 	}
 
@@ -375,7 +395,7 @@ func (a *APIBaseClient) Websocket(url string, headers map[string][]string) (*web
 		logrus.Infoln("WS " + url)
 	}
 
-	return a.Ops.Dialer.Dial(url, http.Header(httpHeaders))
+	return a.Ops.Dialer.DialContext(a.Ops.context(), url, http.Header(httpHeaders))
 }
 
 func (a *APIBaseClient) List(schemaType string, opts *types.ListOpts, respObject interface{}) error {
@@ -0,0 +1,119 @@
+package clientbase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/norman/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_OperationsContext(t *testing.T) {
+	// the server holds every request until the client gives up on it, or the test is done
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	existing := &types.Resource{
+		Links:   map[string]string{SELF: server.URL + "/v3/settings/test"},
+		Actions: map[string]string{"refresh": server.URL + "/v3/settings/test?action=refresh"},
+	}
+
+	newOps := func() *APIOperations {
+		return &APIOperations{
+			Opts:   &ClientOpts{},
+			Client: server.Client(),
+			Types: map[string]types.Schema{
+				"setting": {
+					CollectionMethods: []string{"GET", "POST"},
+					ResourceMethods:   []string{"GET", "PUT", "DELETE"},
+					Links:             map[string]string{COLLECTION: server.URL + "/v3/settings"},
+				},
+			},
+		}
+	}
+
+	operations := map[string]func(ctx context.Context, ops *APIOperations) error{
+		"DoGetContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoGetContext(ctx, server.URL+"/v3/settings", nil, &map[string]any{})
+		},
+		"DoListContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoListContext(ctx, "setting", nil, &map[string]any{})
+		},
+		"DoByIDContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoByIDContext(ctx, "setting", "test", &map[string]any{})
+		},
+		"DoCreateContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoCreateContext(ctx, "setting", map[string]any{}, &map[string]any{})
+		},
+		"DoUpdateContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoUpdateContext(ctx, "setting", existing, map[string]any{}, &map[string]any{})
+		},
+		"DoResourceDeleteContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoResourceDeleteContext(ctx, "setting", existing)
+		},
+		"DoActionContext": func(ctx context.Context, ops *APIOperations) error {
+			return ops.DoActionContext(ctx, "setting", "refresh", existing, nil, &map[string]any{})
+		},
+	}
+
+	for name, operation := range operations {
+		t.Run(name+" is cancelled with its context", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := operation(ctx, newOps())
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+			}
+		})
+
+		t.Run(name+" is cancelled with the context of the client options", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			ops := newOps().WithContext(ctx)
+			err := operation(ops.context(), ops)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func Test_RetryCancelledWithContext(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ops := &APIOperations{
+		Opts: &ClientOpts{
+			RetryPolicy: &RetryPolicy{Backoff: wait.Backoff{Duration: time.Hour, Steps: 3}},
+		},
+		Client: server.Client(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := ops.DoGetContext(ctx, server.URL, nil, &map[string]any{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
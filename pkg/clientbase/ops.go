@@ -2,6 +2,7 @@ package clientbase
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
@@ -26,19 +28,40 @@ type APIOperations struct {
 	Session *session.Session
 }
 
+// context returns the root context of the client options, used by the operations that are not given a context.
+func (a *APIOperations) context() context.Context {
+	if a.Opts != nil && a.Opts.Context != nil {
+		return a.Opts.Context
+	}
+
+	return context.Background()
+}
+
+// WithContext returns a copy of the operations whose requests are bound to ctx instead of the root context.
+func (a *APIOperations) WithContext(ctx context.Context) *APIOperations {
+	ops := *a
+	if a.Opts != nil {
+		opts := *a.Opts
+		ops.Opts = &opts
+	} else {
+		ops.Opts = &ClientOpts{}
+	}
+	ops.Opts.Context = ctx
+
+	return &ops
+}
+
 func (a *APIOperations) SetupRequest(req *http.Request) {
 	req.Header.Add("Authorization", a.Opts.getAuthHeader())
 }
 
+// DoDelete calls DoDeleteContext with the context of the client options.
 func (a *APIOperations) DoDelete(url string) error {
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-
-	a.SetupRequest(req)
+	return a.DoDeleteContext(a.context(), url)
+}
 
-	resp, err := a.Client.Do(req)
+func (a *APIOperations) DoDeleteContext(ctx context.Context, url string) error {
+	resp, err := a.doRequest(ctx, http.MethodDelete, url, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -54,7 +77,12 @@ func (a *APIOperations) DoDelete(url string) error {
 	return nil
 }
 
+// DoGet calls DoGetContext with the context of the client options.
 func (a *APIOperations) DoGet(url string, opts *types.ListOpts, respObject interface{}) error {
+	return a.DoGetContext(a.context(), url, opts, respObject)
+}
+
+func (a *APIOperations) DoGetContext(ctx context.Context, url string, opts *types.ListOpts, respObject interface{}) error {
 	if opts == nil {
 		opts = NewListOpts()
 	}
@@ -67,14 +95,7 @@ func (a *APIOperations) DoGet(url string, opts *types.ListOpts, respObject inter
 		logrus.Infoln("GET " + url)
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	a.SetupRequest(req)
-
-	resp, err := a.Client.Do(req)
+	resp, err := a.doRequest(ctx, http.MethodGet, url, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -101,12 +122,17 @@ func (a *APIOperations) DoGet(url string, opts *types.ListOpts, respObject inter
 	return nil
 }
 
+// DoList calls DoListContext with the context of the client options.
 func (a *APIOperations) DoList(schemaType string, opts *types.ListOpts, respObject interface{}) error {
+	return a.DoListContext(a.context(), schemaType, opts, respObject)
+}
+
+func (a *APIOperations) DoListContext(ctx context.Context, schemaType string, opts *types.ListOpts, respObject interface{}) error {
 	collectionURL, err := a.GetCollectionURL(schemaType, "GET")
 	if err != nil {
 		return err
 	}
-	return a.DoGet(collectionURL, opts, respObject)
+	return a.DoGetContext(ctx, collectionURL, opts, respObject)
 }
 
 func (a *APIOperations) GetCollectionURL(schemaType, method string) (string, error) {
@@ -126,11 +152,21 @@ func (a *APIOperations) GetCollectionURL(schemaType, method string) (string, err
 	return collectionURL, nil
 }
 
+// DoNext calls DoNextContext with the context of the client options.
 func (a *APIOperations) DoNext(nextURL string, respObject interface{}) error {
-	return a.DoGet(nextURL, nil, respObject)
+	return a.DoNextContext(a.context(), nextURL, respObject)
+}
+
+func (a *APIOperations) DoNextContext(ctx context.Context, nextURL string, respObject interface{}) error {
+	return a.DoGetContext(ctx, nextURL, nil, respObject)
 }
 
+// DoModify calls DoModifyContext with the context of the client options.
 func (a *APIOperations) DoModify(method string, url string, createObj interface{}, respObject interface{}) error {
+	return a.DoModifyContext(a.context(), method, url, createObj, respObject)
+}
+
+func (a *APIOperations) DoModifyContext(ctx context.Context, method string, url string, createObj interface{}, respObject interface{}) error {
 	if createObj == nil {
 		createObj = map[string]string{}
 	}
@@ -147,15 +183,7 @@ func (a *APIOperations) DoModify(method string, url string, createObj interface{
 		logrus.Infoln("Request => " + string(bodyContent))
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(bodyContent))
-	if err != nil {
-		return err
-	}
-
-	a.SetupRequest(req)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.Client.Do(req)
+	resp, err := a.doRequest(ctx, method, url, bodyContent, http.Header{"Content-Type": {"application/json"}})
 	if err != nil {
 		return err
 	}
@@ -181,7 +209,12 @@ func (a *APIOperations) DoModify(method string, url string, createObj interface{
 	return nil
 }
 
+// DoCreate calls DoCreateContext with the context of the client options.
 func (a *APIOperations) DoCreate(schemaType string, createObj interface{}, respObject interface{}) error {
+	return a.DoCreateContext(a.context(), schemaType, createObj, respObject)
+}
+
+func (a *APIOperations) DoCreateContext(ctx context.Context, schemaType string, createObj interface{}, respObject interface{}) error {
 	if createObj == nil {
 		createObj = map[string]string{}
 	}
@@ -206,7 +239,12 @@ func (a *APIOperations) DoCreate(schemaType string, createObj interface{}, respO
 		collectionURL = re.ReplaceAllString(schema.Links[SELF], schema.PluralName)
 	}
 
-	err := a.DoModify("POST", collectionURL, createObj, respObject)
+	createObj, err := a.WithRunLabels(schema, createObj)
+	if err != nil {
+		return err
+	}
+
+	err = a.DoModifyContext(ctx, "POST", collectionURL, createObj, respObject)
 	if err != nil {
 		return err
 	}
@@ -230,29 +268,54 @@ func (a *APIOperations) DoCreate(schemaType string, createObj interface{}, respO
 		resource = reflect.Indirect(v).FieldByName("Resource").Interface().(types.Resource)
 	}
 
-	a.Session.RegisterCleanupFunc(func() error {
-		if !(schemaType == "cloudCredential") { // Skip resource deletion if resource is a cloud credential
-			err := a.DoResourceDelete(schemaType, &resource)
-			if err != nil && (strings.Contains(err.Error(), "404 Not Found") || strings.Contains(err.Error(), "failed to find self URL of [&{  map[] map[]}]")) {
-				return nil
-			}
-			return err
+	// cloud credentials are not deleted, they must not be reported as leaked either
+	var cleanupResource *session.Resource
+	if schemaType != "cloudCredential" {
+		cleanupResource = &session.Resource{
+			Type:      schemaType,
+			ID:        resource.ID,
+			URL:       resource.Links[SELF],
+			ClusterID: session.ClusterIDFromURL(a.Opts.URL),
 		}
-		return nil
+	}
+
+	a.Session.RegisterCleanupEntry(session.CleanupEntry{
+		Resource: cleanupResource,
+		Cleanup: func() error {
+			if !(schemaType == "cloudCredential") { // Skip resource deletion if resource is a cloud credential
+				// the creation context may be cancelled by the time the session is cleaned up
+				err := a.DoResourceDeleteContext(context.Background(), schemaType, &resource)
+				if err != nil && (strings.Contains(err.Error(), "404 Not Found") || strings.Contains(err.Error(), "failed to find self URL of [&{  map[] map[]}]")) {
+					return nil
+				}
+				return err
+			}
+			return nil
+		},
 	})
 
 	return nil
 }
 
+// DoReplace calls DoReplaceContext with the context of the client options.
 func (a *APIOperations) DoReplace(schemaType string, existing *types.Resource, updates interface{}, respObject interface{}) error {
-	return a.doUpdate(schemaType, true, existing, updates, respObject)
+	return a.DoReplaceContext(a.context(), schemaType, existing, updates, respObject)
 }
 
+func (a *APIOperations) DoReplaceContext(ctx context.Context, schemaType string, existing *types.Resource, updates interface{}, respObject interface{}) error {
+	return a.doUpdate(ctx, schemaType, true, existing, updates, respObject)
+}
+
+// DoUpdate calls DoUpdateContext with the context of the client options.
 func (a *APIOperations) DoUpdate(schemaType string, existing *types.Resource, updates interface{}, respObject interface{}) error {
-	return a.doUpdate(schemaType, false, existing, updates, respObject)
+	return a.DoUpdateContext(a.context(), schemaType, existing, updates, respObject)
 }
 
-func (a *APIOperations) doUpdate(schemaType string, replace bool, existing *types.Resource, updates interface{}, respObject interface{}) error {
+func (a *APIOperations) DoUpdateContext(ctx context.Context, schemaType string, existing *types.Resource, updates interface{}, respObject interface{}) error {
+	return a.doUpdate(ctx, schemaType, false, existing, updates, respObject)
+}
+
+func (a *APIOperations) doUpdate(ctx context.Context, schemaType string, replace bool, existing *types.Resource, updates interface{}, respObject interface{}) error {
 	if existing == nil {
 		return errors.New("Existing object is nil")
 	}
@@ -290,10 +353,40 @@ func (a *APIOperations) doUpdate(schemaType string, replace bool, existing *type
 		return errors.New("Resource type [" + schemaType + "] is not updatable")
 	}
 
-	return a.DoModify("PUT", selfURL, updates, respObject)
+	err := a.DoModifyContext(ctx, "PUT", selfURL, updates, respObject)
+	if a.Opts == nil || a.Opts.RetryPolicy == nil || !a.Opts.RetryPolicy.RefetchOnConflict {
+		return err
+	}
+
+	// the webhook and the stale cache can reject an update made against an outdated resource version
+	backoff := a.Opts.RetryPolicy.Backoff
+	for attempt := 2; isConflict(err) && backoff.Steps > 1; attempt++ {
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		updates, err = a.refreshResourceVersion(ctx, existing.Links[SELF], updates)
+		if err != nil {
+			return err
+		}
+
+		logrus.Debugf("retrying PUT %s after a conflict, attempt %d", selfURL, attempt)
+		err = a.DoModifyContext(ctx, "PUT", selfURL, updates, respObject)
+	}
+
+	return err
 }
 
+// DoByID calls DoByIDContext with the context of the client options.
 func (a *APIOperations) DoByID(schemaType string, id string, respObject interface{}) error {
+	return a.DoByIDContext(a.context(), schemaType, id, respObject)
+}
+
+func (a *APIOperations) DoByIDContext(ctx context.Context, schemaType string, id string, respObject interface{}) error {
 	schema, ok := a.Types[schemaType]
 	if !ok {
 		return errors.New("Unknown schema type [" + schemaType + "]")
@@ -308,10 +401,15 @@ func (a *APIOperations) DoByID(schemaType string, id string, respObject interfac
 		return errors.New("Failed to find collection URL for [" + schemaType + "]")
 	}
 
-	return a.DoGet(collectionURL+"/"+id, nil, respObject)
+	return a.DoGetContext(ctx, collectionURL+"/"+id, nil, respObject)
 }
 
+// DoResourceDelete calls DoResourceDeleteContext with the context of the client options.
 func (a *APIOperations) DoResourceDelete(schemaType string, existing *types.Resource) error {
+	return a.DoResourceDeleteContext(a.context(), schemaType, existing)
+}
+
+func (a *APIOperations) DoResourceDeleteContext(ctx context.Context, schemaType string, existing *types.Resource) error {
 	schema, ok := a.Types[schemaType]
 	if !ok {
 		return errors.New("Unknown schema type [" + schemaType + "]")
@@ -326,12 +424,15 @@ func (a *APIOperations) DoResourceDelete(schemaType string, existing *types.Reso
 		return fmt.Errorf("failed to find self URL of [%v]", existing)
 	}
 
-	return a.DoDelete(selfURL)
+	return a.DoDeleteContext(ctx, selfURL)
 }
 
-func (a *APIOperations) DoAction(schemaType string, action string,
-	existing *types.Resource, inputObject, respObject interface{},
-) error {
+// DoAction calls DoActionContext with the context of the client options.
+func (a *APIOperations) DoAction(schemaType string, action string, existing *types.Resource, inputObject, respObject interface{}) error {
+	return a.DoActionContext(a.context(), schemaType, action, existing, inputObject, respObject)
+}
+
+func (a *APIOperations) DoActionContext(ctx context.Context, schemaType string, action string, existing *types.Resource, inputObject, respObject interface{}) error {
 	if existing == nil {
 		return errors.New("Existing object is nil")
 	}
@@ -341,12 +442,15 @@ func (a *APIOperations) DoAction(schemaType string, action string,
 		return fmt.Errorf("action [%v] not available on [%v]", action, existing)
 	}
 
-	return a.doAction(schemaType, action, actionURL, inputObject, respObject)
+	return a.doAction(ctx, schemaType, action, actionURL, inputObject, respObject)
 }
 
-func (a *APIOperations) DoCollectionAction(schemaType string, action string,
-	existing *types.Collection, inputObject, respObject interface{},
-) error {
+// DoCollectionAction calls DoCollectionActionContext with the context of the client options.
+func (a *APIOperations) DoCollectionAction(schemaType string, action string, existing *types.Collection, inputObject, respObject interface{}) error {
+	return a.DoCollectionActionContext(a.context(), schemaType, action, existing, inputObject, respObject)
+}
+
+func (a *APIOperations) DoCollectionActionContext(ctx context.Context, schemaType string, action string, existing *types.Collection, inputObject, respObject interface{}) error {
 	if existing == nil {
 		return errors.New("Existing object is nil")
 	}
@@ -356,10 +460,11 @@ func (a *APIOperations) DoCollectionAction(schemaType string, action string,
 		return fmt.Errorf("action [%v] not available on [%v]", action, existing)
 	}
 
-	return a.doAction(schemaType, action, actionURL, inputObject, respObject)
+	return a.doAction(ctx, schemaType, action, actionURL, inputObject, respObject)
 }
 
 func (a *APIOperations) doAction(
+	ctx context.Context,
 	schemaType string,
 	action string,
 	actionURL string,
@@ -388,7 +493,7 @@ func (a *APIOperations) doAction(
 		input = bytes.NewBuffer(bodyContent)
 	}
 
-	req, err := http.NewRequest("POST", actionURL, input)
+	req, err := http.NewRequestWithContext(ctx, "POST", actionURL, input)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,168 @@
+package clientbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultRetryStatusCodes are the response status codes retried when a RetryPolicy does not list any, they are
+// returned by Rancher while it is restarting or overloaded.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// idempotentMethods are the only methods retried, since retrying a POST may create a resource twice.
+var idempotentMethods = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete}
+
+// RetryPolicy configures how APIOperations retries requests failing with transient errors.
+type RetryPolicy struct {
+	// Backoff is the backoff between attempts, its Steps being the maximum number of attempts.
+	Backoff wait.Backoff
+	// StatusCodes are the response status codes that are retried, DefaultRetryStatusCodes if empty.
+	StatusCodes []int
+	// RetryConnectionErrors retries requests that failed without a response, e.g. connection refused.
+	RetryConnectionErrors bool
+	// RefetchOnConflict retries updates failing with 409 Conflict after refreshing the resource version of the
+	// update from the latest version of the resource.
+	RefetchOnConflict bool
+}
+
+// shouldRetry returns whether a request with the given method that received resp or err must be retried.
+func (p *RetryPolicy) shouldRetry(method string, resp *http.Response, err error) bool {
+	if !slices.Contains(idempotentMethods, method) {
+		return false
+	}
+
+	if err != nil {
+		return p.RetryConnectionErrors && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	statusCodes := p.StatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultRetryStatusCodes
+	}
+
+	return slices.Contains(statusCodes, resp.StatusCode)
+}
+
+// doRequest sends a request built from method, url and body, retrying it according to the retry policy of the client
+// options. The caller must close the body of the returned response.
+func (a *APIOperations) doRequest(ctx context.Context, method, url string, body []byte, header http.Header) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		a.SetupRequest(req)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		return req, nil
+	}
+
+	var policy *RetryPolicy
+	if a.Opts != nil {
+		policy = a.Opts.RetryPolicy
+	}
+
+	if policy == nil {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		return a.Client.Do(req)
+	}
+
+	backoff := policy.Backoff
+	attempt := 0
+	for {
+		attempt++
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := a.Client.Do(req)
+		if backoff.Steps <= 1 || !policy.shouldRetry(method, resp, err) {
+			if attempt > 1 {
+				logrus.Debugf("%s %s completed after %d attempts", method, url, attempt)
+			}
+			return resp, err
+		}
+
+		if err != nil {
+			logrus.Debugf("retrying %s %s after attempt %d failed: %v", method, url, attempt, err)
+		} else {
+			logrus.Debugf("retrying %s %s after attempt %d returned %s", method, url, attempt, resp.Status)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isConflict returns whether err is a 409 Conflict returned by the API.
+func isConflict(err error) bool {
+	apiError, ok := err.(*APIError)
+	return ok && apiError.StatusCode == http.StatusConflict
+}
+
+// refreshResourceVersion returns updates with its resource version replaced by the one of the latest version of the
+// resource at selfURL. Steve objects hold it in metadata.resourceVersion, Norman objects at the top level.
+func (a *APIOperations) refreshResourceVersion(ctx context.Context, selfURL string, updates any) (any, error) {
+	var latest map[string]any
+	err := a.DoGetContext(ctx, selfURL, nil, &latest)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(updates)
+	if err != nil {
+		return nil, err
+	}
+
+	var refreshed map[string]any
+	err = json.Unmarshal(content, &refreshed)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata, ok := refreshed["metadata"].(map[string]any); ok {
+		if latestMetadata, ok := latest["metadata"].(map[string]any); ok {
+			metadata["resourceVersion"] = latestMetadata["resourceVersion"]
+		}
+	}
+	if _, ok := refreshed["resourceVersion"]; ok {
+		refreshed["resourceVersion"] = latest["resourceVersion"]
+	}
+
+	return refreshed, nil
+}
@@ -0,0 +1,102 @@
+package clientbase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/norman/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "get succeeds after transient errors", method: http.MethodGet, failures: 2, wantAttempts: 3},
+		{name: "get gives up after the backoff steps", method: http.MethodGet, failures: 5, wantAttempts: 3, wantErr: true},
+		{name: "post is not retried", method: http.MethodPost, failures: 1, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte("{}"))
+			}))
+			defer server.Close()
+
+			ops := &APIOperations{
+				Opts: &ClientOpts{
+					RetryPolicy: &RetryPolicy{Backoff: wait.Backoff{Steps: 3}},
+				},
+				Client: server.Client(),
+			}
+
+			var resp map[string]any
+			var err error
+			if tt.method == http.MethodGet {
+				err = ops.DoGet(server.URL, nil, &resp)
+			} else {
+				err = ops.DoModify(tt.method, server.URL, nil, &resp)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func Test_RefetchOnConflictCancelledWithContext(t *testing.T) {
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			puts++
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	ops := &APIOperations{
+		Opts: &ClientOpts{
+			RetryPolicy: &RetryPolicy{
+				Backoff:           wait.Backoff{Duration: time.Hour, Steps: 3},
+				RefetchOnConflict: true,
+			},
+		},
+		Client: server.Client(),
+		Types: map[string]types.Schema{
+			"setting": {ResourceMethods: []string{"PUT"}},
+		},
+	}
+	existing := &types.Resource{Links: map[string]string{SELF: server.URL + "/v3/settings/test"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := ops.DoUpdateContext(ctx, "setting", existing, map[string]any{}, &map[string]any{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if puts != 1 {
+		t.Errorf("puts = %d, want 1", puts)
+	}
+}
@@ -0,0 +1,57 @@
+package clientbase
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/rancher/norman/types"
+)
+
+// WithRunLabels returns obj with the run labels of the session, see session.RunLabels. The labels are added to the
+// labels field of the norman types that have one, and otherwise to the metadata of Kubernetes objects, e.g. the ones
+// created through Steve. Existing labels are kept.
+func (a *APIOperations) WithRunLabels(schema types.Schema, obj interface{}) (interface{}, error) {
+	_, hasLabels := schema.ResourceFields["labels"]
+	_, hasMetadata := schema.ResourceFields["metadata"]
+	if len(schema.ResourceFields) > 0 && !hasLabels && !hasMetadata {
+		// a norman type without labels, there is nothing to label
+		return obj, nil
+	}
+
+	content, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are kept as json.Number so that the large integers, e.g. quantities of bytes, are sent unchanged
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var m map[string]interface{}
+	if err := decoder.Decode(&m); err != nil || m == nil {
+		// not an object, there is nothing to label
+		return obj, nil
+	}
+
+	parent := m
+	if !hasLabels {
+		metadata, ok := m["metadata"].(map[string]interface{})
+		if !ok {
+			return obj, nil
+		}
+		parent = metadata
+	}
+
+	labels, _ := parent["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+	}
+	for key, value := range a.Session.RunLabels() {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
+	parent["labels"] = labels
+
+	return m, nil
+}
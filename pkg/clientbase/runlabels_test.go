@@ -0,0 +1,92 @@
+package clientbase
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/shepherd/pkg/session"
+)
+
+func Test_WithRunLabels(t *testing.T) {
+	ts := session.NewSession()
+	ts.RunID = "run1"
+	ops := &APIOperations{Session: ts}
+	norman := types.Schema{ResourceFields: map[string]types.Field{"labels": {}}}
+
+	tests := []struct {
+		name   string
+		schema types.Schema
+		obj    interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name: "kubernetes object",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "test", "labels": map[string]interface{}{"app": "test"}},
+			},
+			want: map[string]interface{}{"app": "test", session.RunIDLabel: "run1"},
+		},
+		{
+			name:   "norman object",
+			schema: norman,
+			obj: struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			}{Name: "test", Labels: map[string]string{session.RunIDLabel: "kept"}},
+			want: map[string]interface{}{session.RunIDLabel: "kept"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ops.WithRunLabels(tt.schema, tt.obj)
+			if err != nil {
+				t.Fatalf("WithRunLabels() error = %v", err)
+			}
+
+			m := got.(map[string]interface{})
+			if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+				m = metadata
+			}
+			labels := m["labels"].(map[string]interface{})
+			delete(labels, session.RunStartedLabel)
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("labels = %v, want %v", labels, tt.want)
+			}
+		})
+	}
+
+	obj := map[string]interface{}{"name": "unlabelled"}
+	got, _ := ops.WithRunLabels(types.Schema{}, obj)
+	if !reflect.DeepEqual(got, obj) {
+		t.Errorf("WithRunLabels() = %v, want the object of a schema without labels unchanged", got)
+	}
+
+	unlabelled := types.Schema{ResourceFields: map[string]types.Field{"name": {}}}
+	obj = map[string]interface{}{"name": "unlabelled", "metadata": map[string]interface{}{}}
+	got, _ = ops.WithRunLabels(unlabelled, obj)
+	if !reflect.DeepEqual(got, obj) {
+		t.Errorf("WithRunLabels() = %v, want the object of a norman type without labels unchanged", got)
+	}
+}
+
+func Test_WithRunLabelsKeepsNumbers(t *testing.T) {
+	ops := &APIOperations{Session: session.NewSession()}
+	norman := types.Schema{ResourceFields: map[string]types.Field{"labels": {}}}
+
+	got, err := ops.WithRunLabels(norman, map[string]interface{}{"quantity": int64(1<<53 + 1)})
+	if err != nil {
+		t.Fatalf("WithRunLabels() error = %v", err)
+	}
+
+	content, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(content), `"quantity":9007199254740993`) {
+		t.Errorf("WithRunLabels() = %s, want the quantity 9007199254740993 unchanged", content)
+	}
+}
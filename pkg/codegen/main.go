@@ -208,6 +208,10 @@ func main() {
 		panic(err)
 	}
 
+	if err := addClientWithContext(); err != nil {
+		panic(err)
+	}
+
 	// Loop through all generated controller paths and replace imports and
 	// and add test session
 	for _, path := range generatedControllerPaths {
@@ -247,6 +251,53 @@ func replaceClientBasePackages() error {
 	})
 }
 
+// clientWithContext is the WithContext method added to the generated clients, and the newClient function it shares
+// with NewClient to build a Client and its operations from a base client.
+const clientWithContext = `	return newClient(baseClient), nil
+}
+
+// WithContext returns a copy of the Client whose requests are bound to ctx, so they are cancelled along with it.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	baseClient := c.APIBaseClient
+	baseClient.Ops = c.Ops.WithContext(ctx)
+	baseClient.Opts = baseClient.Ops.Opts
+
+	return newClient(baseClient)
+}
+
+func newClient(baseClient clientbase.APIBaseClient) *Client {
+	client := &Client{
+		APIBaseClient: baseClient,
+	}
+`
+
+// addClientWithContext walks through the zz_generated_client generated by generator.GenerateClient to add the
+// WithContext method, which binds the requests of a Client to a context.Context.
+func addClientWithContext() error {
+	return filepath.Walk("./clients/rancher/generated", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(info.Name(), "zz_generated_client") {
+			input, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			replacement := bytes.Replace(input, []byte("import (\n"), []byte("import (\n\t\"context\"\n\n"), 1)
+			replacement = bytes.Replace(replacement, []byte("\treturn client, nil\n}"), []byte("\treturn client\n}"), 1)
+			replacement = bytes.Replace(replacement, []byte("\tclient := &Client{\n\t\tAPIBaseClient: baseClient,\n\t}\n"), []byte(clientWithContext), 1)
+
+			if err = os.WriteFile(path, replacement, 0666); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 // Walk through the generated controllers and add test session
 // to necessary functions and structs
 func addControllerTestSession(root string) error {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	_ "github.com/rancher/shepherd/clients/corral"
+	_ "github.com/rancher/shepherd/clients/ec2"
+	_ "github.com/rancher/shepherd/clients/harvester"
+	_ "github.com/rancher/shepherd/clients/k3d"
+	_ "github.com/rancher/shepherd/clients/rancher"
+	_ "github.com/rancher/shepherd/clients/rancher/auth/activedirectory"
+	_ "github.com/rancher/shepherd/clients/rancher/auth/openldap"
+	_ "github.com/rancher/shepherd/clients/rkecli"
+	_ "github.com/rancher/shepherd/clients/tfexec"
+	_ "github.com/rancher/shepherd/extensions/auth/openldap"
+	_ "github.com/rancher/shepherd/extensions/cloudcredentials"
+	_ "github.com/rancher/shepherd/extensions/clusters/aks"
+	_ "github.com/rancher/shepherd/extensions/clusters/alibaba"
+	_ "github.com/rancher/shepherd/extensions/clusters/eks"
+	_ "github.com/rancher/shepherd/extensions/clusters/gke"
+	_ "github.com/rancher/shepherd/extensions/rancherversion"
+	"github.com/rancher/shepherd/pkg/config"
+	_ "github.com/rancher/shepherd/pkg/environmentflag"
+	_ "github.com/rancher/shepherd/pkg/nodes"
+	"github.com/sirupsen/logrus"
+)
+
+// config writes the JSON Schema of CATTLE_TEST_CONFIG files with the config keys registered by the shepherd
+// packages, or validates the files listed in CATTLE_TEST_CONFIG against them.
+//
+//	go run ./pkg/config/cmd -o cattle-config.schema.json
+//	go run ./pkg/config/cmd -validate
+func main() {
+	output := flag.String("o", "", "path of the JSON Schema to write, the standard output by default")
+	validate := flag.Bool("validate", false, "validate the files listed in CATTLE_TEST_CONFIG instead of writing the schema")
+	flag.Parse()
+
+	if *validate {
+		loaded, err := config.LoadFromEnv()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if err := loaded.Validate(); err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Info("config is valid")
+		return
+	}
+
+	schema, err := config.JSONSchema()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(append(schema, '\n'))
+		return
+	}
+
+	if err := os.WriteFile(*output, append(schema, '\n'), 0644); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -4,60 +4,50 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"sync/atomic"
 
-	"github.com/creasty/defaults"
 	"sigs.k8s.io/yaml"
 )
 
 // ConfigEnvironmentKey is a const that stores cattle config's environment key.
 const ConfigEnvironmentKey = "CATTLE_TEST_CONFIG"
 
-// LoadConfig reads the file defined by  the `CATTLE_TEST_CONFIG` environment variable and loads the object found at the given key onto the given configuration reference.
-// The functions takes a pointer of the object.
-func LoadConfig(key string, config interface{}) {
-	configPath := os.Getenv(ConfigEnvironmentKey)
+// writes counts the configuration files written by UpdateConfig and WriteConfig.
+var writes atomic.Uint64
+
+// Writes returns how many times UpdateConfig and WriteConfig wrote a configuration file, so that the configurations
+// cached by their callers can be reloaded when it changes.
+func Writes() uint64 {
+	return writes.Load()
+}
 
-	if configPath == "" {
+// LoadConfig reads the files defined by the `CATTLE_TEST_CONFIG` environment variable and loads the object found at the given key onto the given configuration reference.
+// The functions takes a pointer of the object. When `CATTLE_TEST_CONFIG` is not set, config is left empty.
+// It panics on errors, see Load to get them returned instead.
+func LoadConfig(key string, config interface{}) {
+	err := Load(key, config)
+	if errors.Is(err, ErrConfigNotSet) {
 		yaml.Unmarshal([]byte("{}"), config)
 		return
 	}
-
-	allString, err := os.ReadFile(configPath)
-	if err != nil {
-		panic(err)
-	}
-
-	var all map[string]interface{}
-	err = yaml.Unmarshal(allString, &all)
-	if err != nil {
-		panic(err)
-	}
-
-	scoped := all[key]
-	scopedString, err := yaml.Marshal(scoped)
 	if err != nil {
 		panic(err)
 	}
-
-	err = yaml.Unmarshal(scopedString, config)
-	if err != nil {
-		panic(err)
-	}
-
-	if err := defaults.Set(config); err != nil {
-		panic(err)
-	}
-
 }
 
 // UpdateConfig is function that updates the CATTLE_TEST_CONFIG yaml/json that the framework uses.
+// The secrets resolved from references are written back as their references.
+// When CATTLE_TEST_CONFIG lists several files, the last one is updated since it takes precedence over the others.
+// Only the values of config that differ from the loaded config are written, so that the file does not get the values
+// of the other files, of the environment overrides or of the defaults.
 func UpdateConfig(key string, config interface{}) {
-	configPath := os.Getenv(ConfigEnvironmentKey)
-
-	if configPath == "" {
+	files := configFiles()
+	if len(files) == 0 {
 		yaml.Unmarshal([]byte("{}"), config)
 		return
 	}
+	configPath := files[len(files)-1]
 
 	// Read json buffer from jsonFile
 	byteValue, err := os.ReadFile(configPath)
@@ -72,8 +62,23 @@ func UpdateConfig(key string, config interface{}) {
 	if err != nil {
 		panic(err)
 	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
 
-	result[key] = config
+	loaded, err := LoadFiles(files...)
+	if err != nil {
+		panic(err)
+	}
+
+	changes, err := loaded.changes(key, config)
+	if err != nil {
+		panic(err)
+	}
+	if changes == nil {
+		return
+	}
+	result[key] = mergeValues(result[key], changes)
 
 	yamlConfig, err := yaml.Marshal(result)
 
@@ -85,6 +90,7 @@ func UpdateConfig(key string, config interface{}) {
 	if err != nil {
 		panic(err)
 	}
+	writes.Add(1)
 }
 
 // LoadAndUpdateConfig is function that loads and updates the CATTLE_TEST_CONFIG yaml/json that the framework uses,
@@ -97,15 +103,36 @@ func LoadAndUpdateConfig(key string, config any, updateFunc func()) {
 	UpdateConfig(key, config)
 }
 
-// WriteConfig writes a CATTLE_TEST_CONFIG config file when one is not previously written.
+// WriteConfig writes a CATTLE_TEST_CONFIG config file when one is not previously written, the last one when several are listed.
+// When several files are listed, only the values of config that differ from the config the other files load are
+// written.
 func WriteConfig(key string, config interface{}) error {
-	configPath := os.Getenv("CATTLE_TEST_CONFIG")
-	if configPath == "" {
+	files := configFiles()
+	if len(files) == 0 {
 		return errors.New("cannot write config because environment variable CATTLE_TEST_CONFIG is not set")
 	}
+	configPath := files[len(files)-1]
+
+	var scoped any
+	var err error
+	if len(files) == 1 {
+		scoped, err = withReferences(key, config)
+	} else {
+		var loaded *Config
+		loaded, err = LoadFiles(files[:len(files)-1]...)
+		if err == nil {
+			scoped, err = loaded.changes(key, config)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error marshalling config as YAML: %w", err)
+	}
+	if scoped == nil {
+		scoped = map[string]interface{}{}
+	}
 
 	all := map[string]interface{}{}
-	all[key] = config
+	all[key] = scoped
 
 	yamlConfig, err := yaml.Marshal(all)
 	if err != nil {
@@ -116,10 +143,81 @@ func WriteConfig(key string, config interface{}) error {
 	if err != nil {
 		return fmt.Errorf("error writing config to file: %w", err)
 	}
+	writes.Add(1)
 
 	return nil
 }
 
+// changes returns the values of config, with its resolved secrets as their references, that differ from the config
+// c loads at key, nil if there are none. The values c sets that config does not have are changed to null.
+func (c *Config) changes(key string, config any) (any, error) {
+	current, err := withReferences(key, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous any
+	if t := reflect.TypeOf(config); t != nil && t.Kind() == reflect.Pointer {
+		loaded := reflect.New(t.Elem()).Interface()
+		// a config c cannot load, e.g. an invalid one, is written as a whole
+		if c.unmarshal(key, loaded, false) == nil {
+			previous, err = withReferences(key, loaded)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	changes, _ := diffValues(previous, current)
+
+	return changes, nil
+}
+
+// diffValues returns the values of current that differ from previous, and whether there are any. Maps are compared
+// key by key, the keys previous has that current does not are changed to nil.
+func diffValues(previous, current any) (any, bool) {
+	previousMap, previousIsMap := previous.(map[string]any)
+	currentMap, currentIsMap := current.(map[string]any)
+	if !previousIsMap || !currentIsMap {
+		if reflect.DeepEqual(previous, current) {
+			return nil, false
+		}
+		return current, true
+	}
+
+	changes := map[string]any{}
+	for key, value := range currentMap {
+		if changed, ok := diffValues(previousMap[key], value); ok {
+			changes[key] = changed
+		}
+	}
+	for key := range previousMap {
+		if _, ok := currentMap[key]; !ok {
+			changes[key] = nil
+		}
+	}
+	if len(changes) == 0 {
+		return nil, false
+	}
+
+	return changes, true
+}
+
+// mergeValues merges override onto base the way the config files are merged, and returns the result.
+func mergeValues(base, override any) any {
+	baseMap, baseIsMap := base.(map[string]any)
+	overrideMap, overrideIsMap := override.(map[string]any)
+	if !baseIsMap || !overrideIsMap {
+		return override
+	}
+
+	for key, value := range overrideMap {
+		baseMap[key] = mergeValues(baseMap[key], value)
+	}
+
+	return baseMap
+}
+
 // LoadConfigFromFile loads an entire yaml file into a map[string]any
 func LoadConfigFromFile(filePath string) map[string]any {
 	allString, err := os.ReadFile(filePath)
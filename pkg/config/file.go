@@ -9,6 +9,8 @@ import (
 
 // NewConfigFileName is a constructor function that creates a configuration yaml file name
 // that returns ConfigFileName.
+//
+// Deprecated: write the configuration files in a workspace.Workspace instead.
 func NewConfigFileName(dirName string, params ...string) file.Name {
 	fileName := strings.Join(params, "-")
 
@@ -0,0 +1,346 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/creasty/defaults"
+	"sigs.k8s.io/yaml"
+)
+
+// EnvOverridePrefix is the prefix of the environment variables overriding configuration values, e.g.
+// CATTLE_TEST_RANCHER_ADMIN_TOKEN overrides the adminToken field of the rancher key.
+const EnvOverridePrefix = "CATTLE_TEST_"
+
+// ErrConfigNotSet is returned when the CATTLE_TEST_CONFIG environment variable is not set.
+var ErrConfigNotSet = errors.New("environment variable " + ConfigEnvironmentKey + " is not set")
+
+var (
+	envWordBoundaryRegexp = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	envInvalidCharsRegexp = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Source is where a configuration value came from, either a file or an environment variable. The zero Source means
+// the value was not set and comes from the defaults of the configuration.
+type Source struct {
+	File   string
+	EnvVar string
+}
+
+func (s Source) String() string {
+	switch {
+	case s.EnvVar != "":
+		return "environment variable " + s.EnvVar
+	case s.File != "":
+		return "file " + s.File
+	default:
+		return "default"
+	}
+}
+
+// Config is the merge of an ordered list of configuration files, each file overriding the values of the previous
+// ones. Maps are merged key by key, lists and scalar values are replaced. The Source of every value is recorded.
+type Config struct {
+	lock    sync.Mutex
+	values  map[string]any
+	sources map[string]Source
+}
+
+// configFiles returns the files listed in CATTLE_TEST_CONFIG, separated like PATH, e.g. base.yaml:overlay.yaml.
+func configFiles() []string {
+	return filepath.SplitList(os.Getenv(ConfigEnvironmentKey))
+}
+
+// LoadFromEnv loads the Config from the files listed in CATTLE_TEST_CONFIG, separated like PATH, e.g.
+// base.yaml:staging.yaml:secrets.yaml. It returns ErrConfigNotSet when the variable is not set.
+func LoadFromEnv() (*Config, error) {
+	files := configFiles()
+	if len(files) == 0 {
+		return nil, ErrConfigNotSet
+	}
+
+	return LoadFiles(files...)
+}
+
+// LoadFiles loads the Config from files, in order.
+func LoadFiles(files ...string) (*Config, error) {
+	config := &Config{
+		values:  map[string]any{},
+		sources: map[string]Source{},
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+
+		var values map[string]any
+		err = yaml.Unmarshal(data, &values)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", file, err)
+		}
+
+		config.values = config.merge("", config.values, values, Source{File: file}).(map[string]any)
+	}
+
+	return config, nil
+}
+
+// merge merges override onto base, recording source for the overridden values, and returns the result.
+func (c *Config) merge(path string, base, override any, source Source) any {
+	baseMap, baseIsMap := base.(map[string]any)
+	overrideMap, overrideIsMap := override.(map[string]any)
+	if !baseIsMap || !overrideIsMap {
+		c.forget(path)
+		if !overrideIsMap {
+			c.sources[path] = source
+			return override
+		}
+		baseMap = map[string]any{}
+	}
+
+	for key, value := range overrideMap {
+		baseMap[key] = c.merge(joinPath(path, key), baseMap[key], value, source)
+	}
+
+	return baseMap
+}
+
+// forget drops the sources of path and of the values under it.
+func (c *Config) forget(path string) {
+	for sourcePath := range c.sources {
+		if sourcePath == path || strings.HasPrefix(sourcePath, path+".") {
+			delete(c.sources, sourcePath)
+		}
+	}
+}
+
+// Unmarshal loads the values at key onto config, a pointer to a struct, after applying the environment overrides
+// and resolving the secret references of the SecretFields, then sets the defaults and checks the `validate:` tags. The unknown fields
+// are reported when CATTLE_TEST_CONFIG_STRICT is true.
+func (c *Config) Unmarshal(key string, config any) error {
+	return c.unmarshal(key, config, strictFromEnv())
+}
+
+func (c *Config) unmarshal(key string, config any, strict bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	scoped, _ := copyValue(c.values[key]).(map[string]any)
+	if scoped == nil {
+		scoped = map[string]any{}
+	}
+
+	err := c.applyEnvOverrides(key, nil, envName(key), reflect.TypeOf(config), scoped, map[reflect.Type]bool{})
+	if err != nil {
+		return err
+	}
+
+	_, err = resolveReferences(key, nil, scoped, false)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(scoped)
+	if err != nil {
+		return fmt.Errorf("error marshalling config %s: %w", key, err)
+	}
+
+	err = decode(data, config, strict)
+	if err != nil {
+		return fmt.Errorf("error loading config %s: %w", key, err)
+	}
+
+	if err := defaults.Set(config); err != nil {
+		return fmt.Errorf("error setting the defaults of config %s: %w", key, err)
+	}
+
+	if err := validateValue(key, reflect.ValueOf(config)); err != nil {
+		return fmt.Errorf("invalid config %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// applyEnvOverrides sets the fields of t set by environment variables in values, found at fields in the config key,
+// recursing into nested structs.
+func (c *Config) applyEnvOverrides(key string, fields []any, envPrefix string, t reflect.Type, values map[string]any, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			err := c.applyEnvOverrides(key, fields, envPrefix, field.Type, values, visited)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		fieldPath := formatPath(key, appendPath(fields, name))
+		fieldEnv := envPrefix + "_" + envName(name)
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if fieldType.Kind() == reflect.Struct {
+			nested, _ := values[name].(map[string]any)
+			if nested == nil {
+				nested = map[string]any{}
+			}
+			err := c.applyEnvOverrides(key, appendPath(fields, name), fieldEnv, fieldType, nested, visited)
+			if err != nil {
+				return err
+			}
+			if len(nested) > 0 {
+				values[name] = nested
+			}
+			continue
+		}
+
+		envValue, ok := os.LookupEnv(EnvOverridePrefix + fieldEnv)
+		if !ok {
+			continue
+		}
+
+		var value any = envValue
+		if fieldType.Kind() != reflect.String {
+			err := yaml.Unmarshal([]byte(envValue), &value)
+			if err != nil {
+				return fmt.Errorf("error parsing environment variable %s: %w", EnvOverridePrefix+fieldEnv, err)
+			}
+		}
+
+		if SecretFields[strings.ToLower(name)] {
+			// the secret is written back as a reference to the variable rather than in plain text
+			trackSecret(secretReference{
+				key:       key,
+				path:      appendPath(fields, name),
+				reference: EnvReferencePrefix + EnvOverridePrefix + fieldEnv,
+			}, envValue)
+		}
+
+		values[name] = value
+		c.forget(fieldPath)
+		c.sources[fieldPath] = Source{EnvVar: EnvOverridePrefix + fieldEnv}
+	}
+
+	return nil
+}
+
+// Source returns where the value at path, e.g. rancher.host, came from. It returns false when the value was not
+// set, in which case it comes from the defaults.
+func (c *Config) Source(path string) (Source, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	source, ok := c.sources[path]
+	return source, ok
+}
+
+// Sources returns the Source of every value set under key, e.g. rancher, by path.
+func (c *Config) Sources(key string) map[string]Source {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sources := map[string]Source{}
+	for path, source := range c.sources {
+		if path == key || strings.HasPrefix(path, key+".") {
+			sources[path] = source
+		}
+	}
+
+	return sources
+}
+
+// Load loads the object found at key in the files listed in CATTLE_TEST_CONFIG onto config, a pointer to a struct,
+// applying the environment overrides and the defaults.
+func Load(key string, config any) error {
+	loaded, err := LoadFromEnv()
+	if err != nil {
+		return err
+	}
+
+	return loaded.Unmarshal(key, config)
+}
+
+// jsonName returns the name of field in the json tag, and whether the field is an embedded struct whose fields are
+// inlined.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if tag != "" {
+		return tag, false
+	}
+
+	return field.Name, field.Anonymous
+}
+
+// EnvOverrideName returns the environment variable overriding the value at the path made of keys, e.g.
+// CATTLE_TEST_RANCHER_ADMIN_TOKEN for rancher and adminToken.
+func EnvOverrideName(keys ...string) string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, envName(key))
+	}
+
+	return EnvOverridePrefix + strings.Join(names, "_")
+}
+
+// envName returns the environment variable form of name, e.g. ADMIN_TOKEN for adminToken.
+func envName(name string) string {
+	name = envWordBoundaryRegexp.ReplaceAllString(name, "${1}_${2}")
+	name = envInvalidCharsRegexp.ReplaceAllString(name, "_")
+
+	return strings.ToUpper(name)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// copyValue deep copies a value decoded from yaml.
+func copyValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for key, nested := range value {
+			copied[key] = copyValue(nested)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, nested := range value {
+			copied[i] = copyValue(nested)
+		}
+		return copied
+	default:
+		return value
+	}
+}
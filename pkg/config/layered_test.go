@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testConfig struct {
+	Host       string            `json:"host" yaml:"host"`
+	AdminToken string            `json:"adminToken" yaml:"adminToken"`
+	Insecure   *bool             `json:"insecure" yaml:"insecure" default:"true"`
+	Retries    int               `json:"retries" yaml:"retries"`
+	Labels     map[string]string `json:"labels" yaml:"labels"`
+	Nested     testNested        `json:"nested" yaml:"nested"`
+}
+
+type testNested struct {
+	Version string `json:"version" yaml:"version"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	return path
+}
+
+func Test_LayeredConfig(t *testing.T) {
+	base := writeFile(t, "base.yaml", `
+test:
+  host: base.example.com
+  retries: 1
+  labels:
+    team: qa
+    env: base
+  nested:
+    version: "1.0"
+`)
+	overlay := writeFile(t, "overlay.yaml", `
+test:
+  host: overlay.example.com
+  labels:
+    env: overlay
+`)
+	secrets := writeFile(t, "secrets.yaml", `
+test:
+  adminToken: secret
+`)
+
+	t.Setenv(ConfigEnvironmentKey, base+string(os.PathListSeparator)+overlay+string(os.PathListSeparator)+secrets)
+	t.Setenv("CATTLE_TEST_TEST_RETRIES", "3")
+	t.Setenv("CATTLE_TEST_TEST_NESTED_VERSION", "2.0")
+
+	config, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("LoadFromEnv() error = %v", err)
+	}
+
+	var got testConfig
+	err = config.Unmarshal("test", &got)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if got.Host != "overlay.example.com" || got.AdminToken != "secret" || got.Retries != 3 || got.Nested.Version != "2.0" {
+		t.Errorf("Unmarshal() = %+v, want the overlay host, the secret token and the environment overrides", got)
+	}
+	if got.Labels["team"] != "qa" || got.Labels["env"] != "overlay" {
+		t.Errorf("Unmarshal() labels = %v, want the merged labels", got.Labels)
+	}
+	if got.Insecure == nil || !*got.Insecure {
+		t.Errorf("Unmarshal() insecure = %v, want the default", got.Insecure)
+	}
+
+	sources := map[string]string{
+		"test.host":           "file " + overlay,
+		"test.adminToken":     "file " + secrets,
+		"test.labels.team":    "file " + base,
+		"test.retries":        "environment variable CATTLE_TEST_TEST_RETRIES",
+		"test.nested.version": "environment variable CATTLE_TEST_TEST_NESTED_VERSION",
+	}
+	for path, want := range sources {
+		source, ok := config.Source(path)
+		if !ok || source.String() != want {
+			t.Errorf("Source(%s) = %s, want %s", path, source, want)
+		}
+	}
+	if _, ok := config.Source("test.insecure"); ok {
+		t.Errorf("Source(test.insecure) is set, want the default")
+	}
+}
+
+func Test_LoadErrors(t *testing.T) {
+	t.Setenv(ConfigEnvironmentKey, "")
+	var config testConfig
+	if err := Load("test", &config); err != ErrConfigNotSet {
+		t.Errorf("Load() error = %v, want ErrConfigNotSet", err)
+	}
+
+	t.Setenv(ConfigEnvironmentKey, filepath.Join(t.TempDir(), "missing.yaml"))
+	if err := Load("test", &config); err == nil {
+		t.Errorf("Load() of a missing file succeeded, want an error")
+	}
+
+	t.Setenv(ConfigEnvironmentKey, writeFile(t, "invalid.yaml", "test: [unclosed"))
+	if err := Load("test", &config); err == nil {
+		t.Errorf("Load() of an invalid file succeeded, want an error")
+	}
+}
+
+func Test_UpdateLayeredConfig(t *testing.T) {
+	base := writeFile(t, "base.yaml", `
+test:
+  host: base.example.com
+  retries: 1
+  labels:
+    team: qa
+`)
+	overlay := writeFile(t, "overlay.yaml", `
+test:
+  labels:
+    env: overlay
+`)
+	t.Setenv(ConfigEnvironmentKey, base+string(os.PathListSeparator)+overlay)
+	t.Setenv("CATTLE_TEST_TEST_ADMIN_TOKEN", "token-from-env")
+
+	var config testConfig
+	LoadConfig("test", &config)
+	config.Host = "updated.example.com"
+	config.Labels["env"] = "updated"
+	UpdateConfig("test", &config)
+
+	written := LoadConfigFromFile(overlay)
+	want := map[string]any{"host": "updated.example.com", "labels": map[string]any{"env": "updated"}}
+	if !reflect.DeepEqual(written["test"], want) {
+		t.Errorf("UpdateConfig() wrote %v to the overlay, want only its own values and the changes %v", written["test"], want)
+	}
+
+	err := WriteConfig("test", &config)
+	if err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+
+	written = LoadConfigFromFile(overlay)
+	if !reflect.DeepEqual(written["test"], want) {
+		t.Errorf("WriteConfig() wrote %v to the overlay, want only the changes %v", written["test"], want)
+	}
+
+	var updated testConfig
+	LoadConfig("test", &updated)
+	if updated.Host != "updated.example.com" || updated.Retries != 1 || updated.Labels["team"] != "qa" || updated.Labels["env"] != "updated" {
+		t.Errorf("LoadConfig() = %+v, want the updated values merged onto the base", updated)
+	}
+}
+
+func Test_LoadConfigNotSet(t *testing.T) {
+	t.Setenv(ConfigEnvironmentKey, "")
+	t.Setenv("CATTLE_TEST_TEST_HOST", "env.example.com")
+
+	var config testConfig
+	LoadConfig("test", &config)
+	if config.Host != "" || config.Insecure != nil {
+		t.Errorf("LoadConfig() = %+v, want an empty config when %s is not set", config, ConfigEnvironmentKey)
+	}
+}
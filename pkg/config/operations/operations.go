@@ -2,66 +2,52 @@ package operations
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"strings"
 
 	"sigs.k8s.io/yaml"
 )
 
-// ReplaceValue recursively traverses keyPath, replacing the specified replaceVal in searchMap.
+// ReplaceValue sets replaceVal at keyPath in searchMap, see ParseKeyPath for the syntax of keyPath. The lists met by
+// a key are traversed, setting replaceVal in each of their elements.
 func ReplaceValue(keyPath []string, replaceVal any, searchMap map[string]any) (map[string]any, error) {
-	if len(keyPath) <= 1 {
-		searchMap[keyPath[0]] = replaceVal
-
-		return searchMap, nil
-	} else {
-		var err error
+	path, err := ParseKeyPath(keyPath)
+	if err != nil {
+		return nil, err
+	}
 
-		if _, ok := searchMap[keyPath[0]].(map[string]any); ok {
-			searchMap[keyPath[0]], err = ReplaceValue(keyPath[1:], replaceVal, searchMap[keyPath[0]].(map[string]any))
-			if err != nil {
-				return nil, err
-			}
-		} else if _, ok := searchMap[keyPath[0]].([]any); ok {
-			for i := range searchMap[keyPath[0]].([]any) {
-				searchMap[keyPath[0]].([]any)[i], err = ReplaceValue(keyPath[1:], replaceVal, searchMap[keyPath[0]].([]any)[i].(map[string]any))
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	err = path.set(searchMap, replaceVal, true)
+	if err != nil {
+		return nil, err
 	}
 
 	return searchMap, nil
 }
 
-// GetValue recursively traverses keyPath, returning the value of the specified keyPath in searchMap.
+// GetValue returns the value at keyPath in searchMap, see ParseKeyPath for the syntax of keyPath. The lists met by a
+// key are traversed, it fails when the key path matches several values, see GetValues.
 func GetValue(keyPath []string, searchMap map[string]any) (any, error) {
-	var err error
-	var keypathvalues any
-	if len(keyPath) == 1 {
-		keypathvalues, ok := searchMap[keyPath[0]]
-		if !ok {
-			err = errors.New(fmt.Sprintf("expected key does not exist: %s", keyPath[0]))
-		}
-		return keypathvalues, err
-	} else {
-		if _, ok := searchMap[keyPath[0]].(map[string]any); ok {
-			keypathvalues, err = GetValue(keyPath[1:], searchMap[keyPath[0]].(map[string]any))
-			if err != nil {
-				return nil, err
-			}
-		} else if _, ok := searchMap[keyPath[0]].([]any); ok {
-			for i := range searchMap[keyPath[0]].([]any) {
-				keypathvalues, err = GetValue(keyPath[1:], searchMap[keyPath[0]].([]any)[i].(map[string]any))
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	values, err := GetValues(keyPath, searchMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 1 {
+		return nil, fmt.Errorf("key path %s matches %d values", strings.Join(keyPath, "."), len(values))
+	}
+
+	return values[0], nil
+}
+
+// GetValues returns the values at keyPath in searchMap, see ParseKeyPath for the syntax of keyPath. The lists met by
+// a key are traversed, returning the values in each of their elements.
+func GetValues(keyPath []string, searchMap map[string]any) ([]any, error) {
+	path, err := ParseKeyPath(keyPath)
+	if err != nil {
+		return nil, err
 	}
 
-	return keypathvalues, err
+	return path.get(searchMap, true)
 }
 
 // LoadObjectFromMap unmarshals a specific key's value into an object
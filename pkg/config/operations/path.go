@@ -0,0 +1,451 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	keySegment segmentKind = iota
+	indexSegment
+	selectorSegment
+	wildcardSegment
+	appendSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+	value string
+}
+
+func (s segment) String() string {
+	switch s.kind {
+	case indexSegment:
+		return "[" + strconv.Itoa(s.index) + "]"
+	case selectorSegment:
+		return "[" + s.key + "=" + s.value + "]"
+	case wildcardSegment:
+		return "[*]"
+	case appendSegment:
+		return "[+]"
+	default:
+		if strings.ContainsAny(s.key, ".[]") {
+			return "[" + strconv.Quote(s.key) + "]"
+		}
+		return "." + s.key
+	}
+}
+
+// Path is a parsed path expression locating values in a config map. Keys are separated by dots and followed by
+// brackets indexing lists or selecting their elements:
+//
+//	machinePools[2].quantity          the quantity of the third machine pool, negative indexes count from the end
+//	machinePools[name=etcd].quantity  the quantity of the machine pools named etcd
+//	machinePools[*].quantity          the quantity of every machine pool, * also matches every key of a map
+//	machinePools[+]                   a new machine pool appended by Set
+//	labels["app.kubernetes.io/name"]  a key containing dots
+type Path struct {
+	segments []segment
+}
+
+// ParsePath parses expression into a Path.
+func ParsePath(expression string) (Path, error) {
+	segments, err := parseSegments(expression, true)
+	if err != nil {
+		return Path{}, fmt.Errorf("invalid path %q: %w", expression, err)
+	}
+
+	return Path{segments: segments}, nil
+}
+
+// ParseKeyPath parses a key path, e.g. a Permutation.KeyPath, into a Path. Every element is a key, that may contain
+// dots, optionally followed by brackets, e.g. []string{"machinePools[name=etcd]", "quantity"}.
+func ParseKeyPath(keyPath []string) (Path, error) {
+	var path Path
+	for _, element := range keyPath {
+		segments, err := parseSegments(element, false)
+		if err != nil {
+			return Path{}, fmt.Errorf("invalid key path %q: %w", keyPath, err)
+		}
+		path.segments = append(path.segments, segments...)
+	}
+
+	if len(path.segments) == 0 {
+		return Path{}, errors.New("empty key path")
+	}
+
+	return path, validateSegments(path.segments)
+}
+
+func (p Path) String() string {
+	return strings.TrimPrefix(p.prefix(len(p.segments)), ".")
+}
+
+// prefix returns the expression of the first n segments.
+func (p Path) prefix(n int) string {
+	var expression strings.Builder
+	for _, s := range p.segments[:n] {
+		expression.WriteString(s.String())
+	}
+
+	return expression.String()
+}
+
+func parseSegments(expression string, splitDots bool) ([]segment, error) {
+	var segments []segment
+	var name strings.Builder
+	afterDot, afterBracket := false, false
+
+	flush := func() {
+		if name.Len() == 0 {
+			return
+		}
+		if name.String() == "*" {
+			segments = append(segments, segment{kind: wildcardSegment})
+		} else {
+			segments = append(segments, segment{kind: keySegment, key: name.String()})
+		}
+		name.Reset()
+	}
+
+	for i := 0; i < len(expression); i++ {
+		switch c := expression[i]; {
+		case c == '.' && splitDots:
+			if name.Len() == 0 && !afterBracket {
+				return nil, fmt.Errorf("empty key at %d", i)
+			}
+			flush()
+			afterDot, afterBracket = true, false
+		case c == '[':
+			if name.Len() == 0 && afterDot {
+				return nil, fmt.Errorf("empty key at %d", i)
+			}
+			flush()
+
+			end := strings.IndexByte(expression[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket at %d", i)
+			}
+			s, err := parseBracket(expression[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, s)
+
+			i += end
+			afterDot, afterBracket = false, true
+		case c == ']':
+			return nil, fmt.Errorf("unexpected ] at %d", i)
+		default:
+			name.WriteByte(c)
+			afterBracket = false
+		}
+	}
+
+	if name.Len() == 0 && afterDot {
+		return nil, errors.New("empty key at the end")
+	}
+	flush()
+
+	if len(segments) == 0 {
+		return nil, errors.New("empty path")
+	}
+
+	return segments, validateSegments(segments)
+}
+
+func validateSegments(segments []segment) error {
+	for i, s := range segments {
+		if s.kind == appendSegment && i != len(segments)-1 {
+			return errors.New("[+] must end the path")
+		}
+	}
+
+	return nil
+}
+
+func parseBracket(content string) (segment, error) {
+	switch {
+	case content == "":
+		return segment{}, errors.New("empty brackets")
+	case content == "*":
+		return segment{kind: wildcardSegment}, nil
+	case content == "+":
+		return segment{kind: appendSegment}, nil
+	case content[0] == '"' || content[0] == '\'':
+		if len(content) < 2 || content[len(content)-1] != content[0] {
+			return segment{}, fmt.Errorf("unterminated quoted key %s", content)
+		}
+		return segment{kind: keySegment, key: content[1 : len(content)-1]}, nil
+	}
+
+	if index, err := strconv.Atoi(content); err == nil {
+		return segment{kind: indexSegment, index: index}, nil
+	}
+
+	if key, value, ok := strings.Cut(content, "="); ok {
+		if key == "" {
+			return segment{}, fmt.Errorf("empty selector key in [%s]", content)
+		}
+		return segment{kind: selectorSegment, key: key, value: value}, nil
+	}
+
+	return segment{kind: keySegment, key: content}, nil
+}
+
+// operation is applied to the values matched by a Path.
+type operation struct {
+	// create creates the missing maps and lists on the path
+	create bool
+	// fanOut applies the key segments to every element of the lists they meet
+	fanOut bool
+	// apply returns the new value of a matched value and whether to remove it
+	apply func(value any, exists bool) (any, bool, error)
+}
+
+// Get returns the values matched by the Path in data, it fails when none is.
+func (p Path) Get(data map[string]any) ([]any, error) {
+	return p.get(data, false)
+}
+
+func (p Path) get(data map[string]any, fanOut bool) ([]any, error) {
+	var values []any
+	_, _, err := p.walk(data, 0, &operation{
+		fanOut: fanOut,
+		apply: func(value any, exists bool) (any, bool, error) {
+			if !exists {
+				return nil, false, errNotFound
+			}
+			values = append(values, value)
+			return value, false, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Set sets value at every match of the Path in data, creating the missing keys. A path ending with [+] appends value
+// to the list.
+func (p Path) Set(data map[string]any, value any) error {
+	return p.set(data, value, false)
+}
+
+func (p Path) set(data map[string]any, value any, fanOut bool) error {
+	_, _, err := p.walk(data, 0, &operation{
+		create: true,
+		fanOut: fanOut,
+		apply: func(any, bool) (any, bool, error) {
+			return value, false, nil
+		},
+	})
+
+	return err
+}
+
+// Delete removes the values matched by the Path from data, the keys of maps and the elements of lists.
+func (p Path) Delete(data map[string]any) error {
+	_, _, err := p.walk(data, 0, &operation{
+		apply: func(value any, exists bool) (any, bool, error) {
+			if !exists {
+				return nil, false, errNotFound
+			}
+			return nil, true, nil
+		},
+	})
+
+	return err
+}
+
+var errNotFound = errors.New("not found")
+
+// walk applies op to the values matched by the segments of the Path from the ith one in node, it returns the new
+// node and whether it is removed.
+func (p Path) walk(node any, i int, op *operation) (any, bool, error) {
+	s := p.segments[i]
+	location := strings.TrimPrefix(p.prefix(i+1), ".")
+
+	// step walks the rest of the Path in a child of node
+	step := func(child any, exists bool) (any, bool, error) {
+		if i == len(p.segments)-1 {
+			newChild, remove, err := op.apply(child, exists)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %w", location, err)
+			}
+			return newChild, remove, nil
+		}
+
+		if !exists || child == nil {
+			if !op.create {
+				return nil, false, fmt.Errorf("%s: %w", location, errNotFound)
+			}
+			switch p.segments[i+1].kind {
+			case keySegment:
+				child = map[string]any{}
+			case appendSegment:
+				child = []any{}
+			default:
+				return nil, false, fmt.Errorf("%s: %w", location, errNotFound)
+			}
+		}
+
+		return p.walk(child, i+1, op)
+	}
+
+	switch s.kind {
+	case keySegment:
+		if list, ok := node.([]any); ok && op.fanOut {
+			return p.eachElement(list, func(int, any) bool { return true }, func(element any) (any, bool, error) {
+				return p.walk(element, i, op)
+			})
+		}
+
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: expected a map, got %s", location, typeName(node))
+		}
+
+		child, exists := m[s.key]
+		newChild, remove, err := step(child, exists)
+		if err != nil {
+			return nil, false, err
+		}
+		if remove {
+			delete(m, s.key)
+		} else {
+			m[s.key] = newChild
+		}
+		return m, false, nil
+	case wildcardSegment:
+		if m, ok := node.(map[string]any); ok {
+			keys := make([]string, 0, len(m))
+			for key := range m {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				newChild, remove, err := step(m[key], true)
+				if err != nil {
+					return nil, false, err
+				}
+				if remove {
+					delete(m, key)
+				} else {
+					m[key] = newChild
+				}
+			}
+			return m, false, nil
+		}
+
+		list, ok := node.([]any)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: expected a map or a list, got %s", location, typeName(node))
+		}
+		return p.eachElement(list, func(int, any) bool { return true }, func(element any) (any, bool, error) {
+			return step(element, true)
+		})
+	case indexSegment:
+		list, ok := node.([]any)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: expected a list, got %s", location, typeName(node))
+		}
+
+		index := s.index
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return nil, false, fmt.Errorf("%s: index out of range, the list has %d elements", location, len(list))
+		}
+
+		return p.eachElement(list, func(j int, _ any) bool { return j == index }, func(element any) (any, bool, error) {
+			return step(element, true)
+		})
+	case selectorSegment:
+		list, ok := node.([]any)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: expected a list, got %s", location, typeName(node))
+		}
+
+		matches := func(_ int, element any) bool {
+			m, ok := element.(map[string]any)
+			if !ok {
+				return false
+			}
+			value, exists := m[s.key]
+			return exists && fmt.Sprint(value) == s.value
+		}
+
+		found := false
+		for j, element := range list {
+			found = found || matches(j, element)
+		}
+		if !found {
+			return nil, false, fmt.Errorf("%s: no element with %s=%s", location, s.key, s.value)
+		}
+
+		return p.eachElement(list, matches, func(element any) (any, bool, error) {
+			return step(element, true)
+		})
+	case appendSegment:
+		list, ok := node.([]any)
+		if !ok {
+			return nil, false, fmt.Errorf("%s: expected a list, got %s", location, typeName(node))
+		}
+
+		newElement, remove, err := step(nil, false)
+		if err != nil {
+			return nil, false, err
+		}
+		if remove {
+			return list, false, nil
+		}
+		return append(list, newElement), false, nil
+	}
+
+	return nil, false, fmt.Errorf("%s: unknown segment", location)
+}
+
+// eachElement applies f to the elements of list matching, and returns the list without the removed elements.
+func (p Path) eachElement(list []any, matching func(int, any) bool, f func(any) (any, bool, error)) (any, bool, error) {
+	kept := list[:0:0]
+	for j, element := range list {
+		if !matching(j, element) {
+			kept = append(kept, element)
+			continue
+		}
+
+		newElement, remove, err := f(element)
+		if err != nil {
+			return nil, false, err
+		}
+		if !remove {
+			kept = append(kept, newElement)
+		}
+	}
+
+	return kept, false, nil
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nothing"
+	case map[string]any:
+		return "a map"
+	case []any:
+		return "a list"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,121 @@
+package operations
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testConfig() map[string]any {
+	return map[string]any{
+		"provisioningInput": map[string]any{
+			"machinePools": []any{
+				map[string]any{"name": "etcd", "quantity": 1},
+				map[string]any{"name": "cp", "quantity": 1},
+				map[string]any{"name": "worker", "quantity": 3},
+			},
+			"labels": map[string]any{"app.kubernetes.io/name": "shepherd"},
+		},
+	}
+}
+
+func Test_PathGet(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{expression: "provisioningInput.machinePools[2].quantity", want: "[3]"},
+		{expression: "provisioningInput.machinePools[-1].name", want: "[worker]"},
+		{expression: "provisioningInput.machinePools[name=cp].quantity", want: "[1]"},
+		{expression: "provisioningInput.machinePools[*].name", want: "[etcd cp worker]"},
+		{expression: `provisioningInput.labels["app.kubernetes.io/name"]`, want: "[shepherd]"},
+		{expression: "provisioningInput.*.app", wantErr: "provisioningInput[*].app: not found"},
+		{expression: "provisioningInput.machinePools[3]", wantErr: "index out of range"},
+		{expression: "provisioningInput.machinePools[name=windows]", wantErr: "no element with name=windows"},
+		{expression: "provisioningInput.machinePools.quantity", wantErr: "expected a map, got a list"},
+		{expression: "provisioningInput.missing", wantErr: "provisioningInput.missing: not found"},
+		{expression: "provisioningInput..machinePools", wantErr: "empty key"},
+		{expression: "provisioningInput.machinePools[0", wantErr: "unclosed bracket"},
+		{expression: "provisioningInput.machinePools[+].name", wantErr: "[+] must end the path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			path, err := ParsePath(tt.expression)
+			var values []any
+			if err == nil {
+				values, err = path.Get(testConfig())
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Get() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if fmt.Sprint(values) != tt.want {
+				t.Errorf("Get() = %v, want %s", values, tt.want)
+			}
+		})
+	}
+}
+
+func Test_PathSetAndDelete(t *testing.T) {
+	config := testConfig()
+
+	set := func(expression string, value any) {
+		path, err := ParsePath(expression)
+		if err == nil {
+			err = path.Set(config, value)
+		}
+		if err != nil {
+			t.Fatalf("Set(%s) error = %v", expression, err)
+		}
+	}
+	set("provisioningInput.machinePools[name=worker].quantity", 5)
+	set("provisioningInput.machinePools[+]", map[string]any{"name": "windows", "quantity": 1})
+	set("provisioningInput.nodeProviders[+]", "ec2")
+	set("provisioningInput.cni.name", "calico")
+
+	path, _ := ParsePath("provisioningInput.machinePools[name=etcd]")
+	if err := path.Delete(config); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got := fmt.Sprint(config["provisioningInput"])
+	want := "map[cni:map[name:calico] labels:map[app.kubernetes.io/name:shepherd] machinePools:[map[name:cp quantity:1] map[name:worker quantity:5] map[name:windows quantity:1]] nodeProviders:[ec2]]"
+	if got != want {
+		t.Errorf("config = %s, want %s", got, want)
+	}
+}
+
+func Test_KeyPath(t *testing.T) {
+	config := testConfig()
+
+	_, err := ReplaceValue([]string{"provisioningInput", "machinePools", "quantity"}, 2, config)
+	if err != nil {
+		t.Fatalf("ReplaceValue() error = %v", err)
+	}
+	values, err := GetValues([]string{"provisioningInput", "machinePools", "quantity"}, config)
+	if err != nil || fmt.Sprint(values) != "[2 2 2]" {
+		t.Errorf("GetValues() = %v, %v, want every quantity replaced", values, err)
+	}
+
+	_, err = GetValue([]string{"provisioningInput", "machinePools", "quantity"}, config)
+	if err == nil {
+		t.Errorf("GetValue() of several values succeeded, want an error")
+	}
+
+	value, err := GetValue([]string{"provisioningInput", "labels", "app.kubernetes.io/name"}, config)
+	if err != nil || value != "shepherd" {
+		t.Errorf("GetValue() = %v, %v, want the label with dots", value, err)
+	}
+
+	_, err = ReplaceValue([]string{"provisioningInput", "labels", "app.kubernetes.io/name", "nested"}, 1, config)
+	if err == nil || !strings.Contains(err.Error(), "expected a map, got string") {
+		t.Errorf("ReplaceValue() error = %v, want a type mismatch", err)
+	}
+}
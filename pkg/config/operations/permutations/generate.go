@@ -0,0 +1,535 @@
+package permutations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	mapOperations "github.com/rancher/shepherd/pkg/config/operations"
+)
+
+const (
+	// IDsEnvironmentKey is the environment variable listing the comma separated IDs of the configs Generate returns,
+	// so that failing configs can be re-run alone.
+	IDsEnvironmentKey = "CATTLE_TEST_PERMUTATION_IDS"
+
+	// candidateRows is the number of candidate rows built for every row added to a covering array.
+	candidateRows = 20
+	// maxCompletions is the number of completions of a tuple searched when no greedy candidate row is allowed.
+	maxCompletions = 10000
+	idLength       = 12
+)
+
+// Condition matches the configs whose value at KeyPath matches.
+type Condition struct {
+	KeyPath []string
+	Match   func(value any) bool
+}
+
+// Is returns a Condition matching the configs whose value at keyPath is one of values. The values are compared in
+// their JSON form, so that e.g. 2 matches the float64 2 of a config loaded from yaml.
+func Is(keyPath []string, values ...any) Condition {
+	normalized := make([]any, 0, len(values))
+	for _, value := range values {
+		normalized = append(normalized, normalize(value))
+	}
+
+	return Condition{
+		KeyPath: keyPath,
+		Match: func(value any) bool {
+			value = normalize(value)
+			for _, candidate := range normalized {
+				if reflect.DeepEqual(candidate, value) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Contains returns a Condition matching the configs whose value at keyPath contains substring, e.g. "k3s" for the
+// k3s versions.
+func Contains(keyPath []string, substring string) Condition {
+	return Condition{
+		KeyPath: keyPath,
+		Match: func(value any) bool {
+			s, ok := value.(string)
+			return ok && strings.Contains(s, substring)
+		},
+	}
+}
+
+// Constraint excludes the configs it returns true for.
+type Constraint func(config map[string]any) bool
+
+// Exclude returns a Constraint excluding the configs matching all of conditions, e.g. windows nodes with k3s:
+//
+//	permutations.Exclude(
+//		permutations.Is([]string{"provisioningInput", "nodeOS"}, "windows"),
+//		permutations.Contains([]string{"provisioningInput", "k8sVersion"}, "k3s"),
+//	)
+func Exclude(conditions ...Condition) Constraint {
+	return func(config map[string]any) bool {
+		for _, condition := range conditions {
+			value, err := mapOperations.GetValue(condition.KeyPath, config)
+			if err != nil || !condition.Match(value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Filter returns the configs that no constraint excludes.
+func Filter(configs []map[string]any, constraints ...Constraint) []map[string]any {
+	var filtered []map[string]any
+	for _, config := range configs {
+		if !excluded(config, constraints) {
+			filtered = append(filtered, config)
+		}
+	}
+
+	return filtered
+}
+
+func excluded(config map[string]any, constraints []Constraint) bool {
+	for _, constraint := range constraints {
+		if constraint(config) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Options are the options of Generate.
+type Options struct {
+	// Constraints exclude configs from the generated ones.
+	Constraints []Constraint
+	// Strength is the number of permutations whose value combinations are all covered, 2 generates a pairwise
+	// covering array. 0, or a strength greater or equal to the number of permutations, generates every combination.
+	Strength int
+	// Sample is the number of configs sampled from the generated ones, 0 returns all of them.
+	Sample int
+	// Seed seeds the covering array construction and the sampling, the same seed always generates the same configs.
+	Seed int64
+	// IDs restricts the generated configs to the ones with these IDs. When empty, the IDs listed in
+	// CATTLE_TEST_PERMUTATION_IDS are used.
+	IDs []string
+}
+
+// Generated is a config generated from permutations.
+type Generated struct {
+	// ID identifies the values of the permutations in the config, it does not depend on the options.
+	ID string
+	// Name is a readable description of the values of the permutations in the config, e.g. for subtests names.
+	Name string
+	// Values are the values of the permutations in the config, by dot separated key path.
+	Values map[string]any
+	Config map[string]any
+}
+
+// assignment is the value set at a key path.
+type assignment struct {
+	keyPath []string
+	value   any
+}
+
+// level is one of the alternatives of a permutation: its value and the values of the child permutations related
+// to it.
+type level []assignment
+
+// Generate permutes baseConfig with permutations like Permute, excluding the configs matching the constraints. With
+// a strength, only enough configs to cover every combination of the values of any strength permutations are
+// generated, the relationships of a permutation are kept together with its values. Combinations that the constraints
+// exclude from every config are left uncovered.
+func Generate(permutations []Permutation, baseConfig map[string]any, opts Options) ([]Generated, error) {
+	if len(permutations) == 0 {
+		return nil, errors.New("no permutations provided")
+	}
+
+	factors := make([][]level, 0, len(permutations))
+	for _, permutation := range permutations {
+		levels := levelsOf(permutation)
+		if len(levels) == 0 {
+			return nil, fmt.Errorf("permutation %s has no values", strings.Join(permutation.KeyPath, "."))
+		}
+		factors = append(factors, levels)
+	}
+
+	build := func(row []int) (map[string]any, error) {
+		config, err := mapOperations.DeepCopyMap(baseConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		for factor, levelIndex := range row {
+			for _, assignment := range factors[factor][levelIndex] {
+				config, err = mapOperations.ReplaceValue(assignment.keyPath, assignment.value, config)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return config, nil
+	}
+
+	var buildErr error
+	allowed := func(row []int) bool {
+		config, err := build(row)
+		if err != nil {
+			buildErr = err
+			return false
+		}
+		return !excluded(config, opts.Constraints)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	var rows [][]int
+	if opts.Strength <= 0 || opts.Strength >= len(factors) {
+		rows = product(factors, allowed)
+	} else {
+		rows = coveringArray(factors, opts.Strength, allowed, rng)
+	}
+	if buildErr != nil {
+		return nil, buildErr
+	}
+
+	ids := opts.IDs
+	if len(ids) == 0 && os.Getenv(IDsEnvironmentKey) != "" {
+		ids = strings.Split(os.Getenv(IDsEnvironmentKey), ",")
+	}
+
+	var generated []Generated
+	for _, row := range rows {
+		config, err := build(row)
+		if err != nil {
+			return nil, err
+		}
+
+		g := newGenerated(factors, row, config)
+		if len(ids) > 0 && !containsID(ids, g.ID) {
+			continue
+		}
+		generated = append(generated, g)
+	}
+
+	if opts.Sample > 0 && opts.Sample < len(generated) {
+		picked := rng.Perm(len(generated))[:opts.Sample]
+		sort.Ints(picked)
+
+		sampled := make([]Generated, 0, opts.Sample)
+		for _, i := range picked {
+			sampled = append(sampled, generated[i])
+		}
+		generated = sampled
+	}
+
+	return generated, nil
+}
+
+// levelsOf returns the levels of permutation, applying its relationships the way Permute does.
+func levelsOf(permutation Permutation) []level {
+	var levels []level
+	for _, value := range permutation.KeyPathValues {
+		combinations := []level{{{keyPath: permutation.KeyPath, value: value}}}
+
+		for _, relationship := range permutation.KeyPathValueRelationships {
+			if len(relationship.ChildPermutations) == 0 || relationship.ParentValue != value {
+				continue
+			}
+
+			for _, child := range relationship.ChildPermutations {
+				var expanded []level
+				for _, combination := range combinations {
+					for _, childLevel := range levelsOf(child) {
+						expanded = append(expanded, append(append(level{}, combination...), childLevel...))
+					}
+				}
+				combinations = expanded
+			}
+		}
+
+		levels = append(levels, combinations...)
+	}
+
+	return levels
+}
+
+// product returns the allowed rows of the cartesian product of factors, the first factor varying the slowest.
+func product(factors [][]level, allowed func([]int) bool) [][]int {
+	var rows [][]int
+	row := make([]int, len(factors))
+	for {
+		if allowed(row) {
+			rows = append(rows, append([]int{}, row...))
+		}
+
+		i := len(row) - 1
+		for ; i >= 0; i-- {
+			row[i]++
+			if row[i] < len(factors[i]) {
+				break
+			}
+			row[i] = 0
+		}
+		if i < 0 {
+			return rows
+		}
+	}
+}
+
+// coveringArray greedily builds allowed rows until every combination of the levels of any strength factors is in a
+// row, or could not be completed into an allowed row.
+func coveringArray(factors [][]level, strength int, allowed func([]int) bool, rng *rand.Rand) [][]int {
+	combos := combinations(len(factors), strength)
+	combosByFactor := make([][][]int, len(factors))
+	for _, combo := range combos {
+		for _, factor := range combo {
+			combosByFactor[factor] = append(combosByFactor[factor], combo)
+		}
+	}
+
+	// the uncovered tuples are kept in generation order so that the construction is deterministic
+	type tuple struct {
+		key string
+		row []int
+	}
+	var tuples []tuple
+	uncovered := map[string]bool{}
+	for _, combo := range combos {
+		levels := make([]int, len(combo))
+		for {
+			row := make([]int, len(factors))
+			for i := range row {
+				row[i] = -1
+			}
+			for i, factor := range combo {
+				row[factor] = levels[i]
+			}
+			key := tupleKey(combo, row)
+			tuples = append(tuples, tuple{key: key, row: row})
+			uncovered[key] = true
+
+			i := len(levels) - 1
+			for ; i >= 0; i-- {
+				levels[i]++
+				if levels[i] < len(factors[combo[i]]) {
+					break
+				}
+				levels[i] = 0
+			}
+			if i < 0 {
+				break
+			}
+		}
+	}
+
+	countNew := func(row []int, combos [][]int) int {
+		count := 0
+		for _, combo := range combos {
+			complete := true
+			for _, factor := range combo {
+				complete = complete && row[factor] >= 0
+			}
+			if complete && uncovered[tupleKey(combo, row)] {
+				count++
+			}
+		}
+		return count
+	}
+
+	var rows [][]int
+	for _, seed := range tuples {
+		if !uncovered[seed.key] {
+			continue
+		}
+
+		var best []int
+		bestScore := 0
+		for attempt := 0; attempt < candidateRows; attempt++ {
+			row := append([]int{}, seed.row...)
+			for _, factor := range rng.Perm(len(factors)) {
+				if row[factor] >= 0 {
+					continue
+				}
+
+				var candidates []int
+				candidateScore := -1
+				for levelIndex := range factors[factor] {
+					row[factor] = levelIndex
+					score := countNew(row, combosByFactor[factor])
+					if score > candidateScore {
+						candidates, candidateScore = []int{levelIndex}, score
+					} else if score == candidateScore {
+						candidates = append(candidates, levelIndex)
+					}
+				}
+				row[factor] = candidates[rng.Intn(len(candidates))]
+			}
+
+			if !allowed(row) {
+				continue
+			}
+			if score := countNew(row, combos); best == nil || score > bestScore {
+				best, bestScore = row, score
+			}
+		}
+
+		if best == nil {
+			// the greedy rows were all excluded, the completions of the tuple are searched instead
+			best = complete(factors, seed.row, allowed, func(row []int) int { return countNew(row, combos) })
+		}
+		if best == nil {
+			// the tuple is excluded by the constraints
+			delete(uncovered, seed.key)
+			continue
+		}
+
+		for _, combo := range combos {
+			delete(uncovered, tupleKey(combo, best))
+		}
+		rows = append(rows, best)
+	}
+
+	return rows
+}
+
+// complete returns the allowed completion of the unset factors of row covering the most new tuples, or nil when
+// none of the first maxCompletions is allowed.
+func complete(factors [][]level, row []int, allowed func([]int) bool, score func([]int) int) []int {
+	var unset []int
+	for factor, levelIndex := range row {
+		if levelIndex < 0 {
+			unset = append(unset, factor)
+		}
+	}
+
+	var best []int
+	bestScore := 0
+	candidate := append([]int{}, row...)
+	for _, factor := range unset {
+		candidate[factor] = 0
+	}
+
+	for searched := 0; searched < maxCompletions; searched++ {
+		if allowed(candidate) {
+			if candidateScore := score(candidate); best == nil || candidateScore > bestScore {
+				best, bestScore = append([]int{}, candidate...), candidateScore
+			}
+		}
+
+		i := len(unset) - 1
+		for ; i >= 0; i-- {
+			candidate[unset[i]]++
+			if candidate[unset[i]] < len(factors[unset[i]]) {
+				break
+			}
+			candidate[unset[i]] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+
+	return best
+}
+
+// combinations returns the combinations of size k of the integers from 0 to n, in lexicographic order.
+func combinations(n, k int) [][]int {
+	var combos [][]int
+	combo := make([]int, k)
+
+	var fill func(start, index int)
+	fill = func(start, index int) {
+		if index == k {
+			combos = append(combos, append([]int{}, combo...))
+			return
+		}
+		for i := start; i <= n-(k-index); i++ {
+			combo[index] = i
+			fill(i+1, index+1)
+		}
+	}
+	fill(0, 0)
+
+	return combos
+}
+
+func tupleKey(combo, row []int) string {
+	var key strings.Builder
+	for _, factor := range combo {
+		fmt.Fprintf(&key, "%d:%d,", factor, row[factor])
+	}
+
+	return key.String()
+}
+
+// newGenerated describes the config built from row.
+func newGenerated(factors [][]level, row []int, config map[string]any) Generated {
+	values := map[string]any{}
+	var names []string
+	for factor, levelIndex := range row {
+		for _, assignment := range factors[factor][levelIndex] {
+			path := strings.Join(assignment.keyPath, ".")
+			values[path] = assignment.value
+
+			key := assignment.keyPath[len(assignment.keyPath)-1]
+			switch value := normalize(assignment.value).(type) {
+			case map[string]any, []any:
+				// structured values are named after their hash
+				data, _ := json.Marshal(value)
+				sum := sha256.Sum256(data)
+				names = append(names, key+"="+hex.EncodeToString(sum[:])[:6])
+			default:
+				names = append(names, fmt.Sprintf("%s=%v", key, value))
+			}
+		}
+	}
+
+	// json sorts the map keys, so the ID only depends on the values
+	data, _ := json.Marshal(values)
+	sum := sha256.Sum256(data)
+
+	return Generated{
+		ID:     hex.EncodeToString(sum[:])[:idLength],
+		Name:   strings.Join(names, ","),
+		Values: values,
+		Config: config,
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if strings.TrimSpace(candidate) == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalize converts value to its json representation, the way DeepCopyMap does for the configs.
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+
+	return normalized
+}
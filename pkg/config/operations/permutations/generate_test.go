@@ -0,0 +1,145 @@
+package permutations
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func matrix() ([]Permutation, map[string]any) {
+	baseConfig := map[string]any{
+		"provisioning": map[string]any{
+			"provider":   "",
+			"k8sVersion": "",
+			"cni":        "",
+			"os":         "",
+		},
+	}
+
+	permutations := []Permutation{
+		CreatePermutation([]string{"provisioning", "provider"}, []any{"aws", "azure", "vsphere"}, nil),
+		CreatePermutation([]string{"provisioning", "k8sVersion"}, []any{"v1.30.2+rke2r1", "v1.30.2+k3s1", "v1.29.6+rke2r1"}, nil),
+		CreatePermutation([]string{"provisioning", "cni"}, []any{"calico", "canal", "cilium"}, nil),
+		CreatePermutation([]string{"provisioning", "os"}, []any{"ubuntu", "sles", "windows"}, nil),
+	}
+
+	return permutations, baseConfig
+}
+
+func Test_GeneratePairwise(t *testing.T) {
+	permutations, baseConfig := matrix()
+	windowsWithK3s := Exclude(
+		Is([]string{"provisioning", "os"}, "windows"),
+		Contains([]string{"provisioning", "k8sVersion"}, "k3s"),
+	)
+
+	generated, err := Generate(permutations, baseConfig, Options{Strength: 2, Constraints: []Constraint{windowsWithK3s}})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(generated) >= 81 || len(generated) < 9 {
+		t.Errorf("Generate() returned %d configs, want a pairwise covering array smaller than the 81 combinations", len(generated))
+	}
+
+	covered := map[string]bool{}
+	for _, g := range generated {
+		if windowsWithK3s(g.Config) {
+			t.Errorf("Generate() returned the excluded config %s", g.Name)
+		}
+		for _, a := range permutations {
+			for _, b := range permutations {
+				covered[fmt.Sprint(g.Values[pathOf(a)], "/", g.Values[pathOf(b)])] = true
+			}
+		}
+	}
+
+	for i, a := range permutations {
+		for _, b := range permutations[i+1:] {
+			for _, aValue := range a.KeyPathValues {
+				for _, bValue := range b.KeyPathValues {
+					pair := fmt.Sprint(aValue, "/", bValue)
+					excludedPair := pair == "v1.30.2+k3s1/windows"
+					if covered[pair] == excludedPair {
+						t.Errorf("pair %s covered = %v, want %v", pair, covered[pair], !excludedPair)
+					}
+				}
+			}
+		}
+	}
+}
+
+func Test_GenerateExcludeNumber(t *testing.T) {
+	baseConfig := map[string]any{"provisioning": map[string]any{"nodeCount": 0}}
+	permutations := []Permutation{
+		CreatePermutation([]string{"provisioning", "nodeCount"}, []any{1, 2, int32(3)}, nil),
+	}
+	excludeTwo := Exclude(Is([]string{"provisioning", "nodeCount"}, 2))
+
+	generated, err := Generate(permutations, baseConfig, Options{Constraints: []Constraint{excludeTwo}})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(generated) != 2 {
+		t.Fatalf("Generate() returned %d configs, want the 2 configs without 2 nodes", len(generated))
+	}
+	for _, g := range generated {
+		if fmt.Sprint(g.Values[pathOf(permutations[0])]) == "2" {
+			t.Errorf("Generate() returned the excluded config %s", g.Name)
+		}
+	}
+
+	if !Is([]string{"provisioning", "nodeCount"}, 3).Match(int32(3)) {
+		t.Errorf("Is(3) does not match int32(3)")
+	}
+}
+
+func Test_GenerateIDs(t *testing.T) {
+	permutations, baseConfig := matrix()
+
+	all, err := Generate(permutations, baseConfig, Options{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(all) != 81 {
+		t.Fatalf("Generate() returned %d configs, want all the 81 combinations", len(all))
+	}
+
+	sampled, err := Generate(permutations, baseConfig, Options{Sample: 5, Seed: 42})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	again, _ := Generate(permutations, baseConfig, Options{Sample: 5, Seed: 42})
+	if len(sampled) != 5 || fmt.Sprint(idsOf(sampled)) != fmt.Sprint(idsOf(again)) {
+		t.Errorf("Generate() sampled %v then %v, want the same 5 configs", idsOf(sampled), idsOf(again))
+	}
+
+	t.Setenv(IDsEnvironmentKey, sampled[2].ID)
+	rerun, err := Generate(permutations, baseConfig, Options{Strength: 2})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(rerun) > 1 || len(rerun) == 1 && rerun[0].Name != sampled[2].Name {
+		t.Errorf("Generate() with %s = %v, want at most the config %s", IDsEnvironmentKey, idsOf(rerun), sampled[2].ID)
+	}
+
+	only, err := Generate(permutations, baseConfig, Options{IDs: []string{sampled[2].ID}})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(only) != 1 || only[0].Name != sampled[2].Name {
+		t.Errorf("Generate() with IDs = %v, want the config %s", idsOf(only), sampled[2].ID)
+	}
+}
+
+func pathOf(permutation Permutation) string {
+	return strings.Join(permutation.KeyPath, ".")
+}
+
+func idsOf(generated []Generated) []string {
+	var ids []string
+	for _, g := range generated {
+		ids = append(ids, g.ID)
+	}
+
+	return ids
+}
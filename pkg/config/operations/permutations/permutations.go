@@ -12,7 +12,9 @@ type Relationship struct {
 	ChildPermutations []Permutation `json:"childPermutations" yaml:"childPermutations"`
 }
 
-// Permutation structs are used to describe a single permutation
+// Permutation structs are used to describe a single permutation. The elements of the KeyPath are keys optionally
+// followed by list indexes or selectors, e.g. []string{"machinePools[name=etcd]", "quantity"}, see
+// operations.ParseKeyPath.
 type Permutation struct {
 	KeyPath                   []string       `json:"keyPath" yaml:"keyPath"`
 	KeyPathValues             []any          `json:"keyPathValue" yaml:"keyPath"`
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// registry maps the config keys to the types registered for them. A key may be registered with several types when
+// packages share it, e.g. openLDAP.
+var registry = struct {
+	lock  sync.RWMutex
+	types map[string][]reflect.Type
+}{
+	types: map[string][]reflect.Type{},
+}
+
+// Register registers the type of prototype as the type of the config found at key, so that the config can be
+// validated with Config.Validate and described by JSONSchema. Packages register their configs in init:
+//
+//	func init() {
+//		config.Register(ConfigurationFileKey, Config{})
+//	}
+func Register(key string, prototype any) {
+	t := reflect.TypeOf(prototype)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	for _, registered := range registry.types[key] {
+		if registered == t {
+			return
+		}
+	}
+	registry.types[key] = append(registry.types[key], t)
+}
+
+// RegisteredKeys returns the registered config keys, sorted.
+func RegisteredKeys() []string {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	keys := make([]string, 0, len(registry.types))
+	for key := range registry.types {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// registeredTypes returns the types registered for key.
+func registeredTypes(key string) []reflect.Type {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	return registry.types[key]
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// JSONSchema returns the JSON Schema of a CATTLE_TEST_CONFIG file with the registered config keys, so that editors
+// can complete and check config files. The `default:` and `validate:` tags are part of the schema.
+func JSONSchema() ([]byte, error) {
+	properties := map[string]any{}
+	for _, key := range RegisteredKeys() {
+		types := registeredTypes(key)
+		if len(types) == 1 {
+			properties[key] = typeSchema(types[0], map[reflect.Type]bool{})
+			continue
+		}
+
+		var anyOf []any
+		for _, t := range types {
+			anyOf = append(anyOf, typeSchema(t, map[reflect.Type]bool{}))
+		}
+		properties[key] = map[string]any{"anyOf": anyOf}
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"$schema":    jsonSchemaDraft,
+		"title":      ConfigEnvironmentKey,
+		"type":       "object",
+		"properties": properties,
+	}, "", "  ")
+}
+
+// typeSchema returns the JSON Schema of t, visiting holds the structs being described to stop on recursive types.
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// types with their own encoding, e.g. metav1.Time, are not described
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{},
+			"additionalProperties": false,
+		}
+		structSchema(t, schema, visiting)
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema adds the fields of the struct t to the properties of schema, inlining the embedded structs.
+func structSchema(t reflect.Type, schema map[string]any, visiting map[reflect.Type]bool) {
+	properties := schema["properties"].(map[string]any)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				structSchema(embedded, schema, visiting)
+			}
+			continue
+		}
+
+		fieldSchema := typeSchema(field.Type, visiting)
+		if value, ok := field.Tag.Lookup("default"); ok && value != "" {
+			if defaultValue, ok := parseScalar(fieldSchema, value); ok {
+				fieldSchema["default"] = defaultValue
+			}
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			ruleName, argument, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			switch ruleName {
+			case "required":
+				required, _ := schema["required"].([]string)
+				schema["required"] = append(required, name)
+			case "min", "max":
+				if keyword := boundKeyword(fieldSchema, ruleName); keyword != "" {
+					if bound, err := strconv.ParseFloat(argument, 64); err == nil {
+						fieldSchema[keyword] = bound
+					}
+				}
+			case "oneof":
+				var enum []any
+				for _, value := range strings.Fields(argument) {
+					if parsed, ok := parseScalar(fieldSchema, value); ok {
+						enum = append(enum, parsed)
+					}
+				}
+				fieldSchema["enum"] = enum
+			case "url":
+				fieldSchema["format"] = "uri"
+			}
+		}
+
+		properties[name] = fieldSchema
+	}
+}
+
+// boundKeyword returns the JSON Schema keyword of the min or max rule for the type of schema.
+func boundKeyword(schema map[string]any, rule string) string {
+	switch schema["type"] {
+	case "integer", "number":
+		return rule + "imum"
+	case "string":
+		return rule + "Length"
+	case "array":
+		return rule + "Items"
+	case "object":
+		return rule + "Properties"
+	default:
+		return ""
+	}
+}
+
+// parseScalar parses value as the scalar type of schema.
+func parseScalar(schema map[string]any, value string) (any, bool) {
+	switch schema["type"] {
+	case "string":
+		return value, true
+	case "boolean":
+		parsed, err := strconv.ParseBool(value)
+		return parsed, err == nil
+	case "integer":
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		return parsed, err == nil
+	case "number":
+		parsed, err := strconv.ParseFloat(value, 64)
+		return parsed, err == nil
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// EnvReferencePrefix prefixes the values read from an environment variable, e.g. env:RANCHER_ADMIN_TOKEN.
+	EnvReferencePrefix = "env:"
+	// FileReferencePrefix prefixes the values read from a file, e.g. file:/home/user/.ssh/id_rsa.
+	FileReferencePrefix = "file:"
+	// ExecReferencePrefix prefixes the values printed by a command, e.g. exec:vault kv get -field=token secret/rancher.
+	ExecReferencePrefix = "exec:"
+
+	// Redacted replaces the secrets in redacted configs.
+	Redacted = "REDACTED"
+
+	execReferenceTimeout = 30 * time.Second
+)
+
+// SecretFields are the lower cased names of the fields redacted by MarshalRedacted, whose environment overrides are
+// not persisted by UpdateConfig and WriteConfig, and whose references are resolved when loading the configs.
+var SecretFields = map[string]bool{
+	"accesskey":         true,
+	"accesskeysecret":   true,
+	"accesstoken":       true,
+	"admintoken":        true,
+	"adminpassword":     true,
+	"apikey":            true,
+	"authencodedjson":   true,
+	"clientsecret":      true,
+	"kubeconfigcontent": true,
+	"password":          true,
+	"privatekey":        true,
+	"secret":            true,
+	"secretaccesskey":   true,
+	"secretkey":         true,
+	"sshkey":            true,
+	"sshprivatekey":     true,
+	"token":             true,
+}
+
+// resolvedSecrets tracks the references resolved while loading configs, so that they are written back instead of
+// the secrets and the secrets are redacted wherever they appear. The secrets of the file and exec references are
+// cached, so that their files are read and their commands run once per process.
+var resolvedSecrets = struct {
+	lock       sync.Mutex
+	references map[string]secretReference
+	values     map[string]bool
+	cache      map[string]string
+}{
+	references: map[string]secretReference{},
+	values:     map[string]bool{},
+	cache:      map[string]string{},
+}
+
+// secretReference is a reference resolved while loading the config key, found at path in it. The elements of path
+// are the string keys of maps and the int indexes of lists.
+type secretReference struct {
+	key       string
+	path      []any
+	reference string
+}
+
+// IsReference returns whether value is a reference to a secret, resolved when the config is loaded.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, EnvReferencePrefix) || strings.HasPrefix(value, FileReferencePrefix) ||
+		strings.HasPrefix(value, ExecReferencePrefix)
+}
+
+// ResolveReference returns the secret reference points to. Files and command outputs are trimmed of their trailing
+// newlines.
+func ResolveReference(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, EnvReferencePrefix):
+		name := strings.TrimPrefix(reference, EnvReferencePrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s of reference %s is not set", name, reference)
+		}
+		return value, nil
+	case strings.HasPrefix(reference, FileReferencePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(reference, FileReferencePrefix))
+		if err != nil {
+			return "", fmt.Errorf("error reading reference %s: %w", reference, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(reference, ExecReferencePrefix):
+		args := strings.Fields(strings.TrimPrefix(reference, ExecReferencePrefix))
+		if len(args) == 0 {
+			return "", fmt.Errorf("reference %s has no command", reference)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), execReferenceTimeout)
+		defer cancel()
+
+		// the reference is not logged since the command may contain credentials
+		output, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("error running the command of reference %s%s: %w", ExecReferencePrefix, args[0], err)
+		}
+		return strings.TrimRight(string(output), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("%q is not a reference", reference)
+	}
+}
+
+// resolveReferences replaces the references in value, found at path in the config key, with their secrets. Only the
+// values of the SecretFields, and the values nested under them, are resolved: secret is whether value is one of them.
+func resolveReferences(key string, path []any, value any, secret bool) (any, error) {
+	switch value := value.(type) {
+	case map[string]any:
+		for field, nested := range value {
+			resolved, err := resolveReferences(key, appendPath(path, field), nested, secret || SecretFields[strings.ToLower(field)])
+			if err != nil {
+				return nil, err
+			}
+			value[field] = resolved
+		}
+	case []any:
+		for i, nested := range value {
+			resolved, err := resolveReferences(key, appendPath(path, i), nested, secret)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = resolved
+		}
+	case string:
+		if !secret || !IsReference(value) {
+			return value, nil
+		}
+
+		resolved, err := resolveCachedReference(value)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving %s: %w", formatPath(key, path), err)
+		}
+		trackSecret(secretReference{key: key, path: path, reference: value}, resolved)
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+// resolveCachedReference resolves reference, the file and exec references only the first time they are resolved.
+func resolveCachedReference(reference string) (string, error) {
+	if strings.HasPrefix(reference, EnvReferencePrefix) {
+		return ResolveReference(reference)
+	}
+
+	resolvedSecrets.lock.Lock()
+	secret, ok := resolvedSecrets.cache[reference]
+	resolvedSecrets.lock.Unlock()
+	if ok {
+		return secret, nil
+	}
+
+	secret, err := ResolveReference(reference)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedSecrets.lock.Lock()
+	resolvedSecrets.cache[reference] = secret
+	resolvedSecrets.lock.Unlock()
+
+	return secret, nil
+}
+
+// trackSecret records that the value of reference is secret.
+func trackSecret(reference secretReference, secret string) {
+	resolvedSecrets.lock.Lock()
+	defer resolvedSecrets.lock.Unlock()
+
+	resolvedSecrets.references[formatPath(reference.key, reference.path)] = reference
+	if secret != "" {
+		resolvedSecrets.values[secret] = true
+	}
+}
+
+// withReferences converts config, found at key, to a yaml value in which the resolved secrets are replaced with
+// their references.
+func withReferences(key string, config any) (any, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	err = yaml.Unmarshal(data, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedSecrets.lock.Lock()
+	defer resolvedSecrets.lock.Unlock()
+
+	for _, reference := range resolvedSecrets.references {
+		if reference.key == key && len(reference.path) > 0 {
+			setPath(value, reference.path, reference.reference)
+		}
+	}
+
+	return value, nil
+}
+
+// setPath sets the existing string at path in value to reference.
+func setPath(value any, path []any, reference string) {
+	var current any
+	var set func(any)
+	switch element := path[0].(type) {
+	case string:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		if current, ok = fields[element]; !ok {
+			return
+		}
+		set = func(v any) { fields[element] = v }
+	case int:
+		items, ok := value.([]any)
+		if !ok || element >= len(items) {
+			return
+		}
+		current = items[element]
+		set = func(v any) { items[element] = v }
+	}
+
+	if len(path) > 1 {
+		setPath(current, path[1:], reference)
+		return
+	}
+	if _, ok := current.(string); ok {
+		set(reference)
+	}
+}
+
+// appendPath returns a copy of path with element appended, so that the paths of sibling values do not share their
+// backing array.
+func appendPath(path []any, element any) []any {
+	return append(slices.Clone(path), element)
+}
+
+// formatPath returns the path in the config key as a string, e.g. rancher.items[0].secretKey.
+func formatPath(key string, path []any) string {
+	formatted := key
+	for _, element := range path {
+		switch element := element.(type) {
+		case int:
+			formatted += fmt.Sprintf("[%d]", element)
+		default:
+			formatted = joinPath(formatted, fmt.Sprint(element))
+		}
+	}
+
+	return formatted
+}
+
+// MarshalRedacted marshals config to YAML with the values of the SecretFields and the resolved secrets replaced with
+// Redacted, so that it can be logged.
+func MarshalRedacted(config any) ([]byte, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	err = yaml.Unmarshal(data, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedSecrets.lock.Lock()
+	value = redact(value, false)
+	resolvedSecrets.lock.Unlock()
+
+	return yaml.Marshal(value)
+}
+
+// redact replaces the secrets in value, secret is whether value is the value of a secret field. The resolvedSecrets
+// lock must be held.
+func redact(value any, secret bool) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, nested := range value {
+			value[key] = redact(nested, secret || SecretFields[strings.ToLower(key)])
+		}
+	case []any:
+		for i, nested := range value {
+			value[i] = redact(nested, secret)
+		}
+	case string:
+		if value != "" && (secret || resolvedSecrets.values[value]) {
+			return Redacted
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testSecretConfig struct {
+	Host       string `json:"host" yaml:"host"`
+	AdminToken string `json:"adminToken" yaml:"adminToken"`
+	SSHKey     string `json:"sshKey" yaml:"sshKey"`
+	Password   string `json:"password" yaml:"password"`
+	Region     string `json:"region" yaml:"region"`
+	Items      []struct {
+		Name      string `json:"name" yaml:"name"`
+		SecretKey string `json:"secretKey" yaml:"secretKey"`
+	} `json:"items" yaml:"items"`
+}
+
+func Test_SecretReferences(t *testing.T) {
+	keyFile := writeFile(t, "id_rsa", "private key\n")
+	configFile := writeFile(t, "config.yaml", `
+secrets:
+  host: rancher.example.com
+  adminToken: env:TEST_SECRETS_ADMIN_TOKEN
+  sshKey: file:`+keyFile+`
+  password: exec:echo hunter2
+  region: exec:echo us-east-1
+  items:
+  - name: backup
+    secretKey: env:TEST_SECRETS_SECRET_KEY
+`)
+	t.Setenv(ConfigEnvironmentKey, configFile)
+	t.Setenv("TEST_SECRETS_ADMIN_TOKEN", "token-abc:secret")
+	t.Setenv("TEST_SECRETS_SECRET_KEY", "secret-key-value")
+
+	var config testSecretConfig
+	err := Load("secrets", &config)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.AdminToken != "token-abc:secret" || config.SSHKey != "private key" || config.Password != "hunter2" ||
+		len(config.Items) != 1 || config.Items[0].SecretKey != "secret-key-value" {
+		t.Fatalf("Load() = %+v, want the resolved references", config)
+	}
+	if config.Region != "exec:echo us-east-1" {
+		t.Errorf("Load() region = %s, want the reference of a field that is not secret left as it is", config.Region)
+	}
+
+	redacted, err := MarshalRedacted(config)
+	if err != nil {
+		t.Fatalf("MarshalRedacted() error = %v", err)
+	}
+	if strings.Contains(string(redacted), "token-abc:secret") || strings.Contains(string(redacted), "private key") ||
+		strings.Contains(string(redacted), "hunter2") ||
+		strings.Contains(string(redacted), "secret-key-value") || !strings.Contains(string(redacted), "rancher.example.com") {
+		t.Errorf("MarshalRedacted() = %s, want the secrets redacted", redacted)
+	}
+
+	config.Host = "updated.example.com"
+	UpdateConfig("secrets", &config)
+
+	written, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	for _, want := range []string{
+		"updated.example.com", "env:TEST_SECRETS_ADMIN_TOKEN", "file:" + keyFile, "exec:echo hunter2",
+		"secretKey: env:TEST_SECRETS_SECRET_KEY",
+	} {
+		if !strings.Contains(string(written), want) {
+			t.Errorf("UpdateConfig() wrote %s, want it to contain %s", written, want)
+		}
+	}
+	if strings.Contains(string(written), "token-abc:secret") || strings.Contains(string(written), "secret-key-value") {
+		t.Errorf("UpdateConfig() wrote %s, want the resolved secrets left out", written)
+	}
+}
+
+func Test_ExecReferenceCached(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := writeFile(t, "password.sh", "echo run >> "+runs+"\necho password\n")
+	configFile := writeFile(t, "config.yaml", `
+secrets:
+  password: exec:sh `+script+`
+`)
+	t.Setenv(ConfigEnvironmentKey, configFile)
+
+	for i := 0; i < 2; i++ {
+		var config testSecretConfig
+		err := Load("secrets", &config)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if config.Password != "password" {
+			t.Fatalf("Load() password = %s, want password", config.Password)
+		}
+	}
+
+	data, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if got := strings.Count(string(data), "run"); got != 1 {
+		t.Errorf("the command ran %d times, want 1", got)
+	}
+}
+
+func Test_ResolveReferenceErrors(t *testing.T) {
+	for _, reference := range []string{
+		"env:TEST_SECRETS_UNSET_VARIABLE",
+		"file:" + filepath.Join(t.TempDir(), "missing"),
+		"exec:",
+		"plain",
+	} {
+		if _, err := ResolveReference(reference); err == nil {
+			t.Errorf("ResolveReference(%s) succeeded, want an error", reference)
+		}
+	}
+}
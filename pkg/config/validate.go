@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"sigs.k8s.io/yaml"
+)
+
+// StrictEnvironmentKey is the environment variable that, when set to true, makes LoadConfig and Load report the
+// unknown fields of the configs instead of ignoring them.
+const StrictEnvironmentKey = "CATTLE_TEST_CONFIG_STRICT"
+
+func strictFromEnv() bool {
+	strict, _ := strconv.ParseBool(os.Getenv(StrictEnvironmentKey))
+	return strict
+}
+
+// Validate checks the `validate:` tags of the fields of config, a struct or a pointer to a struct. The rules are
+// separated by commas:
+//
+//	required    the field is not the zero value
+//	min=n       numbers are at least n, strings, slices and maps have at least n elements
+//	max=n       numbers are at most n, strings, slices and maps have at most n elements
+//	oneof=a b   the field is one of the space separated values
+//	url         the field is an absolute URL
+//
+// Nested structs, pointers to structs and the structs of slices and maps are validated too.
+func Validate(config any) error {
+	return validateValue("", reflect.ValueOf(config))
+}
+
+func validateValue(path string, value reflect.Value) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	var errs error
+	switch value.Kind() {
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, inline := jsonName(field)
+			fieldPath := path
+			if !inline {
+				fieldPath = joinPath(path, name)
+			}
+
+			if tag := field.Tag.Get("validate"); tag != "" {
+				for _, rule := range strings.Split(tag, ",") {
+					if err := validateRule(value.Field(i), rule); err != nil {
+						errs = multierror.Append(errs, fmt.Errorf("%s: %w", fieldPath, err))
+					}
+				}
+			}
+
+			if err := validateValue(fieldPath, value.Field(i)); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := validateValue(fmt.Sprintf("%s[%d]", path, i), value.Index(i)); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if err := validateValue(fmt.Sprintf("%s[%v]", path, iter.Key()), iter.Value()); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateRule(value reflect.Value, rule string) error {
+	name, argument, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+	if name == "required" {
+		if value.IsZero() {
+			return fmt.Errorf("is required")
+		}
+		return nil
+	}
+
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			// only required applies to unset fields
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		bound, err := strconv.ParseFloat(argument, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s rule %q", name, rule)
+		}
+
+		size, kind := measure(value)
+		if kind == "" {
+			return fmt.Errorf("%s rule does not apply to %s", name, value.Kind())
+		}
+		if name == "min" && size < bound {
+			return fmt.Errorf("%s %v is lower than %v", kind, size, bound)
+		}
+		if name == "max" && size > bound {
+			return fmt.Errorf("%s %v is greater than %v", kind, size, bound)
+		}
+	case "oneof":
+		actual := fmt.Sprint(value.Interface())
+		for _, allowed := range strings.Fields(argument) {
+			if actual == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", actual, strings.Join(strings.Fields(argument), ", "))
+	case "url":
+		if value.Kind() != reflect.String || value.String() == "" {
+			return nil
+		}
+		parsed, err := url.Parse(value.String())
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("%q is not an absolute URL", value.String())
+		}
+	default:
+		return fmt.Errorf("unknown validation rule %q", rule)
+	}
+
+	return nil
+}
+
+// measure returns the number compared by the min and max rules for value, and what it is.
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "value"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), "value"
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "value"
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "length"
+	default:
+		return 0, ""
+	}
+}
+
+// UnmarshalStrict is Unmarshal reporting the fields of the values at key unknown to config.
+func (c *Config) UnmarshalStrict(key string, config any) error {
+	return c.unmarshal(key, config, true)
+}
+
+// Validate strictly decodes and validates the configs of all the keys of c, the keys that were not registered are
+// reported as unknown. A key registered with several types is valid if it is valid for one of them.
+func (c *Config) Validate() error {
+	c.lock.Lock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	c.lock.Unlock()
+
+	var errs error
+	for _, key := range keys {
+		types := registeredTypes(key)
+		if len(types) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("unknown config key %q", key))
+			continue
+		}
+
+		var keyErr error
+		for _, t := range types {
+			keyErr = c.UnmarshalStrict(key, reflect.New(t).Interface())
+			if keyErr == nil {
+				break
+			}
+		}
+		if keyErr != nil {
+			errs = multierror.Append(errs, keyErr)
+		}
+	}
+
+	return errs
+}
+
+// decode unmarshals the yaml data onto config, reporting the unknown fields when strict.
+func decode(data []byte, config any, strict bool) error {
+	if strict {
+		return yaml.UnmarshalStrict(data, config)
+	}
+
+	return yaml.Unmarshal(data, config)
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testValidatedConfig struct {
+	Host     string            `json:"host" yaml:"host" validate:"required,url"`
+	Mode     string            `json:"mode" yaml:"mode" default:"replay" validate:"oneof=record replay"`
+	Attempts int               `json:"attempts" yaml:"attempts" default:"5" validate:"min=1,max=10"`
+	Nodes    []testNodeConfig  `json:"nodes" yaml:"nodes"`
+	Labels   map[string]string `json:"labels" yaml:"labels" validate:"max=2"`
+}
+
+type testNodeConfig struct {
+	Name string `json:"name" yaml:"name" validate:"required"`
+}
+
+func Test_Validate(t *testing.T) {
+	valid := testValidatedConfig{Host: "https://rancher.example.com", Mode: "record", Attempts: 3, Nodes: []testNodeConfig{{Name: "node"}}}
+	if err := Validate(&valid); err != nil {
+		t.Errorf("Validate() error = %v, want no error", err)
+	}
+
+	invalid := testValidatedConfig{
+		Host:     "rancher.example.com",
+		Mode:     "live",
+		Attempts: 11,
+		Nodes:    []testNodeConfig{{}},
+		Labels:   map[string]string{"a": "", "b": "", "c": ""},
+	}
+	err := Validate(&invalid)
+	if err == nil {
+		t.Fatalf("Validate() succeeded, want errors")
+	}
+	for _, want := range []string{"host: \"rancher.example.com\" is not an absolute URL", "mode: \"live\" is not one of", "attempts: value 11 is greater than 10", "nodes[0].name: is required", "labels: length 3 is greater than 2"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want it to contain %s", err, want)
+		}
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	Register("testValidated", testValidatedConfig{})
+
+	loaded, err := LoadFiles(writeFile(t, "config.yaml", `
+testValidated:
+  host: https://rancher.example.com
+  hots: typo
+testUnknown: {}
+`))
+	if err != nil {
+		t.Fatalf("LoadFiles() error = %v", err)
+	}
+
+	var config testValidatedConfig
+	if err := loaded.Unmarshal("testValidated", &config); err != nil {
+		t.Errorf("Unmarshal() error = %v, want unknown fields to be ignored", err)
+	}
+	if config.Attempts != 5 {
+		t.Errorf("Unmarshal() attempts = %d, want the default", config.Attempts)
+	}
+
+	err = loaded.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown field "hots"`) || !strings.Contains(err.Error(), `unknown config key "testUnknown"`) {
+		t.Errorf("Validate() error = %v, want the unknown field and key", err)
+	}
+}
+
+func Test_JSONSchema(t *testing.T) {
+	Register("testValidated", testValidatedConfig{})
+
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+
+	var schema struct {
+		Properties map[string]struct {
+			Required   []string                  `json:"required"`
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("JSONSchema() is not valid JSON: %v", err)
+	}
+
+	validated := schema.Properties["testValidated"]
+	if len(validated.Required) != 1 || validated.Required[0] != "host" {
+		t.Errorf("JSONSchema() required = %v, want host", validated.Required)
+	}
+	attempts := validated.Properties["attempts"]
+	if attempts["type"] != "integer" || attempts["default"] != float64(5) || attempts["minimum"] != float64(1) || attempts["maximum"] != float64(10) {
+		t.Errorf("JSONSchema() attempts = %v, want an integer from 1 to 10 defaulting to 5", attempts)
+	}
+	if enum, _ := validated.Properties["mode"]["enum"].([]any); len(enum) != 2 {
+		t.Errorf("JSONSchema() mode enum = %v, want record and replay", validated.Properties["mode"]["enum"])
+	}
+	if validated.Properties["nodes"]["type"] != "array" {
+		t.Errorf("JSONSchema() nodes = %v, want an array", validated.Properties["nodes"])
+	}
+}
@@ -14,27 +14,37 @@ const (
 // EnvironmentFlags is a map of environment flags. The key is the flag enum and the value is true if the flag is set.
 type EnvironmentFlags map[EnvironmentFlag]bool
 
+// Config is the flags block of the configuration file. DesiredFlags lists the enabled boolean flags separated by |,
+// Values holds the values of the typed flags by name.
 type Config struct {
-	DesiredFlags string `json:"desiredflags" yaml:"desiredflags" default:""`
+	DesiredFlags string         `json:"desiredflags" yaml:"desiredflags" default:""`
+	Values       map[string]any `json:"values" yaml:"values"`
 }
 
+// enumFlags are the boolean flags of the EnvironmentFlag enum.
+var enumFlags = map[EnvironmentFlag]*Flag[bool]{}
+
 // NewEnvironmentFlags creates a new EnvironmentFlags.
 func NewEnvironmentFlags() EnvironmentFlags {
 	return make(EnvironmentFlags)
 }
 
-// LoadEnvironmentFlags loads the environment flags from the configuration file.
+// LoadEnvironmentFlags loads the environment flags from the configuration file and the environment, see Flag.
 // If the flags field does not exist, it returns an empty map.
 func LoadEnvironmentFlags(configurationFileKey string, e EnvironmentFlags) {
-	flagsConfig := new(Config)
-	config.LoadConfig(configurationFileKey, flagsConfig)
+	if configurationFileKey != ConfigurationFileKey {
+		flagsConfig := new(Config)
+		config.LoadConfig(configurationFileKey, flagsConfig)
 
-	flags := strings.Split(strings.ToLower(flagsConfig.DesiredFlags), "|")
+		flags := strings.Split(strings.ToLower(flagsConfig.DesiredFlags), "|")
+		for i := EnvironmentFlag(0); i < environmentFlagLastItem; i++ {
+			e[i] = slices.Contains(flags, strings.ToLower(i.String()))
+		}
+		return
+	}
 
 	for i := EnvironmentFlag(0); i < environmentFlagLastItem; i++ {
-		doesContainItem := slices.Contains(flags, strings.ToLower(i.String()))
-
-		e[EnvironmentFlag(i)] = doesContainItem
+		e[i] = enumFlags[i].Value()
 	}
 }
 
@@ -43,3 +53,22 @@ func LoadEnvironmentFlags(configurationFileKey string, e EnvironmentFlags) {
 func (e EnvironmentFlags) GetValue(flag EnvironmentFlag) bool {
 	return e[flag]
 }
+
+// SkipUnless skips the test unless all of flags are set.
+func (e EnvironmentFlags) SkipUnless(t Skipper, flags ...EnvironmentFlag) {
+	t.Helper()
+
+	for _, flag := range flags {
+		if !e[flag] {
+			t.Skipf("environment flag %s is not enabled, set %s or add it to the desiredflags", flag, enumFlags[flag].EnvVar())
+		}
+	}
+}
+
+func init() {
+	config.Register(ConfigurationFileKey, Config{})
+
+	for i := EnvironmentFlag(0); i < environmentFlagLastItem; i++ {
+		enumFlags[i] = Bool(i.String(), false, "")
+	}
+}
@@ -0,0 +1,302 @@
+package environmentflag
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Definition is the part of a Flag that does not depend on its type.
+type Definition interface {
+	// Name returns the name of the flag.
+	Name() string
+	// Usage returns the description of the flag.
+	Usage() string
+	// EnvVar returns the environment variable setting the flag.
+	EnvVar() string
+	// IsSet returns whether the flag is set, in the environment or the configuration file.
+	IsSet() bool
+}
+
+// Flag is a typed flag, declared with Bool, String, Int, Duration or List. A flag is set, in order of precedence, by
+// its environment variable, e.g. CATTLE_TEST_FLAGS_UPGRADE_TIMEOUT for upgradeTimeout, by its value in the values of
+// the flags block of the configuration file, or for boolean flags by its name in desiredflags:
+//
+//	flags:
+//	  desiredflags: Long|InstallRancher
+//	  values:
+//	    upgradeTimeout: 30m
+//	    upgradeClusters: [downstream-1, downstream-2]
+type Flag[T any] struct {
+	name  string
+	def   T
+	usage string
+	parse func(value any) (T, error)
+}
+
+// registry holds the declared flags by lower cased name.
+var registry = struct {
+	lock  sync.RWMutex
+	flags map[string]Definition
+}{
+	flags: map[string]Definition{},
+}
+
+func declare[T any](name string, def T, usage string, parse func(value any) (T, error)) *Flag[T] {
+	flag := &Flag[T]{
+		name:  name,
+		def:   def,
+		usage: usage,
+		parse: parse,
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	if _, ok := registry.flags[strings.ToLower(name)]; ok {
+		panic(fmt.Sprintf("environment flag %s is declared twice", name))
+	}
+	registry.flags[strings.ToLower(name)] = flag
+
+	return flag
+}
+
+// Bool declares a boolean flag. It panics if a flag with the same name, regardless of the case, is already declared.
+func Bool(name string, def bool, usage string) *Flag[bool] {
+	return declare(name, def, usage, func(value any) (bool, error) {
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return strconv.ParseBool(fmt.Sprint(value))
+	})
+}
+
+// String declares a string flag. It panics if a flag with the same name, regardless of the case, is already declared.
+func String(name, def, usage string) *Flag[string] {
+	return declare(name, def, usage, func(value any) (string, error) {
+		return fmt.Sprint(value), nil
+	})
+}
+
+// Int declares an integer flag. It panics if a flag with the same name, regardless of the case, is already declared.
+func Int(name string, def int, usage string) *Flag[int] {
+	return declare(name, def, usage, func(value any) (int, error) {
+		if f, ok := value.(float64); ok && f == float64(int(f)) {
+			return int(f), nil
+		}
+		return strconv.Atoi(fmt.Sprint(value))
+	})
+}
+
+// Duration declares a duration flag, set with time.ParseDuration formats such as 30m. It panics if a flag with the
+// same name, regardless of the case, is already declared.
+func Duration(name string, def time.Duration, usage string) *Flag[time.Duration] {
+	return declare(name, def, usage, func(value any) (time.Duration, error) {
+		return time.ParseDuration(fmt.Sprint(value))
+	})
+}
+
+// List declares a list flag, set with a list in the configuration file or comma separated values in the
+// environment. It panics if a flag with the same name, regardless of the case, is already declared.
+func List(name string, def []string, usage string) *Flag[[]string] {
+	return declare(name, def, usage, func(value any) ([]string, error) {
+		var list []string
+		if values, ok := value.([]any); ok {
+			for _, v := range values {
+				list = append(list, fmt.Sprint(v))
+			}
+			return list, nil
+		}
+
+		for _, v := range strings.Split(fmt.Sprint(value), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+		return list, nil
+	})
+}
+
+// Name returns the name of the flag.
+func (f *Flag[T]) Name() string {
+	return f.name
+}
+
+// Usage returns the description of the flag.
+func (f *Flag[T]) Usage() string {
+	return f.usage
+}
+
+// EnvVar returns the environment variable setting the flag.
+func (f *Flag[T]) EnvVar() string {
+	return config.EnvOverrideName(ConfigurationFileKey, f.name)
+}
+
+// Default returns the default value of the flag.
+func (f *Flag[T]) Default() T {
+	return f.def
+}
+
+// IsSet returns whether the flag is set, in the environment or the configuration file.
+func (f *Flag[T]) IsSet() bool {
+	_, ok := f.raw()
+	return ok
+}
+
+// Lookup returns the value of the flag, or its default when it is not set. It fails when the value cannot be parsed
+// as the type of the flag.
+func (f *Flag[T]) Lookup() (T, error) {
+	raw, ok := f.raw()
+	if !ok {
+		return f.def, nil
+	}
+
+	value, err := f.parse(raw)
+	if err != nil {
+		return f.def, fmt.Errorf("invalid value %q of environment flag %s: %w", raw, f.name, err)
+	}
+
+	return value, nil
+}
+
+// Value returns the value of the flag, or its default when it is not set or its value is invalid.
+func (f *Flag[T]) Value() T {
+	value, err := f.Lookup()
+	if err != nil {
+		logrus.Warnf("%v, using the default %v", err, f.def)
+	}
+
+	return value
+}
+
+// raw returns the value of the flag before parsing and whether it is set.
+func (f *Flag[T]) raw() (any, bool) {
+	if value, ok := os.LookupEnv(f.EnvVar()); ok {
+		return value, true
+	}
+
+	flagsConfig := loadConfig()
+	for name, value := range flagsConfig.Values {
+		if strings.EqualFold(name, f.name) {
+			return value, true
+		}
+	}
+
+	var def any = f.def
+	if _, ok := def.(bool); ok {
+		for _, desired := range strings.Split(flagsConfig.DesiredFlags, "|") {
+			if strings.EqualFold(strings.TrimSpace(desired), f.name) {
+				return true, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// cachedConfig is the flags block loaded by loadConfig, and the CATTLE_TEST_CONFIG files and config.Writes it was
+// loaded with.
+var cachedConfig struct {
+	lock   sync.Mutex
+	files  string
+	writes uint64
+	config *Config
+}
+
+// loadConfig returns the flags block of the configuration file. It is reloaded when CATTLE_TEST_CONFIG changes or when
+// the configuration files are written by config.UpdateConfig or config.WriteConfig, so that the flags follow them.
+func loadConfig() *Config {
+	files := os.Getenv(config.ConfigEnvironmentKey)
+	writes := config.Writes()
+
+	cachedConfig.lock.Lock()
+	defer cachedConfig.lock.Unlock()
+
+	if cachedConfig.config != nil && cachedConfig.files == files && cachedConfig.writes == writes {
+		return cachedConfig.config
+	}
+
+	flagsConfig := new(Config)
+	err := config.Load(ConfigurationFileKey, flagsConfig)
+	if err != nil && !errors.Is(err, config.ErrConfigNotSet) {
+		logrus.Warnf("failed to load the environment flags: %v", err)
+	}
+
+	cachedConfig.files = files
+	cachedConfig.writes = writes
+	cachedConfig.config = flagsConfig
+
+	return flagsConfig
+}
+
+// Lookup returns the declared flag named name, regardless of the case.
+func Lookup(name string) (Definition, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	flag, ok := registry.flags[strings.ToLower(name)]
+	return flag, ok
+}
+
+// All returns the declared flags, sorted by name.
+func All() []Definition {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	flags := make([]Definition, 0, len(registry.flags))
+	for _, flag := range registry.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return strings.ToLower(flags[i].Name()) < strings.ToLower(flags[j].Name())
+	})
+
+	return flags
+}
+
+// Skipper is implemented by *testing.T, *testing.B and the T() of testify suites.
+type Skipper interface {
+	Helper()
+	Skipf(format string, args ...any)
+}
+
+// SkipUnlessEnabled skips the test unless all of flags are true.
+func SkipUnlessEnabled(t Skipper, flags ...*Flag[bool]) {
+	t.Helper()
+
+	for _, flag := range flags {
+		if !flag.Value() {
+			t.Skipf("environment flag %s is not enabled, set %s or add it to the desiredflags", flag.Name(), flag.EnvVar())
+		}
+	}
+}
+
+// SkipIfEnabled skips the test if any of flags is true.
+func SkipIfEnabled(t Skipper, flags ...*Flag[bool]) {
+	t.Helper()
+
+	for _, flag := range flags {
+		if flag.Value() {
+			t.Skipf("environment flag %s is enabled", flag.Name())
+		}
+	}
+}
+
+// SkipUnlessSet skips the test unless all of flags are set.
+func SkipUnlessSet(t Skipper, flags ...Definition) {
+	t.Helper()
+
+	for _, flag := range flags {
+		if !flag.IsSet() {
+			t.Skipf("environment flag %s is not set, set %s or its value in the flags configuration", flag.Name(), flag.EnvVar())
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package environmentflag
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/pkg/config"
+)
+
+type fakeSkipper struct {
+	skipped string
+}
+
+func (f *fakeSkipper) Helper() {}
+
+func (f *fakeSkipper) Skipf(format string, args ...any) {
+	if f.skipped == "" {
+		f.skipped = fmt.Sprintf(format, args...)
+	}
+}
+
+func setConfig(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Setenv(config.ConfigEnvironmentKey, path)
+}
+
+func Test_Flags(t *testing.T) {
+	setConfig(t, `
+flags:
+  desiredflags: Long|testEnabled
+  values:
+    testTimeout: 30m
+    testClusters: [downstream-1, downstream-2]
+    testRetries: 3
+    testInvalid: often
+`)
+	t.Setenv("CATTLE_TEST_FLAGS_TEST_RETRIES", "5")
+
+	enabled := Bool("testEnabled", false, "")
+	disabled := Bool("testDisabled", false, "")
+	timeout := Duration("testTimeout", time.Minute, "")
+	clusters := List("testClusters", nil, "")
+	retries := Int("testRetries", 1, "")
+	invalid := Int("testInvalid", 2, "")
+	name := String("testName", "default", "")
+
+	if !enabled.Value() || disabled.Value() {
+		t.Errorf("enabled = %v, disabled = %v, want true, false", enabled.Value(), disabled.Value())
+	}
+	if timeout.Value() != 30*time.Minute {
+		t.Errorf("timeout = %v, want 30m", timeout.Value())
+	}
+	if want := []string{"downstream-1", "downstream-2"}; !reflect.DeepEqual(clusters.Value(), want) {
+		t.Errorf("clusters = %v, want %v", clusters.Value(), want)
+	}
+	if retries.Value() != 5 {
+		t.Errorf("retries = %d, want 5 from %s", retries.Value(), retries.EnvVar())
+	}
+	if _, err := invalid.Lookup(); err == nil || invalid.Value() != 2 {
+		t.Errorf("invalid = %d, want the default and an error", invalid.Value())
+	}
+	if name.IsSet() || name.Value() != "default" {
+		t.Errorf("name = %q, want the default", name.Value())
+	}
+
+	t.Setenv("CATTLE_TEST_FLAGS_TEST_CLUSTERS", "a, b")
+	if want := []string{"a", "b"}; !reflect.DeepEqual(clusters.Value(), want) {
+		t.Errorf("clusters = %v, want %v", clusters.Value(), want)
+	}
+
+	if flag, ok := Lookup("TESTENABLED"); !ok || flag != Definition(enabled) {
+		t.Errorf("Lookup() = %v, %v, want the testEnabled flag", flag, ok)
+	}
+
+	e := NewEnvironmentFlags()
+	LoadEnvironmentFlags(ConfigurationFileKey, e)
+	if !e.GetValue(Long) || e.GetValue(Short) {
+		t.Errorf("Long = %v, Short = %v, want true, false", e.GetValue(Long), e.GetValue(Short))
+	}
+
+	skipper := &fakeSkipper{}
+	SkipUnlessEnabled(skipper, enabled)
+	SkipIfEnabled(skipper, disabled)
+	SkipUnlessSet(skipper, timeout, retries)
+	e.SkipUnless(skipper, Long)
+	if skipper.skipped != "" {
+		t.Errorf("skipped with %q, want no skip", skipper.skipped)
+	}
+
+	SkipUnlessSet(skipper, name)
+	if skipper.skipped == "" {
+		t.Error("not skipped, want a skip for the unset testName")
+	}
+}
+
+func Test_FlagsFollowConfigUpdates(t *testing.T) {
+	setConfig(t, `
+flags:
+  desiredflags: testUpdated
+`)
+	updated := Bool("testUpdated", false, "")
+	if !updated.Value() {
+		t.Fatalf("Value() = false, want the flag enabled by the config")
+	}
+
+	config.UpdateConfig(ConfigurationFileKey, &Config{})
+	if updated.Value() {
+		t.Errorf("Value() = true after UpdateConfig(), want the flag disabled")
+	}
+
+	setConfig(t, `
+flags:
+  desiredflags: testUpdated
+`)
+	if !updated.Value() {
+		t.Errorf("Value() = false after changing %s, want the flag enabled", config.ConfigEnvironmentKey)
+	}
+}
+
+func Test_ConfigLoadedOnce(t *testing.T) {
+	setConfig(t, `
+flags:
+  desiredflags: testCached
+`)
+	cached := Bool("testCached", false, "")
+	if !cached.Value() {
+		t.Fatalf("Value() = false, want the flag enabled by the config")
+	}
+
+	err := os.WriteFile(os.Getenv(config.ConfigEnvironmentKey), []byte("flags: {}\n"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if !cached.Value() {
+		t.Errorf("Value() = false after rewriting the file outside of the config package, want the cached config")
+	}
+}
+
+func Test_DeclareTwice(t *testing.T) {
+	String("testTwice", "", "")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("declaring testTwice twice did not panic")
+		}
+	}()
+	Bool("TESTTWICE", false, "")
+}
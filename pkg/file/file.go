@@ -1,3 +1,7 @@
+// Package file writes files in the working directory.
+//
+// Deprecated: use a workspace.Workspace, a private directory of the session whose secrets are only readable by the
+// owner.
 package file
 
 import (
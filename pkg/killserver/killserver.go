@@ -2,28 +2,127 @@ package killserver
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/rancher/shepherd/pkg/session"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	Port = ":19999"
+
+	// AddressEnvironmentKey is the environment variable overriding the address the KillServer listens on.
+	AddressEnvironmentKey = "CATTLE_TEST_KILLSERVER_ADDRESS"
+	// TokenEnvironmentKey is the environment variable setting the token of the KillServer, a random token is
+	// generated and only logged at the debug level when it is unset.
+	TokenEnvironmentKey = "CATTLE_TEST_KILLSERVER_TOKEN"
+
+	// DefaultDrainTimeout is how long /drain waits for the running tests before cleaning up, unless DrainTimeout is set.
+	DefaultDrainTimeout = 30 * time.Minute
+)
+
+// State is the state of the test process controlled by a KillServer.
+type State string
+
+const (
+	// Running tests are started normally.
+	Running State = "running"
+	// Draining stops starting new tests, waits for the running one and cleans up the session.
+	Draining State = "draining"
+	// Drained is the state once the session is cleaned up, the cleanup report is available.
+	Drained State = "drained"
+	// Aborted is the state once the context was cancelled, without cleaning up.
+	Aborted State = "aborted"
 )
 
-// KillServer is struct used to cancel a context of web service, that listens on a specific port.
+// abortedError is the error of the drains of an aborted KillServer, which do not clean up.
+const abortedError = "the session was aborted"
+
+// Status is the body of the /status endpoint.
+type Status struct {
+	State State `json:"state"`
+	// Tests are the names of the running tests, in the order they started.
+	Tests           []string `json:"tests,omitempty"`
+	PendingCleanups int      `json:"pendingCleanups"`
+}
+
+// Report is the body of the /drain and /report endpoints.
+type Report struct {
+	*session.CleanupReport
+	Error string `json:"error,omitempty"`
+}
+
+// KillServer is struct used to cancel a context of web service, that listens on a specific port. Every request needs
+// the token, as a bearer token. The endpoints are:
+//
+//	GET  /status  the state, the running tests and the number of pending cleanups of the session
+//	POST /drain   stops starting new tests, waits for the running ones, cleans up the session and returns the report
+//	POST /abort   cancels the context right away, without cleaning up
+//	GET  /report  the cleanup report of the last drain
 type KillServer struct {
 	Server http.Server
+	// Token authenticates the requests.
+	Token string
+	// Session is cleaned up by /drain, and its pending cleanups are reported by /status.
+	Session *session.Session
+	// Exit, when set, is called once a drain or an abort is complete and responded to, e.g. os.Exit. The code is 0
+	// for a drain that cleaned up the session, 1 otherwise.
+	Exit func(code int)
+	// DrainTimeout is how long /drain waits for the running tests before cleaning up, DefaultDrainTimeout when zero.
+	DrainTimeout time.Duration
+
 	cancel context.CancelFunc
+	// ctx is the context of the drains requested by /drain, so that they outlive the requests, done once aborted.
+	ctx   context.Context
+	abort context.CancelFunc
+
+	lock  sync.Mutex
+	state State
+	// tests are the names of the running tests, idle is closed once none is running.
+	tests  []string
+	idle   chan struct{}
+	report *Report
 }
 
 // NewKillServer initializes a KillServer at a specific address/port and the cancel context of said web service.
+// An empty addr defaults to AddressEnvironmentKey, then Port.
 func NewKillServer(addr string, cancel context.CancelFunc) *KillServer {
+	if addr == "" {
+		addr = os.Getenv(AddressEnvironmentKey)
+	}
+	if addr == "" {
+		addr = Port
+	}
+
+	token := os.Getenv(TokenEnvironmentKey)
+	if token == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		token = hex.EncodeToString(b)
+		logrus.Warnf("KillServer: %s is not set, the requests need the generated token logged at the debug level", TokenEnvironmentKey)
+		logrus.Debugf("KillServer token: %s", token)
+	}
+
+	ctx, abort := context.WithCancel(context.Background())
+
 	return &KillServer{
 		Server: http.Server{
 			Addr: addr,
 		},
+		Token:  token,
 		cancel: cancel,
+		ctx:    ctx,
+		abort:  abort,
+		state:  Running,
 	}
 }
 
@@ -32,16 +131,248 @@ func (s *KillServer) Start() {
 	s.Server.Handler = s
 
 	err := s.Server.ListenAndServe()
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		logrus.Errorf("KillServer error: %v", err)
 	}
 }
 
+// StartTest records that the test named name is running, tests can run in parallel. It returns false while draining,
+// the test must then not start.
+func (s *KillServer) StartTest(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state != Running {
+		return false
+	}
+
+	if len(s.tests) == 0 {
+		s.idle = make(chan struct{})
+	}
+	s.tests = append(s.tests, name)
+	return true
+}
+
+// EndTest records that the test named name, started by StartTest, is done.
+func (s *KillServer) EndTest(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := slices.Index(s.tests, name)
+	if i < 0 {
+		return
+	}
+
+	s.tests = slices.Delete(s.tests, i, i+1)
+	if len(s.tests) == 0 {
+		close(s.idle)
+		s.idle = nil
+	}
+}
+
+// TestingT is implemented by *testing.T and the T() of testify suites.
+type TestingT interface {
+	Name() string
+	Cleanup(func())
+	Skip(args ...any)
+}
+
+// Track records t as the running test until it is done, and skips it while draining.
+func (s *KillServer) Track(t TestingT) {
+	if !s.StartTest(t.Name()) {
+		t.Skip("the KillServer is draining")
+		return
+	}
+
+	name := t.Name()
+	t.Cleanup(func() { s.EndTest(name) })
+}
+
+// Status returns the state of the KillServer.
+func (s *KillServer) Status() Status {
+	s.lock.Lock()
+	status := Status{
+		State: s.state,
+		Tests: slices.Clone(s.tests),
+	}
+	s.lock.Unlock()
+
+	if s.Session != nil {
+		status.PendingCleanups = s.Session.PendingCleanups()
+	}
+
+	return status
+}
+
 // ServeHTTP should write reply headers and data to the ResponseWriter
 // and then return.
 func (s *KillServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/status":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Status())
+	case "/report":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		s.lock.Lock()
+		report := s.report
+		s.lock.Unlock()
+		if report == nil {
+			http.Error(w, "the session has not been drained", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	case "/drain":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		report := s.drain()
+		writeJSON(w, http.StatusOK, report)
+		if report.Error != "" {
+			s.exit(1)
+			return
+		}
+		s.exit(0)
+	case "/abort":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		s.Abort()
+		writeJSON(w, http.StatusOK, s.Status())
+		s.exit(1)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// drain drains the KillServer for /drain, see Drain, waiting for the running tests for DrainTimeout at most, or until
+// it is aborted, rather than for as long as the request.
+func (s *KillServer) drain() *Report {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timeout := s.DrainTimeout
+	if timeout == 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return s.Drain(ctx)
+}
+
+// Drain stops starting new tests, waits for the running tests until ctx is done, cleans up the session and cancels
+// the context. It returns the cleanup report, the one of the first drain when called again, or an error report
+// without cleaning up once aborted.
+func (s *KillServer) Drain(ctx context.Context) *Report {
+	s.lock.Lock()
+	if s.state == Aborted {
+		s.lock.Unlock()
+		return &Report{Error: abortedError}
+	}
+	if s.report != nil || s.state == Draining {
+		report := s.report
+		s.lock.Unlock()
+		if report == nil {
+			return &Report{Error: "the session is already draining"}
+		}
+		return report
+	}
+	s.state = Draining
+	idle := s.idle
+	s.lock.Unlock()
+
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			logrus.Warnf("KillServer: cleaning up while tests are still running: %v", ctx.Err())
+		}
+	}
+
+	s.lock.Lock()
+	aborted := s.state == Aborted
+	s.lock.Unlock()
+	if aborted {
+		return &Report{Error: abortedError}
+	}
+
+	report := &Report{CleanupReport: &session.CleanupReport{}}
+	if s.Session != nil {
+		start := time.Now()
+		cleanupReport, err := s.Session.CleanupWithReport()
+		report.CleanupReport = cleanupReport
+		if err != nil {
+			report.Error = err.Error()
+		}
+		logrus.Infof("KillServer: cleaned up the session in %v", time.Since(start))
+	}
+
+	s.lock.Lock()
+	s.state = Drained
+	s.report = report
+	s.lock.Unlock()
+
+	s.cancel()
+
+	return report
+}
+
+// Abort cancels the context right away, without cleaning up the session.
+func (s *KillServer) Abort() {
+	s.lock.Lock()
+	s.state = Aborted
+	s.lock.Unlock()
+
+	// stop waiting for the running tests of a drain
+	if s.abort != nil {
+		s.abort()
+	}
 
 	// cancel the context
 	s.cancel()
 }
+
+// exit calls Exit with code once the response is sent.
+func (s *KillServer) exit(code int) {
+	if s.Exit == nil {
+		return
+	}
+
+	go func() {
+		// let the server flush the response
+		time.Sleep(100 * time.Millisecond)
+		s.Exit(code)
+	}()
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		logrus.Errorf("KillServer: failed to write the response: %v", err)
+	}
+}
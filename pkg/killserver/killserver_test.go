@@ -0,0 +1,214 @@
+package killserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/pkg/session"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func request(t *testing.T, s *KillServer, method, path, token string, body any) int {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+
+	if body != nil && recorder.Code == http.StatusOK {
+		err := json.Unmarshal(recorder.Body.Bytes(), body)
+		if err != nil {
+			t.Fatalf("%s %s: invalid body %q: %v", method, path, recorder.Body.String(), err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func Test_KillServerDrain(t *testing.T) {
+	t.Setenv(TokenEnvironmentKey, "secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewKillServer("", cancel)
+	s.Session = session.NewSession()
+
+	cleaned := false
+	s.Session.RegisterCleanupFunc(func() error {
+		cleaned = true
+		return nil
+	})
+
+	if code := request(t, s, http.MethodGet, "/status", "wrong", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /status with a wrong token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := request(t, s, http.MethodGet, "/drain", "secret", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /drain = %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+	if code := request(t, s, http.MethodGet, "/report", "secret", nil); code != http.StatusNotFound {
+		t.Errorf("GET /report before a drain = %d, want %d", code, http.StatusNotFound)
+	}
+
+	exited := make(chan int, 1)
+	s.Exit = func(code int) { exited <- code }
+
+	if !s.StartTest("TestUpgrade") || !s.StartTest("TestParallel") {
+		t.Fatal("StartTest() = false, want true before draining")
+	}
+
+	var status Status
+	request(t, s, http.MethodGet, "/status", "secret", &status)
+	if status.State != Running || !reflect.DeepEqual(status.Tests, []string{"TestUpgrade", "TestParallel"}) || status.PendingCleanups != 1 {
+		t.Errorf("GET /status = %+v, want running TestUpgrade and TestParallel with 1 pending cleanup", status)
+	}
+
+	done := make(chan Report)
+	go func() {
+		var report Report
+		request(t, s, http.MethodPost, "/drain", "secret", &report)
+		done <- report
+	}()
+
+	// the drain waits for every running test
+	for s.Status().State != Draining {
+		time.Sleep(time.Millisecond)
+	}
+	if s.StartTest("TestNext") {
+		t.Error("StartTest() = true while draining, want false")
+	}
+	s.EndTest("TestUpgrade")
+	time.Sleep(10 * time.Millisecond)
+	if cleaned {
+		t.Error("the session was cleaned up while a test is running")
+	}
+	s.EndTest("TestParallel")
+
+	report := <-done
+	if !cleaned || report.CleanupReport == nil || len(report.Cleaned) != 1 {
+		t.Errorf("POST /drain = %+v, want 1 cleaned entry", report)
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Error("the context is not cancelled after draining")
+	}
+	if code := <-exited; code != 0 {
+		t.Errorf("Exit(%d), want Exit(0)", code)
+	}
+
+	var again Report
+	if code := request(t, s, http.MethodGet, "/report", "secret", &again); code != http.StatusOK || len(again.Cleaned) != 1 {
+		t.Errorf("GET /report = %d %+v, want the drain report", code, again)
+	}
+}
+
+func Test_KillServerDrainFailed(t *testing.T) {
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewKillServer("", cancel)
+	s.Session = session.NewSession()
+	s.Session.CleanupBackoff = &wait.Backoff{Steps: 1}
+	s.Session.RegisterCleanupFunc(func() error {
+		return errors.New("not found")
+	})
+	exited := make(chan int, 1)
+	s.Exit = func(code int) { exited <- code }
+
+	var report Report
+	request(t, s, http.MethodPost, "/drain", s.Token, &report)
+	if report.Error == "" || len(report.Failed) != 1 {
+		t.Errorf("POST /drain = %+v, want the failed cleanup", report)
+	}
+	if code := <-exited; code != 1 {
+		t.Errorf("Exit(%d), want Exit(1)", code)
+	}
+}
+
+func Test_KillServerAbort(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewKillServer("", cancel)
+	exited := make(chan int, 1)
+	s.Exit = func(code int) { exited <- code }
+
+	var status Status
+	request(t, s, http.MethodPost, "/abort", s.Token, &status)
+	if status.State != Aborted || ctx.Err() == nil {
+		t.Errorf("POST /abort = %+v, want aborted and the context cancelled", status)
+	}
+	if code := <-exited; code != 1 {
+		t.Errorf("Exit(%d), want Exit(1)", code)
+	}
+}
+
+func Test_KillServerDrainOutlivesRequest(t *testing.T) {
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewKillServer("", cancel)
+	s.Session = session.NewSession()
+	cleaned := make(chan struct{})
+	s.Session.RegisterCleanupFunc(func() error {
+		close(cleaned)
+		return nil
+	})
+
+	if !s.StartTest("TestUpgrade") {
+		t.Fatal("StartTest() = false, want true before draining")
+	}
+
+	// the client gives up on the drain right away
+	ctx, cancelRequest := context.WithCancel(context.Background())
+	cancelRequest()
+	req := httptest.NewRequest(http.MethodPost, "/drain", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	go s.ServeHTTP(httptest.NewRecorder(), req)
+
+	for s.Status().State != Draining {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-cleaned:
+		t.Fatal("the session was cleaned up while a test is running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.EndTest("TestUpgrade")
+	select {
+	case <-cleaned:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the session was not cleaned up once the test ended")
+	}
+}
+
+func Test_KillServerDrainAborted(t *testing.T) {
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewKillServer("", cancel)
+	s.Session = session.NewSession()
+	cleaned := false
+	s.Session.RegisterCleanupFunc(func() error {
+		cleaned = true
+		return nil
+	})
+
+	s.Abort()
+
+	report := s.Drain(context.Background())
+	if report.Error == "" || cleaned {
+		t.Errorf("Drain() = %+v, want an error without cleaning up the aborted session", report)
+	}
+	if state := s.Status().State; state != Aborted {
+		t.Errorf("state = %s, want %s", state, Aborted)
+	}
+}
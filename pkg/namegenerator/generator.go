@@ -0,0 +1,172 @@
+package namegenerator
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rancher/shepherd/pkg/clientbase"
+	"github.com/rancher/shepherd/pkg/session"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	namePrefix           = "auto-"
+	maxCollisionAttempts = 10
+	// maxRunIDLength leaves room in the machine pool names for a base after the run ID and the random suffix.
+	maxRunIDLength = 10
+)
+
+// Kind is the kind of resource a name is generated for, with the limits of its API.
+type Kind struct {
+	Name      string
+	MaxLength int
+	Pattern   *regexp.Regexp
+}
+
+var (
+	dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	gcpPattern      = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+	// nonDNSLabelChars replaces the characters valid in a label value but not in a DNS label.
+	nonDNSLabelChars = strings.NewReplacer(".", "-", "_", "-")
+)
+
+var (
+	// Cluster names are used as DNS labels, e.g. in the names of their machines and namespaces.
+	Cluster = Kind{Name: "cluster", MaxLength: 63, Pattern: dnsLabelPattern}
+	// MachinePool names are short so that the machine names, <cluster>-<pool>-<hash>-<suffix>, fit a hostname.
+	MachinePool = Kind{Name: "machine pool", MaxLength: 30, Pattern: dnsLabelPattern}
+	// Hostname is a DNS-1123 label.
+	Hostname = Kind{Name: "hostname", MaxLength: 63, Pattern: dnsLabelPattern}
+	// AzureResource names fit Linux virtual machines, the shortest Azure limit of the resources created for nodes.
+	AzureResource = Kind{Name: "Azure resource", MaxLength: 64, Pattern: dnsLabelPattern}
+	// GCPResource names start with a letter, as required by Compute Engine.
+	GCPResource = Kind{Name: "GCP resource", MaxLength: 63, Pattern: gcpPattern}
+)
+
+// ExistsFunc returns whether a resource named name already exists.
+type ExistsFunc func(name string) (bool, error)
+
+// ExistsByID returns an ExistsFunc getting the resource with byID, e.g. a SteveClient.ByID or a management client
+// ByID, that exists unless byID fails with not found. A non empty namespace prefixes the IDs.
+func ExistsByID[T any](byID func(id string) (T, error), namespace string) ExistsFunc {
+	return func(name string) (bool, error) {
+		id := name
+		if namespace != "" {
+			id = namespace + "/" + name
+		}
+
+		_, err := byID(id)
+		if clientbase.IsNotFound(err) || apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// Generator generates names from a seed, so that a run can be replayed with the same names, tagged with a run ID.
+// It is safe for concurrent use.
+type Generator struct {
+	lock  sync.Mutex
+	rand  *rand.Rand
+	seed  int64
+	runID string
+}
+
+// New returns a Generator seeded with seed that tags the names with runID, the run ID of the sessions when it is
+// empty. The run ID is sanitized and truncated to 10 characters to fit the names. It does not depend on the seed so
+// that a replayed run does not collide with the resources left by the run it replays.
+func New(seed int64, runID string) *Generator {
+	if runID == "" {
+		runID = session.RunID()
+	}
+
+	return &Generator{
+		rand:  rand.New(rand.NewSource(seed)),
+		seed:  seed,
+		runID: sanitize(runID, maxRunIDLength),
+	}
+}
+
+// Seed returns the seed of the generator.
+func (g *Generator) Seed() int64 {
+	return g.seed
+}
+
+// RunID returns the run ID the names are tagged with.
+func (g *Generator) RunID() string {
+	return g.runID
+}
+
+// RandStringLower returns a random string of n lower case letters.
+func (g *Generator) RandStringLower(n int) string {
+	return g.RandStringWithCharset(n, lowerLetterBytes)
+}
+
+// RandStringWithCharset returns a random string of length characters of charset.
+func (g *Generator) RandStringWithCharset(length int, charset string) string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = charset[g.rand.Intn(len(charset))]
+	}
+	return string(b)
+}
+
+// Name returns a name of kind, auto-<base>-<run ID>-<random suffix>. The base is lower cased, its invalid
+// characters are replaced by hyphens and it is truncated to fit the maximum length of kind.
+func (g *Generator) Name(kind Kind, base string) (string, error) {
+	suffix := "-" + g.runID + "-" + g.RandStringLower(defaultRandStringLength)
+
+	room := kind.MaxLength - len(namePrefix) - len(suffix)
+	if room < 0 {
+		return "", fmt.Errorf("%s names are limited to %d characters, too short for %s<base>%s", kind.Name, kind.MaxLength, namePrefix, suffix)
+	}
+
+	base = sanitize(base, room)
+
+	name := strings.TrimSuffix(namePrefix, "-") + suffix
+	if base != "" {
+		name = namePrefix + base + suffix
+	}
+
+	if len(name) > kind.MaxLength || !kind.Pattern.MatchString(name) {
+		return "", fmt.Errorf("%q is not a valid %s name", name, kind.Name)
+	}
+
+	return name, nil
+}
+
+// UniqueName returns a name of kind, see Name, for which exists returns false. It gives up after 10 names that
+// exist.
+func (g *Generator) UniqueName(kind Kind, base string, exists ExistsFunc) (string, error) {
+	for i := 0; i < maxCollisionAttempts; i++ {
+		name, err := g.Name(kind, base)
+		if err != nil {
+			return "", err
+		}
+
+		found, err := exists(name)
+		if err != nil {
+			return "", fmt.Errorf("checking whether %s %s exists: %w", kind.Name, name, err)
+		}
+		if !found {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no unique %s name after %d attempts", kind.Name, maxCollisionAttempts)
+}
+
+// sanitize returns value as a DNS label of at most length characters, a label value without dots and underscores, see
+// session.SanitizeLabelValue.
+func sanitize(value string, length int) string {
+	return session.SanitizeLabelValue(nonDNSLabelChars.Replace(value), length)
+}
@@ -0,0 +1,132 @@
+package namegenerator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_NameReplay(t *testing.T) {
+	first := New(42, "run1")
+	second := New(42, "run1")
+
+	for i := 0; i < 3; i++ {
+		a, err := first.Name(Cluster, "downstream")
+		if err != nil {
+			t.Fatalf("Name() error = %v", err)
+		}
+		b, _ := second.Name(Cluster, "downstream")
+		if a != b {
+			t.Errorf("Name() = %q and %q with the same seed, want the same names", a, b)
+		}
+		if !strings.HasPrefix(a, "auto-downstream-run1-") {
+			t.Errorf("Name() = %q, want auto-downstream-run1-<suffix>", a)
+		}
+	}
+}
+
+func Test_NameLimits(t *testing.T) {
+	g := New(1, "abc123")
+	long := strings.Repeat("Very_Long.Cluster Name ", 10)
+
+	for _, kind := range []Kind{Cluster, MachinePool, Hostname, AzureResource, GCPResource} {
+		name, err := g.Name(kind, long)
+		if err != nil {
+			t.Fatalf("Name(%s) error = %v", kind.Name, err)
+		}
+		if len(name) > kind.MaxLength || !kind.Pattern.MatchString(name) {
+			t.Errorf("Name(%s) = %q, want a valid name of at most %d characters", kind.Name, name, kind.MaxLength)
+		}
+	}
+
+	name, err := g.Name(GCPResource, "--")
+	if err != nil || !strings.HasPrefix(name, "auto-abc123-") {
+		t.Errorf("Name() = %q, %v, want auto-abc123-<suffix>", name, err)
+	}
+
+	_, err = g.Name(Kind{Name: "tiny", MaxLength: 10, Pattern: dnsLabelPattern}, "base")
+	if err == nil {
+		t.Error("Name() succeeded for a kind too short for the run ID")
+	}
+}
+
+func Test_NameLongRunID(t *testing.T) {
+	g := New(1, "Jenkins_Nightly-Build.1234567890")
+	if g.RunID() != "jenkins-ni" {
+		t.Errorf("RunID() = %q, want the sanitized run ID truncated to 10 characters", g.RunID())
+	}
+
+	for _, kind := range []Kind{Cluster, MachinePool, Hostname, AzureResource, GCPResource} {
+		name, err := g.Name(kind, "pool")
+		if err != nil {
+			t.Fatalf("Name(%s) error = %v", kind.Name, err)
+		}
+		if len(name) > kind.MaxLength || !kind.Pattern.MatchString(name) {
+			t.Errorf("Name(%s) = %q, want a valid name of at most %d characters", kind.Name, name, kind.MaxLength)
+		}
+	}
+}
+
+func Test_DefaultSeedInvalid(t *testing.T) {
+	t.Setenv(SeedEnvironmentKey, "not-a-number")
+
+	if seed := defaultSeed(); seed == 0 {
+		t.Errorf("defaultSeed() = 0, want the current time")
+	}
+}
+
+func Test_UniqueName(t *testing.T) {
+	g := New(7, "run")
+	first, _ := New(7, "run").Name(Cluster, "c")
+	taken := map[string]bool{first: true}
+
+	name, err := g.UniqueName(Cluster, "c", func(name string) (bool, error) {
+		return taken[name], nil
+	})
+	if err != nil || name == first {
+		t.Errorf("UniqueName() = %q, %v, want a name other than %q", name, err, first)
+	}
+
+	_, err = g.UniqueName(Cluster, "c", func(string) (bool, error) {
+		return true, nil
+	})
+	if err == nil {
+		t.Error("UniqueName() succeeded while every name exists")
+	}
+
+	boom := errors.New("boom")
+	_, err = g.UniqueName(Cluster, "c", func(string) (bool, error) {
+		return false, boom
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("UniqueName() error = %v, want %v", err, boom)
+	}
+}
+
+func Test_AppendRandomString(t *testing.T) {
+	name, err := AppendRandomString("downstream")
+	if err != nil {
+		t.Fatalf("AppendRandomString() error = %v", err)
+	}
+	if !strings.Contains(name, "downstream") || !Cluster.Pattern.MatchString(name) {
+		t.Errorf("AppendRandomString() = %q, want a valid cluster name of downstream", name)
+	}
+}
+
+func Test_RandStringIgnoresSeed(t *testing.T) {
+	generator := defaultGenerator
+	defer func() {
+		defaultGenerator = generator
+	}()
+
+	defaultGenerator = New(42, "")
+	first := RandStringAll(32)
+	defaultGenerator = New(42, "")
+	second := RandStringAll(32)
+	if first == second {
+		t.Errorf("RandStringAll() = %q twice with the same seed, want strings independent of the seed", first)
+	}
+	if len(RandStringLower(8)) != 8 {
+		t.Error("RandStringLower(8) did not return 8 characters")
+	}
+}
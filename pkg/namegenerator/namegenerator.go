@@ -1,8 +1,13 @@
 package namegenerator
 
 import (
-	"math/rand"
+	"crypto/rand"
+	"math/big"
+	"os"
+	"strconv"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 const lowerLetterBytes = "abcdefghijklmnopqrstuvwxyz"
@@ -10,37 +15,77 @@ const upperLetterBytes = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 const numberBytes = "0123456789"
 const defaultRandStringLength = 5
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+const (
+	// SeedEnvironmentKey is the environment variable seeding the default generator, set it to the seed logged by a
+	// run to replay its names.
+	SeedEnvironmentKey = "CATTLE_TEST_NAME_SEED"
+)
+
+// defaultGenerator is the generator of the package names, seeded by SeedEnvironmentKey or the current time.
+var defaultGenerator = New(defaultSeed(), "")
+
+// defaultSeed returns the seed set by SeedEnvironmentKey, the current time when it is unset or invalid.
+func defaultSeed() int64 {
+	if value, ok := os.LookupEnv(SeedEnvironmentKey); ok {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			return seed
+		}
+		logrus.Errorf("Invalid %s %q, seeding the names with the current time: %v", SeedEnvironmentKey, value, err)
+	}
+
+	return time.Now().UnixNano()
+}
+
+// Seed returns the seed of the default generator.
+func Seed() int64 {
+	return defaultGenerator.Seed()
+}
+
+// RunID returns the run ID of the default generator, the run ID of the sessions, see session.RunID.
+func RunID() string {
+	return defaultGenerator.RunID()
 }
 
 // RandStringLower returns a random string with lower case alpha
-// chars with the length depending on `n`. Used for creating a random string for resource names, such as clusters.
+// chars with the length depending on `n`. The string is read from crypto/rand, not from the seed of the names, as it
+// is used for passwords and tokens, see Name for the resource names.
 func RandStringLower(n int) string {
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = lowerLetterBytes[rand.Intn(len(lowerLetterBytes))]
-	}
-	return string(b)
+	return RandStringWithCharset(n, lowerLetterBytes)
 }
 
 // RandStringWithCharset returns a random string with specifc characters from the `charset` parameter
-// with the length depending on `n`. Used for creating a random string for resource names, such as clusters.
+// with the length depending on `n`. The string is read from crypto/rand, not from the seed of the names.
 func RandStringWithCharset(length int, charset string) string {
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			panic(err)
+		}
+		b[i] = charset[n.Int64()]
 	}
 	return string(b)
 }
 
-// RandStringLower returns a random string with all alpha-numeric chars
-// with the length depending on `n`. Used for creating a random string for resource names, such as clusters.
+// RandStringAll returns a random string with all alpha-numeric chars
+// with the length depending on `n`, read from crypto/rand, see RandStringWithCharset.
 func RandStringAll(length int) string {
 	return RandStringWithCharset(length, lowerLetterBytes+upperLetterBytes+numberBytes)
 }
 
-func AppendRandomString(baseClusterName string) string {
-	clusterName := "auto-" + baseClusterName + "-" + RandStringLower(defaultRandStringLength)
-	return clusterName
+// AppendRandomString returns a cluster name made of baseClusterName, the run ID and a random suffix, see Name.
+func AppendRandomString(baseClusterName string) (string, error) {
+	return Name(Cluster, baseClusterName)
+}
+
+// Name returns a name of kind made of base, the run ID and a random suffix with the default generator, see
+// Generator.Name.
+func Name(kind Kind, base string) (string, error) {
+	return defaultGenerator.Name(kind, base)
+}
+
+// UniqueName returns a name of kind that does not exist yet with the default generator, see Generator.UniqueName.
+func UniqueName(kind Kind, base string, exists ExistsFunc) (string, error) {
+	return defaultGenerator.UniqueName(kind, base, exists)
 }
@@ -1,10 +1,13 @@
 package nodes
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/pkg/sftp"
 	"github.com/rancher/shepherd/pkg/config"
@@ -49,11 +52,11 @@ func formatAddress(ipAddress string) string {
 	return ipAddress + ":22"
 }
 
-// SCPFileToNode copies a file from the local machine to the specific node created.
-func (n *Node) SCPFileToNode(localPath, remotePath string) error {
+// dial opens an ssh connection to the public IP address of the node.
+func (n *Node) dial() (*ssh.Client, error) {
 	signer, err := ssh.ParsePrivateKey(n.SSHKey)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	auths := []ssh.AuthMethod{ssh.PublicKeys([]ssh.Signer{signer}...)}
@@ -66,7 +69,12 @@ func (n *Node) SCPFileToNode(localPath, remotePath string) error {
 	cfg.SetDefaults()
 
 	ipAddress := formatAddress(n.PublicIPAddress)
-	client, err := ssh.Dial("tcp", ipAddress, cfg)
+	return ssh.Dial("tcp", ipAddress, cfg)
+}
+
+// SCPFileToNode copies a file from the local machine to the specific node created.
+func (n *Node) SCPFileToNode(localPath, remotePath string) error {
+	client, err := n.dial()
 	if err != nil {
 		return err
 	}
@@ -103,40 +111,48 @@ func (n *Node) SCPFileToNode(localPath, remotePath string) error {
 
 // ExecuteCommand executes `command` in the specific node created.
 func (n *Node) ExecuteCommand(command string) (string, error) {
-	signer, err := ssh.ParsePrivateKey(n.SSHKey)
-	var output []byte
-	var outputString string
+	var output bytes.Buffer
+	err := n.ExecuteCommandStream(command, &output, &output)
 
-	if err != nil {
-		return outputString, err
-	}
-
-	auths := []ssh.AuthMethod{ssh.PublicKeys([]ssh.Signer{signer}...)}
-
-	cfg := &ssh.ClientConfig{
-		User:            n.SSHUser,
-		Auth:            auths,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-
-	cfg.SetDefaults()
+	return output.String(), err
+}
 
-	ipAddress := formatAddress(n.PublicIPAddress)
-	client, err := ssh.Dial("tcp", ipAddress, cfg)
+// ExecuteCommandStream executes `command` in the specific node created, writing its output to stdout and stderr as
+// the command runs. stdout and stderr may be the same writer.
+func (n *Node) ExecuteCommandStream(command string, stdout, stderr io.Writer) error {
+	client, err := n.dial()
 	if err != nil {
-		return outputString, err
+		return err
 	}
 	defer client.Close()
 
 	session, err := client.NewSession()
 	if err != nil {
-		return outputString, err
+		return err
 	}
 	defer session.Close()
 
-	output, err = session.CombinedOutput(command)
-	outputString = string(output)
-	return outputString, err
+	if stdout == stderr {
+		stdout = &lockedWriter{writer: stdout}
+		stderr = stdout
+	}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	return session.Run(command)
+}
+
+// lockedWriter serializes the writes of the stdout and stderr of a command to the same writer.
+type lockedWriter struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.writer.Write(p)
 }
 
 // GetSSHKey reads in the ssh file from the .ssh directory, returns the key in []byte format
@@ -170,3 +186,8 @@ func GetSSHPath() *SSHPath {
 
 	return sshPathConfig
 }
+
+func init() {
+	config.Register(ExternalNodeConfigConfigurationFileKey, ExternalNodeConfig{})
+	config.Register(SSHPathConfigurationKey, SSHPath{})
+}
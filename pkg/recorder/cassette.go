@@ -0,0 +1,156 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Request is the recorded part of an HTTP request. Headers are not recorded, and the credentials of JSON bodies are
+// redacted, see Redacted.
+//
+// Cassettes recorded against a live Rancher must still be reviewed before they are shared: the bodies that are not
+// JSON and the websocket streams are recorded verbatim, and so are the credentials stored in fields the recorder
+// does not know about.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response. The Set-Cookie header is dropped and the credentials of the body are redacted,
+// see Request.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a recorded HTTP request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Stream is the data received over a websocket connection to Address, including the upgrade response.
+type Stream struct {
+	Address string `json:"address"`
+	Data    []byte `json:"data"`
+}
+
+// record is a single line of a cassette file.
+type record struct {
+	Interaction *Interaction `json:"interaction,omitempty"`
+	Stream      *Stream      `json:"stream,omitempty"`
+}
+
+// Cassette holds the interactions and streams recorded for a test run. It is stored as one JSON record per line, so
+// that records can be appended as they happen and a killed test still leaves a usable cassette behind.
+type Cassette struct {
+	Interactions []Interaction
+	Streams      []Stream
+
+	lock sync.Mutex
+	file *os.File
+}
+
+// LoadCassette reads the cassette at path.
+func LoadCassette(path string) (*Cassette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cassette := &Cassette{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var r record
+		err := json.Unmarshal(scanner.Bytes(), &r)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Interaction != nil {
+			cassette.Interactions = append(cassette.Interactions, *r.Interaction)
+		}
+		if r.Stream != nil {
+			cassette.Streams = append(cassette.Streams, *r.Stream)
+		}
+	}
+
+	return cassette, scanner.Err()
+}
+
+// createCassette creates an empty cassette at path, truncating any previous recording.
+func createCassette(path string) (*Cassette, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cassette{file: file}, nil
+}
+
+// openCassette opens the cassette at path to append records to it.
+func openCassette(path string) (*Cassette, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette.file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return cassette, nil
+}
+
+// Close closes the cassette file, the records appended afterwards are not written to it. It does nothing for loaded
+// cassettes.
+func (c *Cassette) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+
+	err := c.file.Close()
+	c.file = nil
+
+	return err
+}
+
+// append adds r to the cassette and writes it to the cassette file.
+func (c *Cassette) append(r record) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if r.Interaction != nil {
+		c.Interactions = append(c.Interactions, *r.Interaction)
+	}
+	if r.Stream != nil {
+		c.Streams = append(c.Streams, *r.Stream)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if c.file == nil {
+		return errors.New("cassette is closed")
+	}
+	_, err = c.file.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,295 @@
+// Package recorder provides an HTTP and websocket record/replay layer for the Rancher clients, so that extensions can
+// be tested offline against responses recorded from a live Rancher.
+package recorder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mode is the mode a Recorder operates in.
+type Mode string
+
+const (
+	// ModeRecord forwards every request to the server and records it along with its response.
+	ModeRecord Mode = "record"
+	// ModeReplay answers every request with a recorded response without contacting the server.
+	ModeReplay Mode = "replay"
+)
+
+var (
+	recorders     = map[string]*Recorder{}
+	recordersLock sync.Mutex
+	// recorded are the cassettes recorded by the process, which are appended to rather than truncated when they are
+	// recorded to again once their shared Recorder was closed.
+	recorded = map[string]bool{}
+)
+
+// Recorder records or replays the HTTP and websocket traffic of the clients it is installed on.
+type Recorder struct {
+	mode     Mode
+	path     string
+	cassette *Cassette
+	// refs counts the Shared calls that returned the Recorder and were not closed yet.
+	refs int
+
+	lock     sync.Mutex
+	replayed map[int]bool
+	streams  map[int]bool
+}
+
+// New returns a Recorder for the cassette at path. In ModeRecord the cassette is created or truncated, in ModeReplay it
+// must exist.
+func New(path string, mode Mode) (*Recorder, error) {
+	var cassette *Cassette
+	var err error
+
+	switch mode {
+	case ModeRecord:
+		cassette, err = createCassette(path)
+	case ModeReplay:
+		cassette, err = LoadCassette(path)
+	default:
+		return nil, fmt.Errorf("unknown recorder mode %q", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		mode:     mode,
+		path:     path,
+		cassette: cassette,
+		replayed: map[int]bool{},
+		streams:  map[int]bool{},
+	}, nil
+}
+
+// Shared returns the Recorder of the cassette at path, creating it on first use, so that every client of a test run
+// records to, or replays from, the same cassette. Every call must be matched by a call to Close, the cassette file is
+// closed once all of them are.
+func Shared(path string, mode Mode) (*Recorder, error) {
+	recordersLock.Lock()
+	defer recordersLock.Unlock()
+
+	if recorder, ok := recorders[path]; ok {
+		if recorder.mode != mode {
+			return nil, fmt.Errorf("cassette %s is already used in %s mode", path, recorder.mode)
+		}
+		recorder.refs++
+		return recorder, nil
+	}
+
+	var recorder *Recorder
+	var err error
+	if mode == ModeRecord && recorded[path] {
+		recorder, err = resume(path)
+	} else {
+		recorder, err = New(path, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	recorder.refs = 1
+	recorders[path] = recorder
+	if mode == ModeRecord {
+		recorded[path] = true
+	}
+
+	return recorder, nil
+}
+
+// resume returns a Recorder appending to the cassette at path.
+func resume(path string) (*Recorder, error) {
+	cassette, err := openCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		mode:     ModeRecord,
+		path:     path,
+		cassette: cassette,
+		replayed: map[int]bool{},
+		streams:  map[int]bool{},
+	}, nil
+}
+
+// Close closes the cassette file of the Recorder. A Recorder returned by Shared is only closed once every client
+// sharing it closed it.
+func (r *Recorder) Close() error {
+	recordersLock.Lock()
+	defer recordersLock.Unlock()
+
+	if r.refs > 1 {
+		r.refs--
+		return nil
+	}
+	r.refs = 0
+	if recorders[r.path] == r {
+		delete(recorders, r.path)
+	}
+
+	return r.cassette.Close()
+}
+
+// Mode returns the mode of the Recorder.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Cassette returns the cassette the Recorder records to or replays from.
+func (r *Recorder) Cassette() *Cassette {
+	return r.cassette
+}
+
+// WrapTransport returns a http.RoundTripper recording the traffic going through rt, or replaying it. Its signature
+// matches the rest.Config and clientbase.ClientOpts WrapTransport fields.
+func (r *Recorder) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	return &transport{
+		recorder: r,
+		next:     rt,
+	}
+}
+
+type transport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	recorded := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Body:   redactBody(string(body)),
+	}
+
+	if t.recorder.mode == ModeReplay {
+		response, err := t.recorder.replay(recorded)
+		if err != nil {
+			return nil, err
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(response.Body))),
+			ContentLength: int64(len(response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// session cookies set by login requests must not end up in the cassette
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	// the body is recorded as it is read rather than upfront, so that watches are not blocked until they end
+	resp.Body = &recordBody{
+		ReadCloser: resp.Body,
+		done: func(body []byte) {
+			err := t.recorder.cassette.append(record{
+				Interaction: &Interaction{
+					Request: recorded,
+					Response: Response{
+						StatusCode: resp.StatusCode,
+						Header:     header,
+						Body:       redactBody(string(body)),
+					},
+				},
+			})
+			if err != nil {
+				logrus.Errorf("failed to record %s %s: %v", recorded.Method, recorded.URL, err)
+			}
+		},
+	}
+
+	return resp, nil
+}
+
+// recordBody is a response body calling done with everything that was read from it once it is closed.
+type recordBody struct {
+	io.ReadCloser
+	done func([]byte)
+
+	lock     sync.Mutex
+	data     bytes.Buffer
+	recorded bool
+}
+
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.lock.Lock()
+	b.data.Write(p[:n])
+	b.lock.Unlock()
+
+	return n, err
+}
+
+func (b *recordBody) Close() error {
+	b.lock.Lock()
+	if !b.recorded {
+		b.recorded = true
+		b.done(bytes.Clone(b.data.Bytes()))
+	}
+	b.lock.Unlock()
+
+	return b.ReadCloser.Close()
+}
+
+// replay returns the response of the first interaction matching the method and URL of request that was not
+// replayed yet. Once every matching interaction was replayed the last one keeps being returned, which lets polling
+// loops complete.
+func (r *Recorder) replay(request Request) (*Response, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	last := -1
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Request.Method != request.Method || interaction.Request.URL != request.URL {
+			continue
+		}
+
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return &r.cassette.Interactions[i].Response, nil
+		}
+		last = i
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", request.Method, request.URL)
+	}
+
+	return &r.cassette.Interactions[last].Response, nil
+}
@@ -0,0 +1,169 @@
+package recorder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func Test_RecordAndReplay(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("Set-Cookie", "R_SESS=secret")
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	get := func(client *http.Client, url string) string {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", url, err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+
+		return string(body)
+	}
+
+	recording, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := &http.Client{Transport: recording.WrapTransport(server.Client().Transport)}
+	get(client, server.URL+"/v3/clusters")
+	get(client, server.URL+"/v1/namespaces")
+	if err := recording.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	replaying, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client = &http.Client{Transport: replaying.WrapTransport(nil)}
+
+	if body := get(client, server.URL+"/v1/namespaces"); body != "/v1/namespaces" {
+		t.Errorf("replayed body = %q, want /v1/namespaces", body)
+	}
+	if count != 2 {
+		t.Errorf("server received %d requests, want 2", count)
+	}
+	if cookie := replaying.Cassette().Interactions[0].Response.Header.Get("Set-Cookie"); cookie != "" {
+		t.Errorf("recorded Set-Cookie = %q, want it dropped", cookie)
+	}
+
+	_, err = client.Get(server.URL + "/v3/users")
+	if err == nil {
+		t.Error("Get() of an unrecorded URL error = nil, want an error")
+	}
+}
+
+func Test_RedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "login request",
+			body: `{"username":"admin","password":"secret","responseType":"token"}`,
+			want: `{"password":"REDACTED","responseType":"token","username":"admin"}`,
+		},
+		{
+			name: "created token",
+			body: `{"type":"token","name":"token-abc","token":"token-abc:secret"}`,
+			want: `{"name":"token-abc","token":"REDACTED","type":"token"}`,
+		},
+		{
+			name: "steve secret collection",
+			body: `{"type":"collection","data":[{"type":"secret","data":{"key":"c2VjcmV0"}}]}`,
+			want: `{"data":[{"data":{"key":"REDACTED"},"type":"secret"}],"type":"collection"}`,
+		},
+		{
+			name: "kubernetes secret list",
+			body: `{"kind":"SecretList","items":[{"stringData":{"key":"secret"}}]}`,
+			want: `{"items":[{"stringData":{"key":"REDACTED"}}],"kind":"SecretList"}`,
+		},
+		{
+			name: "watch events",
+			body: `{"type":"ADDED","object":{"kind":"Secret","data":{"key":"c2VjcmV0"}}}` + "\n" + `{"type":"ADDED","object":{"kind":"ConfigMap","data":{"key":"value"}}}`,
+			want: `{"object":{"data":{"key":"REDACTED"},"kind":"Secret"},"type":"ADDED"}` + "\n" + `{"object":{"data":{"key":"value"},"kind":"ConfigMap"},"type":"ADDED"}`,
+		},
+		{
+			name: "generated kubeconfig",
+			body: `{"type":"generateKubeConfigOutput","config":"apiVersion: v1"}`,
+			want: `{"config":"REDACTED","type":"generateKubeConfigOutput"}`,
+		},
+		{
+			name: "not json",
+			body: "password=secret",
+			want: "password=secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody(tt.body); got != tt.want {
+				t.Errorf("redactBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_SharedClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+
+	first, err := Shared(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("Shared() error = %v", err)
+	}
+	second, err := Shared(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("Shared() error = %v", err)
+	}
+	if first != second {
+		t.Fatal("Shared() returned another Recorder for the same cassette")
+	}
+
+	appendRecord := func(recorder *Recorder, url string) {
+		err := recorder.Cassette().append(record{Interaction: &Interaction{Request: Request{Method: http.MethodGet, URL: url}}})
+		if err != nil {
+			t.Fatalf("append() error = %v", err)
+		}
+	}
+
+	appendRecord(first, "/v3/clusters")
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	appendRecord(second, "/v3/users")
+	if err := second.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// the cassette is appended to, not truncated, when it is recorded to again
+	third, err := Shared(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("Shared() error = %v", err)
+	}
+	appendRecord(third, "/v1/namespaces")
+	if err := third.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Errorf("cassette has %d interactions, want 3", len(cassette.Interactions))
+	}
+}
@@ -0,0 +1,112 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Redacted replaces the credentials of the recorded bodies.
+const Redacted = "REDACTED"
+
+// credentialFields are the JSON fields redacted wherever they appear in a recorded body, e.g. the password of a login
+// request or the bearer token of a created token.
+var credentialFields = map[string]bool{
+	"password":        true,
+	"currentPassword": true,
+	"newPassword":     true,
+	"token":           true,
+	"bearerToken":     true,
+	"secretKey":       true,
+	"clientSecret":    true,
+	"privateKey":      true,
+	"sshKey":          true,
+}
+
+// redactBody returns the JSON body, or stream of JSON values of a watch, with the values of its credentials replaced
+// by Redacted: the credentialFields, the data of secrets and the generated kubeconfigs. Bodies that are not JSON are
+// returned as they are.
+func redactBody(body string) string {
+	if body == "" {
+		return body
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	var redacted bytes.Buffer
+	for {
+		var value any
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return body
+		}
+
+		data, err := json.Marshal(redactValue(value, false))
+		if err != nil {
+			return body
+		}
+		if redacted.Len() > 0 {
+			redacted.WriteByte('\n')
+		}
+		redacted.Write(data)
+	}
+
+	return redacted.String()
+}
+
+// redactValue redacts the credentials of value, secret is set for the items of kubernetes secret lists, which have no
+// kind of their own.
+func redactValue(value any, secret bool) any {
+	switch value := value.(type) {
+	case map[string]any:
+		secret = secret || isSecret(value)
+		for key, field := range value {
+			switch {
+			case credentialFields[key]:
+				value[key] = redactString(field)
+			case (key == "data" || key == "stringData") && secret:
+				if data, ok := field.(map[string]any); ok {
+					for dataKey, dataValue := range data {
+						data[dataKey] = redactString(dataValue)
+					}
+				}
+			case key == "config" && value["type"] == "generateKubeConfigOutput":
+				value[key] = redactString(field)
+			case key == "items" && value["kind"] == "SecretList":
+				value[key] = redactValue(field, true)
+			default:
+				value[key] = redactValue(field, false)
+			}
+		}
+	case []any:
+		for i := range value {
+			value[i] = redactValue(value[i], secret)
+		}
+	}
+
+	return value
+}
+
+// isSecret returns whether the object is a kubernetes secret, as returned by the kube and Steve APIs, or a Norman
+// secret.
+func isSecret(object map[string]any) bool {
+	kind, _ := object["kind"].(string)
+	schemaType, _ := object["type"].(string)
+
+	return kind == "Secret" || schemaType == "secret" || strings.HasSuffix(schemaType, "Secret")
+}
+
+// redactString returns Redacted for the non empty strings, value otherwise.
+func redactString(value any) any {
+	if s, ok := value.(string); ok && s != "" {
+		return Redacted
+	}
+
+	return value
+}
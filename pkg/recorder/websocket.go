@@ -0,0 +1,191 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	websocketKeyRegexp    = regexp.MustCompile(`(?i)\r\nSec-WebSocket-Key: ([^\r]+)\r\n`)
+	websocketAcceptRegexp = regexp.MustCompile(`(?i)(\r\nSec-WebSocket-Accept: )([^\r]+)(\r\n)`)
+)
+
+// WrapDialer returns a copy of dialer whose connections are recorded or replayed. Streams are matched by the address
+// they connect to, in the order they were recorded.
+func (r *Recorder) WrapDialer(dialer *websocket.Dialer) *websocket.Dialer {
+	if dialer == nil {
+		dialer = &websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	}
+
+	wrapped := *dialer
+	wrapped.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return r.dial(ctx, dialer, network, addr, nil)
+	}
+	wrapped.NetDialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		tlsConfig := wrapped.TLSClientConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		return r.dial(ctx, dialer, network, addr, tlsConfig)
+	}
+
+	return &wrapped
+}
+
+// dial opens a recorded connection to addr, using TLS when tlsConfig is set, or a connection replaying a stream.
+func (r *Recorder) dial(ctx context.Context, dialer *websocket.Dialer, network, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	if r.mode == ModeReplay {
+		data, err := r.replayStream(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &replayConn{data: data}, nil
+	}
+
+	netDial := dialer.NetDialContext
+	if netDial == nil {
+		netDial = (&net.Dialer{}).DialContext
+	}
+
+	conn, err := netDial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		config := tlsConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+
+		tlsConn := tls.Client(conn, config)
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	return &recordConn{Conn: conn, recorder: r, address: addr}, nil
+}
+
+// replayStream returns the data of the first stream recorded for addr that was not replayed yet.
+func (r *Recorder) replayStream(addr string) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, stream := range r.cassette.Streams {
+		if stream.Address == addr && !r.streams[i] {
+			r.streams[i] = true
+			return stream.Data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no recorded websocket stream for %s", addr)
+}
+
+// recordConn records everything read from the connection, and appends it to the cassette when it is closed.
+type recordConn struct {
+	net.Conn
+	recorder *Recorder
+	address  string
+
+	lock   sync.Mutex
+	data   bytes.Buffer
+	closed bool
+}
+
+func (c *recordConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	c.lock.Lock()
+	c.data.Write(b[:n])
+	c.lock.Unlock()
+
+	return n, err
+}
+
+func (c *recordConn) Close() error {
+	c.lock.Lock()
+	if !c.closed {
+		c.closed = true
+		c.recorder.cassette.append(record{
+			Stream: &Stream{
+				Address: c.address,
+				Data:    bytes.Clone(c.data.Bytes()),
+			},
+		})
+	}
+	c.lock.Unlock()
+
+	return c.Conn.Close()
+}
+
+// replayConn replays a recorded stream. The upgrade response is rewritten to accept the key of the replayed
+// handshake, since the websocket client checks it against the random key it sent.
+type replayConn struct {
+	lock     sync.Mutex
+	data     []byte
+	request  bytes.Buffer
+	accepted bool
+}
+
+func (c *replayConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.accepted {
+		c.request.Write(b)
+	}
+
+	return len(b), nil
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.accepted {
+		c.accepted = true
+		if matches := websocketKeyRegexp.FindSubmatch(c.request.Bytes()); matches != nil {
+			hash := sha1.Sum([]byte(string(bytes.TrimSpace(matches[1])) + websocketGUID))
+			accept := base64.StdEncoding.EncodeToString(hash[:])
+			c.data = websocketAcceptRegexp.ReplaceAll(c.data, []byte("${1}"+accept+"${3}"))
+		}
+	}
+
+	if len(c.data) == 0 {
+		return 0, net.ErrClosed
+	}
+
+	n := copy(b, c.data)
+	c.data = c.data[n:]
+
+	return n, nil
+}
+
+func (c *replayConn) Close() error                       { return nil }
+func (c *replayConn) LocalAddr() net.Addr                { return replayAddr{} }
+func (c *replayConn) RemoteAddr() net.Addr               { return replayAddr{} }
+func (c *replayConn) SetDeadline(_ time.Time) error      { return nil }
+func (c *replayConn) SetReadDeadline(_ time.Time) error  { return nil }
+func (c *replayConn) SetWriteDeadline(_ time.Time) error { return nil }
+
+type replayAddr struct{}
+
+func (replayAddr) Network() string { return "replay" }
+func (replayAddr) String() string  { return "replay" }
@@ -0,0 +1,217 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	journalRegistered = "registered"
+	journalCleaned    = "cleaned"
+)
+
+// journalRecord is a single line of a session journal.
+type journalRecord struct {
+	Op       string    `json:"op"`
+	ID       int       `json:"id"`
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// journal is an append only file recording every resource registered with a persistent session and whether it was
+// cleaned up, so that the resources can still be deleted if the test process is killed.
+type journal struct {
+	lock   sync.Mutex
+	file   *os.File
+	nextID int
+}
+
+// openJournal opens or creates the journal at path, continuing the IDs of the records it already holds.
+func openJournal(path string) (*journal, error) {
+	records, err := readJournal(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &journal{
+		file:   file,
+		nextID: 1,
+	}
+	for _, record := range records {
+		if record.ID >= j.nextID {
+			j.nextID = record.ID + 1
+		}
+	}
+
+	return j, nil
+}
+
+// register appends a record for resource and returns its ID.
+func (j *journal) register(resource *Resource) int {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	id := j.nextID
+	j.nextID++
+	j.write(journalRecord{Op: journalRegistered, ID: id, Resource: resource})
+
+	return id
+}
+
+// cleaned appends a record marking the resource registered under id as deleted.
+func (j *journal) cleaned(id int) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.write(journalRecord{Op: journalCleaned, ID: id})
+}
+
+// close closes the journal file, the records written afterwards are dropped.
+func (j *journal) close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+
+	err := j.file.Close()
+	j.file = nil
+
+	return err
+}
+
+func (j *journal) write(record journalRecord) {
+	if j.file == nil {
+		logrus.Errorf("failed to write session journal: the journal is closed")
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		logrus.Errorf("failed to marshal session journal record: %v", err)
+		return
+	}
+
+	_, err = j.file.Write(append(data, '\n'))
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		logrus.Errorf("failed to write session journal %s: %v", j.file.Name(), err)
+	}
+}
+
+func readJournal(path string) ([]journalRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []journalRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record journalRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			// the last line may be truncated if the process was killed while writing it
+			logrus.Warnf("skipping malformed session journal record in %s: %v", path, err)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// pendingRecords returns the registered records that have no matching cleaned record, in registration order.
+func pendingRecords(records []journalRecord) []journalRecord {
+	cleaned := map[int]bool{}
+	for _, record := range records {
+		if record.Op == journalCleaned {
+			cleaned[record.ID] = true
+		}
+	}
+
+	var pending []journalRecord
+	for _, record := range records {
+		if record.Op == journalRegistered && record.Resource != nil && !cleaned[record.ID] {
+			pending = append(pending, record)
+		}
+	}
+
+	return pending
+}
+
+// NewPersistentSession instantiates a new `Session` that journals every resource registered with it, and every
+// successful cleanup, to the file at path. Resources left in the journal by a killed test can be deleted with `Resume`.
+func NewPersistentSession(path string) (*Session, error) {
+	j, err := openJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := NewSession()
+	ts.journal = j
+	ts.ownsJournal = true
+
+	return ts, nil
+}
+
+// ReadJournal returns the resources of the journal at path that have not been cleaned up yet, in registration order.
+func ReadJournal(path string) ([]Resource, error) {
+	records, err := readJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, record := range pendingRecords(records) {
+		resources = append(resources, *record.Resource)
+	}
+
+	return resources, nil
+}
+
+// Resume returns a persistent `Session` on the journal at path, with a cleanup entry calling deleteFunc for every
+// resource that has not been cleaned up yet. Calling `Cleanup` on the returned session deletes them and records them
+// as cleaned in the journal, callers that do not clean it up must `Close` it.
+func Resume(path string, deleteFunc func(Resource) error) (*Session, error) {
+	records, err := readJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := NewPersistentSession(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range pendingRecords(records) {
+		resource := *record.Resource
+		ts.cleanupQueue = append(ts.cleanupQueue, CleanupEntry{
+			Resource: &resource,
+			Cleanup: func() error {
+				return deleteFunc(resource)
+			},
+			journalID: record.ID,
+		})
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LeakManifest is the list of resources a session failed to clean up. It is written to `Session.LeakManifestPath`
+// so that the resources can be deleted later on by a janitor.
+type LeakManifest struct {
+	Resources []Resource `json:"resources" yaml:"resources"`
+}
+
+// WriteLeakManifest writes the given resources to path, as JSON if the file has a .json extension and as YAML otherwise.
+func WriteLeakManifest(path string, resources []Resource) error {
+	manifest := LeakManifest{Resources: resources}
+
+	var data []byte
+	var err error
+	if filepath.Ext(path) == ".json" {
+		data, err = json.MarshalIndent(manifest, "", "  ")
+	} else {
+		data, err = yaml.Marshal(manifest)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadLeakManifest reads a manifest previously written by WriteLeakManifest.
+func ReadLeakManifest(path string) (*LeakManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &LeakManifest{}
+	err = yaml.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	shepherdPackagePrefix = "github.com/rancher/shepherd/"
+
+	// LocalClusterID is the ClusterID of the resources created in the cluster Rancher runs in.
+	LocalClusterID = "local"
+)
+
+var clusterIDRegexp = regexp.MustCompile(`/clusters/([^/]+)`)
+
+// ClusterIDFromURL returns the ID of the downstream cluster a client URL or rest config host proxies to, or
+// LocalClusterID when it does not proxy to a downstream cluster.
+func ClusterIDFromURL(clientURL string) string {
+	matches := clusterIDRegexp.FindStringSubmatch(clientURL)
+	if len(matches) < 2 {
+		return LocalClusterID
+	}
+
+	return matches[1]
+}
+
+// Resource describes the object deleted by a `CleanupEntry`. Kubernetes objects are identified by their GVK/resource
+// and namespace/name, Norman objects by their schema type, ID and self link.
+type Resource struct {
+	Group     string `json:"group,omitempty" yaml:"group,omitempty"`
+	Version   string `json:"version,omitempty" yaml:"version,omitempty"`
+	Kind      string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Resource  string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Type      string `json:"type,omitempty" yaml:"type,omitempty"`
+	ID        string `json:"id,omitempty" yaml:"id,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	// ClusterID is the ID of the cluster the kubernetes object was created in, LocalClusterID for the local cluster.
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+	CreatedBy string `json:"createdBy,omitempty" yaml:"createdBy,omitempty"`
+}
+
+// String returns a human readable identifier of the resource.
+func (r *Resource) String() string {
+	if r.Type != "" {
+		return fmt.Sprintf("(%s) %s", r.Type, r.ID)
+	}
+
+	name := r.Name
+	if r.Namespace != "" {
+		name = r.Namespace + "/" + name
+	}
+
+	gvk := r.Kind
+	if r.Group != "" || r.Version != "" {
+		gvk = strings.TrimPrefix(r.Group+"/"+r.Version, "/") + ", Kind=" + r.Kind
+	}
+
+	return fmt.Sprintf("(%s) %s", gvk, name)
+}
+
+// CleanupResult is the outcome of running a single `CleanupEntry`.
+type CleanupResult struct {
+	Name     string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Resource *Resource     `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Attempts int           `json:"attempts" yaml:"attempts"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Error    string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// description returns the resource identifier if there is one, the entry name otherwise.
+func (r *CleanupResult) description() string {
+	if r.Resource != nil {
+		return r.Resource.String()
+	}
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return "unnamed cleanup function"
+}
+
+// CleanupReport is returned by `Session.CleanupWithReport` and lists every cleanup that succeeded or failed,
+// including the ones of nested sessions.
+type CleanupReport struct {
+	Cleaned  []CleanupResult `json:"cleaned,omitempty" yaml:"cleaned,omitempty"`
+	Failed   []CleanupResult `json:"failed,omitempty" yaml:"failed,omitempty"`
+	Duration time.Duration   `json:"duration" yaml:"duration"`
+}
+
+// Leaked returns the resources whose cleanup failed.
+func (r *CleanupReport) Leaked() []Resource {
+	var resources []Resource
+	for _, result := range r.Failed {
+		if result.Resource != nil {
+			resources = append(resources, *result.Resource)
+		}
+	}
+
+	return resources
+}
+
+// merge appends the results of a nested session's report.
+func (r *CleanupReport) merge(nested *CleanupReport) {
+	r.Cleaned = append(r.Cleaned, nested.Cleaned...)
+	r.Failed = append(r.Failed, nested.Failed...)
+}
+
+// callSite returns the file:line of the first caller outside of shepherd, or the first caller outside of this
+// package when the resource was created by shepherd itself.
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	var fallback string
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, shepherdPackagePrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if fallback == "" && !strings.HasPrefix(frame.Function, shepherdPackagePrefix+"pkg/session.") {
+			fallback = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return fallback
+		}
+	}
+}
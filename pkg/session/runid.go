@@ -0,0 +1,137 @@
+package session
+
+import (
+	"crypto/rand"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RunIDEnvironmentKey is the environment variable setting the run ID, a random one is generated when it is unset.
+	RunIDEnvironmentKey = "CATTLE_TEST_RUN_ID"
+	// RunIDLabel is the label, or the EC2 tag, holding the ID of the run that created a resource.
+	RunIDLabel = "shepherd.cattle.io/run-id"
+	// RunStartedLabel is the label, or the EC2 tag, holding the unix time the run that created a resource started at.
+	RunStartedLabel = "shepherd.cattle.io/run-started"
+
+	runIDLength  = 6
+	runIDCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	// maxLabelValueLength is the maximum length of a label value.
+	maxLabelValueLength = 63
+	// labelValueTrimmedChars are the characters valid in a label value but not at its ends.
+	labelValueTrimmedChars = "-_."
+)
+
+var (
+	runID      = newRunID()
+	runStarted = time.Now()
+
+	invalidLabelValueChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+	repeatedHyphens        = regexp.MustCompile(`-{2,}`)
+)
+
+// newRunID returns the run ID set by RunIDEnvironmentKey, sanitized to be a valid label value, or a random one when it
+// is unset or has no valid character.
+func newRunID() string {
+	if value := os.Getenv(RunIDEnvironmentKey); value != "" {
+		if id := SanitizeLabelValue(value, maxLabelValueLength); id != "" {
+			if id != value {
+				logrus.Warnf("Using the run ID %q for the %s %q, which is not a valid label value", id, RunIDEnvironmentKey, value)
+			}
+			return id
+		}
+		logrus.Warnf("Ignoring the %s %q, which has no valid label value character", RunIDEnvironmentKey, value)
+	}
+
+	b := make([]byte, runIDLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(runIDCharset))))
+		if err != nil {
+			panic(err)
+		}
+		b[i] = runIDCharset[n.Int64()]
+	}
+	return string(b)
+}
+
+// SanitizeLabelValue lower cases value, replaces the characters that are not valid in a label value by hyphens and
+// truncates it to maxLength characters, at most the 63 characters of a label value, so that it starts and ends with
+// a letter or a digit.
+func SanitizeLabelValue(value string, maxLength int) string {
+	maxLength = min(maxLength, maxLabelValueLength)
+
+	value = invalidLabelValueChars.ReplaceAllString(strings.ToLower(value), "-")
+	value = repeatedHyphens.ReplaceAllString(value, "-")
+	value = strings.Trim(value, labelValueTrimmedChars)
+	if len(value) > maxLength {
+		value = strings.TrimRight(value[:maxLength], labelValueTrimmedChars)
+	}
+
+	return value
+}
+
+// RunID returns the ID of the current run, shared by the sessions of the process.
+func RunID() string {
+	return runID
+}
+
+// RunStarted returns the time the current run started at.
+func RunStarted() time.Time {
+	return runStarted
+}
+
+// RunLabels returns the labels, or the EC2 tags, identifying the resources created by the current run,
+// RunIDLabel and RunStartedLabel.
+func RunLabels() map[string]string {
+	return runLabels(RunID())
+}
+
+// RunLabels returns the labels stamped on the resources created by the clients of the session, see RunLabels. The
+// RunID of the session overrides the run ID of the process.
+func (ts *Session) RunLabels() map[string]string {
+	if ts != nil && ts.RunID != "" {
+		return runLabels(ts.RunID)
+	}
+
+	return RunLabels()
+}
+
+func runLabels(id string) map[string]string {
+	return map[string]string{
+		RunIDLabel:      id,
+		RunStartedLabel: strconv.FormatInt(RunStarted().Unix(), 10),
+	}
+}
+
+// StampRunLabels adds the RunLabels of the session to obj, keeping its own values of these labels.
+func (ts *Session) StampRunLabels(obj metav1.Object) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	for key, value := range ts.RunLabels() {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
+
+	obj.SetLabels(labels)
+}
+
+// ParseRunStarted parses the value of a RunStartedLabel.
+func ParseRunStarted(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(seconds, 0), nil
+}
@@ -1,8 +1,11 @@
 package session
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
@@ -10,71 +13,340 @@ import (
 // CleanupFunc is the type RegisterCleanupFunc accepts
 type CleanupFunc func() error
 
+// CleanupEntry is a cleanup function registered with a `Session`, optionally named so that other entries can declare
+// dependencies on it.
+type CleanupEntry struct {
+	// Name identifies the entry for dependency purposes. Several entries may share the same name, in which case a
+	// dependency on that name refers to all of them.
+	Name string
+	// DependsOn lists the names of the entries this resource depends on, e.g. a workload depends on its namespace and
+	// a machine pool depends on its cluster. An entry is always cleaned up before the entries it depends on.
+	DependsOn []string
+	// Resource optionally describes the object deleted by Cleanup, it is reported if the cleanup fails.
+	Resource *Resource
+	// Cleanup is the function deleting the resource.
+	Cleanup CleanupFunc
+
+	journalID int
+}
+
 // Session is used to track resources created by tests by having a LIFO queue the keeps track of the delete functions.
 type Session struct {
 	CleanupEnabled bool
-	cleanupQueue   []CleanupFunc
-	open           bool
+	// CleanupConcurrency is the maximum number of cleanup functions run at the same time by `Cleanup`. Values lower than
+	// or equal to 1 run the queue serially. Regardless of the value, entries are never run before the entries depending on them.
+	CleanupConcurrency int
+	// CleanupBackoff overrides the backoff used to retry a failing cleanup function.
+	CleanupBackoff *wait.Backoff
+	// LeakManifestPath is the file the resources that could not be cleaned up are written to, see `WriteLeakManifest`.
+	LeakManifestPath string
+	// RunID overrides the run ID stamped on the resources created by the clients of the session, see `RunLabels`.
+	RunID string
+
+	lock          sync.Mutex
+	cleanupQueue  []CleanupEntry
+	closeFuncs    []CleanupFunc
+	nestedReports []*CleanupReport
+	journal       *journal
+	ownsJournal   bool
+	open          bool
 }
 
 // NewSession is a constructor instantiates a new `Session`
 func NewSession() *Session {
 	return &Session{
-		CleanupEnabled: true,
-		cleanupQueue:   []CleanupFunc{},
-		open:           true,
+		CleanupEnabled:     true,
+		CleanupConcurrency: 1,
+		cleanupQueue:       []CleanupEntry{},
+		open:               true,
 	}
 }
 
 // RegisterCleanupFunc is function registers clean up functions in the `Session` queue.
-// Functions passed to this method will be called in the order they are added when `Cleanup` is called.
+// Functions passed to this method will be called in the reverse order they are added when `Cleanup` is called.
+// It is safe to call from multiple goroutines.
 // If Session is closed, it will cause a panic if a new cleanup function is registered.
 func (ts *Session) RegisterCleanupFunc(f CleanupFunc) {
-	if ts.open {
-		ts.cleanupQueue = append(ts.cleanupQueue, f)
-	} else {
+	ts.RegisterCleanupEntry(CleanupEntry{Cleanup: f})
+}
+
+// RegisterNamedCleanupFunc registers a clean up function under name in the `Session` queue. dependsOn are the names
+// of previously or later registered entries that must only be cleaned up once f has completed.
+func (ts *Session) RegisterNamedCleanupFunc(name string, f CleanupFunc, dependsOn ...string) {
+	ts.RegisterCleanupEntry(CleanupEntry{
+		Name:      name,
+		DependsOn: dependsOn,
+		Cleanup:   f,
+	})
+}
+
+// RegisterCleanupEntry registers a `CleanupEntry` in the `Session` queue. It is safe to call from multiple goroutines.
+// The call site creating the resource is recorded when the entry describes a resource.
+// If Session is closed, it will cause a panic if a new entry is registered.
+func (ts *Session) RegisterCleanupEntry(entry CleanupEntry) {
+	if entry.Resource != nil && entry.Resource.CreatedBy == "" {
+		resource := *entry.Resource
+		resource.CreatedBy = callSite()
+		entry.Resource = &resource
+	}
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	if !ts.open {
 		panic("attempted to register cleanup function to closed test session")
 	}
 
+	if ts.journal != nil && entry.Resource != nil {
+		entry.journalID = ts.journal.register(entry.Resource)
+	}
+
+	ts.cleanupQueue = append(ts.cleanupQueue, entry)
+}
+
+// RegisterCloseFunc registers f to be called when the `Session` is closed, once `Cleanup` has run every cleanup
+// function, e.g. to close what records their requests. Functions passed to this method are called in the reverse order
+// they are added. If Session is closed, it will cause a panic if a new close function is registered.
+func (ts *Session) RegisterCloseFunc(f CleanupFunc) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	if !ts.open {
+		panic("attempted to register close function to closed test session")
+	}
+
+	ts.closeFuncs = append(ts.closeFuncs, f)
 }
 
-// Cleanup this method will call all registered cleanup functions in order and close the test session.
+// Close calls the registered close functions and closes the journal of a persistent session, `Cleanup` closes the
+// session once the cleanup functions have run. Sessions that are not cleaned up must be closed.
+func (ts *Session) Close() error {
+	ts.lock.Lock()
+	closeFuncs := ts.closeFuncs
+	ts.closeFuncs = nil
+	ts.lock.Unlock()
+
+	var errs error
+	for i := len(closeFuncs) - 1; i >= 0; i-- {
+		err := closeFuncs[i]()
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	if ts.ownsJournal {
+		err := ts.journal.close()
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// PendingCleanups returns the number of registered cleanup functions that have not been run yet, the ones of nested
+// sessions count as one.
+func (ts *Session) PendingCleanups() int {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	return len(ts.cleanupQueue)
+}
+
+// Cleanup this method will call all registered cleanup functions and close the test session. Entries are run in the
+// reverse order they were registered, except that an entry is held back until every entry depending on it is done.
+// When CleanupConcurrency is greater than 1, independent entries are run in parallel.
 func (ts *Session) Cleanup() {
-	if ts.CleanupEnabled {
-		ts.open = false
-
-		// sometimes it is necessary to retry cleanup due to the webhook validator block initial delete attempts
-		// due to using a stale cache
-		var backoff = wait.Backoff{
-			Duration: 100 * time.Millisecond,
-			Factor:   2,
-			Jitter:   0,
-			Steps:    5,
+	_, _ = ts.CleanupWithReport()
+}
+
+// CleanupWithReport behaves like Cleanup, and returns a report of every cleanup function that was run along with an
+// aggregated error of the ones that failed. If LeakManifestPath is set, the leaked resources are written to it.
+func (ts *Session) CleanupWithReport() (*CleanupReport, error) {
+	defer func() {
+		err := ts.Close()
+		if err != nil {
+			logrus.Errorf("failed to close session: %v", err)
+		}
+	}()
+
+	report := &CleanupReport{}
+	if !ts.CleanupEnabled {
+		return report, nil
+	}
+
+	ts.lock.Lock()
+	ts.open = false
+	queue := ts.cleanupQueue
+	ts.cleanupQueue = []CleanupEntry{}
+	ts.lock.Unlock()
+
+	// sometimes it is necessary to retry cleanup due to the webhook validator block initial delete attempts
+	// due to using a stale cache
+	backoff := wait.Backoff{
+		Duration: 100 * time.Millisecond,
+		Factor:   2,
+		Jitter:   0,
+		Steps:    5,
+	}
+	if ts.CleanupBackoff != nil {
+		backoff = *ts.CleanupBackoff
+	}
+
+	var errs error
+	var resultLock sync.Mutex
+	start := time.Now()
+
+	runCleanupQueue(queue, ts.CleanupConcurrency, func(entry CleanupEntry) {
+		result := CleanupResult{
+			Name:     entry.Name,
+			Resource: entry.Resource,
+		}
+		entryStart := time.Now()
+
+		var cleanupErr error
+		err := wait.ExponentialBackoff(backoff, func() (done bool, err error) {
+			result.Attempts++
+			cleanupErr = entry.Cleanup()
+			if cleanupErr != nil {
+				return false, nil
+			}
+			return true, nil
+		})
+		result.Duration = time.Since(entryStart)
+
+		resultLock.Lock()
+		defer resultLock.Unlock()
+
+		if err != nil {
+			logrus.Errorf("failed to cleanup %s. Backoff error: %v. Cleanup error: %v", result.description(), err, cleanupErr)
+			result.Error = fmt.Sprintf("%v: %v", err, cleanupErr)
+			report.Failed = append(report.Failed, result)
+			errs = multierror.Append(errs, fmt.Errorf("failed to cleanup %s: %w", result.description(), cleanupErr))
+			return
 		}
+		report.Cleaned = append(report.Cleaned, result)
 
-		for i := len(ts.cleanupQueue) - 1; i >= 0; i-- {
-			var cleanupErr error
-			err := wait.ExponentialBackoff(backoff, func() (done bool, err error) {
-				cleanupErr = ts.cleanupQueue[i]()
-				if cleanupErr != nil {
-					return false, nil
+		if ts.journal != nil && entry.journalID != 0 {
+			ts.journal.cleaned(entry.journalID)
+		}
+	})
+
+	ts.lock.Lock()
+	for _, nested := range ts.nestedReports {
+		report.merge(nested)
+		for _, result := range nested.Failed {
+			errs = multierror.Append(errs, fmt.Errorf("failed to cleanup %s: %s", result.description(), result.Error))
+		}
+	}
+	ts.nestedReports = nil
+	ts.lock.Unlock()
+
+	report.Duration = time.Since(start)
+
+	if ts.LeakManifestPath != "" && len(report.Leaked()) > 0 {
+		err := WriteLeakManifest(ts.LeakManifestPath, report.Leaked())
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to write leak manifest: %w", err))
+		}
+	}
+
+	return report, errs
+}
+
+// runCleanupQueue calls run for every entry of queue, with at most concurrency calls in flight. The most recently
+// registered entry that is not blocked by a dependent entry is always the next one started.
+func runCleanupQueue(queue []CleanupEntry, concurrency int, run func(CleanupEntry)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	byName := map[string][]int{}
+	for i, entry := range queue {
+		if entry.Name != "" {
+			byName[entry.Name] = append(byName[entry.Name], i)
+		}
+	}
+
+	// blockers counts the dependents that have not been cleaned up yet, unblocks lists the dependencies of an entry.
+	blockers := make([]int, len(queue))
+	unblocks := make([][]int, len(queue))
+	for i, entry := range queue {
+		for _, dependency := range entry.DependsOn {
+			for _, j := range byName[dependency] {
+				if j == i {
+					continue
+				}
+				blockers[j]++
+				unblocks[i] = append(unblocks[i], j)
+			}
+		}
+	}
+
+	started := make([]bool, len(queue))
+	finished := make(chan int)
+	running, done := 0, 0
+
+	next := func() int {
+		for i := len(queue) - 1; i >= 0; i-- {
+			if !started[i] && blockers[i] == 0 {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for done < len(queue) {
+		for running < concurrency {
+			i := next()
+			if i < 0 {
+				break
+			}
+
+			started[i] = true
+			running++
+			go func(i int) {
+				run(queue[i])
+				finished <- i
+			}(i)
+		}
+
+		if running == 0 {
+			// only a dependency cycle can leave entries blocked with nothing running, release the most recent one.
+			for i := len(queue) - 1; i >= 0; i-- {
+				if !started[i] {
+					logrus.Errorf("cleanup dependency cycle detected, running %s before its dependents", queue[i].Name)
+					blockers[i] = 0
+					break
 				}
-				return true, nil
-			})
-			if err != nil {
-				logrus.Errorf("failed to cleanup resource. Backoff error: %v. Cleanup error: %v", err, cleanupErr)
 			}
+			continue
+		}
+
+		i := <-finished
+		running--
+		done++
+		for _, j := range unblocks[i] {
+			blockers[j]--
 		}
-		ts.cleanupQueue = []CleanupFunc{}
 	}
 }
 
 // NewSession returns a `Session` who's cleanup method is registered with this `Session`
 func (ts *Session) NewSession() *Session {
 	sess := NewSession()
+	sess.CleanupConcurrency = ts.CleanupConcurrency
+	sess.CleanupBackoff = ts.CleanupBackoff
+	sess.RunID = ts.RunID
+	sess.journal = ts.journal
 
 	ts.RegisterCleanupFunc(func() error {
-		sess.Cleanup()
+		report, _ := sess.CleanupWithReport()
+
+		ts.lock.Lock()
+		ts.nestedReports = append(ts.nestedReports, report)
+		ts.lock.Unlock()
+
 		return nil
 	})
 
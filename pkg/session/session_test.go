@@ -0,0 +1,261 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_CleanupOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		entries     []CleanupEntry
+		want        []string
+	}{
+		{
+			name: "lifo",
+			entries: []CleanupEntry{
+				{Name: "first"},
+				{Name: "second"},
+				{Name: "third"},
+			},
+			want: []string{"third", "second", "first"},
+		},
+		{
+			name: "dependency registered after dependent",
+			entries: []CleanupEntry{
+				{Name: "workload", DependsOn: []string{"namespace"}},
+				{Name: "namespace"},
+			},
+			want: []string{"workload", "namespace"},
+		},
+		{
+			name:        "parallel",
+			concurrency: 4,
+			entries: []CleanupEntry{
+				{Name: "cluster"},
+				{Name: "pool", DependsOn: []string{"cluster"}},
+				{Name: "pool", DependsOn: []string{"cluster"}},
+			},
+			want: []string{"pool", "pool", "cluster"},
+		},
+		{
+			name: "cycle",
+			entries: []CleanupEntry{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			want: []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var got []string
+
+			ts := NewSession()
+			ts.CleanupConcurrency = tt.concurrency
+			for _, entry := range tt.entries {
+				name := entry.Name
+				entry.Cleanup = func() error {
+					lock.Lock()
+					defer lock.Unlock()
+					got = append(got, name)
+					return nil
+				}
+				ts.RegisterCleanupEntry(entry)
+			}
+
+			ts.Cleanup()
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cleanup() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_RegisterCleanupFuncConcurrent(t *testing.T) {
+	ts := NewSession()
+
+	var count int
+	var lock sync.Mutex
+	var waitGroup sync.WaitGroup
+	for range 50 {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			ts.RegisterCleanupFunc(func() error {
+				lock.Lock()
+				defer lock.Unlock()
+				count++
+				return nil
+			})
+		}()
+	}
+	waitGroup.Wait()
+
+	ts.CleanupConcurrency = 8
+	ts.Cleanup()
+
+	if count != 50 {
+		t.Errorf("Cleanup() ran %d functions, want 50", count)
+	}
+}
+
+func Test_CleanupWithReport(t *testing.T) {
+	ts := NewSession()
+	ts.CleanupBackoff = &wait.Backoff{Steps: 2}
+	ts.LeakManifestPath = filepath.Join(t.TempDir(), "leaks.yaml")
+
+	ts.RegisterCleanupFunc(func() error { return nil })
+	ts.RegisterCleanupEntry(CleanupEntry{
+		Resource: &Resource{Kind: "Namespace", Version: "v1", Name: "leaked"},
+		Cleanup:  func() error { return errors.New("webhook denied the request") },
+	})
+
+	report, err := ts.CleanupWithReport()
+	if err == nil {
+		t.Fatal("CleanupWithReport() error = nil, want an error")
+	}
+	if len(report.Cleaned) != 1 || len(report.Failed) != 1 {
+		t.Fatalf("CleanupWithReport() cleaned %d and failed %d, want 1 and 1", len(report.Cleaned), len(report.Failed))
+	}
+	if report.Failed[0].Attempts != 2 {
+		t.Errorf("CleanupWithReport() attempts = %d, want 2", report.Failed[0].Attempts)
+	}
+
+	manifest, err := ReadLeakManifest(ts.LeakManifestPath)
+	if err != nil {
+		t.Fatalf("ReadLeakManifest() error = %v", err)
+	}
+	if len(manifest.Resources) != 1 || manifest.Resources[0].Name != "leaked" || manifest.Resources[0].CreatedBy == "" {
+		t.Errorf("ReadLeakManifest() = %+v, want the leaked namespace with its call site", manifest.Resources)
+	}
+}
+
+func Test_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.journal")
+
+	ts, err := NewPersistentSession(path)
+	if err != nil {
+		t.Fatalf("NewPersistentSession() error = %v", err)
+	}
+	defer ts.Close()
+
+	ts.RegisterCleanupEntry(CleanupEntry{
+		Resource: &Resource{Kind: "Namespace", Version: "v1", Name: "cleaned"},
+		Cleanup:  func() error { return nil },
+	})
+	ts.RegisterCleanupEntry(CleanupEntry{
+		Resource: &Resource{Kind: "Namespace", Version: "v1", Name: "killed"},
+		Cleanup:  func() error { return nil },
+	})
+
+	// simulate the test process being killed after deleting the first resource only
+	ts.journal.cleaned(1)
+
+	var deleted []string
+	resumed, err := Resume(path, func(resource Resource) error {
+		deleted = append(deleted, resource.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	resumed.Cleanup()
+
+	if !reflect.DeepEqual(deleted, []string{"killed"}) {
+		t.Errorf("Resume() deleted %v, want [killed]", deleted)
+	}
+	if resumed.journal.file != nil {
+		t.Errorf("Cleanup() did not close the journal")
+	}
+	if err := resumed.Close(); err != nil {
+		t.Errorf("Close() after Cleanup() error = %v", err)
+	}
+
+	pending, err := ReadJournal(path)
+	if err != nil {
+		t.Fatalf("ReadJournal() error = %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("ReadJournal() = %v, want no pending resources", pending)
+	}
+}
+
+func Test_ClusterIDFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://rancher.example.com/v3":                            LocalClusterID,
+		"rancher.example.com":                                       LocalClusterID,
+		"https://rancher.example.com/v3/clusters/c-m-abc":           "c-m-abc",
+		"https://rancher.example.com/k8s/clusters/c-m-abc":          "c-m-abc",
+		"https://rancher.example.com/k8s/clusters/c-m-abc/api/v1/x": "c-m-abc",
+	}
+
+	for url, want := range tests {
+		if got := ClusterIDFromURL(url); got != want {
+			t.Errorf("ClusterIDFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func Test_SanitizeRunID(t *testing.T) {
+	tests := map[string]string{
+		"abc123":                       "abc123",
+		"Jenkins_Nightly#42":           "jenkins_nightly-42",
+		"--release/v2.9.x--":           "release-v2.9.x",
+		"***":                          "",
+		strings.Repeat("a", 62) + "-b": strings.Repeat("a", 62),
+	}
+
+	for id, want := range tests {
+		got := SanitizeLabelValue(id, maxLabelValueLength)
+		if got != want {
+			t.Errorf("SanitizeLabelValue(%q) = %q, want %q", id, got, want)
+		}
+		if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+			t.Errorf("SanitizeLabelValue(%q) = %q, not a valid label value: %v", id, got, errs)
+		}
+	}
+}
+
+func Test_CloseFuncAfterCleanup(t *testing.T) {
+	ts := NewSession()
+	ts.CleanupConcurrency = 8
+
+	var lock sync.Mutex
+	var got []string
+	record := func(name string) CleanupFunc {
+		return func() error {
+			lock.Lock()
+			defer lock.Unlock()
+			got = append(got, name)
+			return nil
+		}
+	}
+
+	ts.RegisterCloseFunc(record("recorder"))
+	for range 10 {
+		ts.RegisterCleanupFunc(record("resource"))
+	}
+
+	ts.Cleanup()
+
+	if len(got) != 11 || got[10] != "recorder" {
+		t.Errorf("Cleanup() order = %v, want the close function last", got)
+	}
+
+	err := ts.Close()
+	if err != nil || len(got) != 11 {
+		t.Errorf("Close() = %v, ran %d functions, want the close function run once", err, len(got))
+	}
+}
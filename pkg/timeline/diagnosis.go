@@ -0,0 +1,77 @@
+package timeline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Diagnosis explains why the tracked object is not ready, from the last state of the objects a Recorder observed.
+type Diagnosis struct {
+	// StuckCondition is the condition of the tracked object that has not been true for the longest time.
+	StuckCondition *Event `json:"stuckCondition,omitempty" yaml:"stuckCondition,omitempty"`
+	// FailingObject is the last object, other than the tracked one, e.g. a machine, in error or with a false condition.
+	FailingObject *Event `json:"failingObject,omitempty" yaml:"failingObject,omitempty"`
+	// LastError is the last event reporting an error.
+	LastError *Event  `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	Phases    []Phase `json:"phases" yaml:"phases"`
+	// Elapsed is the time since the first phase started.
+	Elapsed time.Duration `json:"elapsed" yaml:"elapsed"`
+}
+
+// Diagnose returns the Diagnosis of the observed objects.
+func (r *Recorder) Diagnose() *Diagnosis {
+	phases := r.Phases()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	diagnosis := &Diagnosis{
+		Phases:  phases,
+		Elapsed: r.Now().Sub(r.start),
+	}
+
+	// the last event of a condition is the time it got its current status
+	for _, event := range r.last {
+		if event.Kind == r.kind && event.Condition != "" && event.Status != "True" {
+			if diagnosis.StuckCondition == nil || event.Time.Before(diagnosis.StuckCondition.Time) {
+				diagnosis.StuckCondition = &event
+			}
+		}
+	}
+
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		key := event.Kind + "/" + event.Name + "/" + event.Condition
+		current := r.last[key].Time.Equal(event.Time)
+
+		if diagnosis.LastError == nil && event.Error {
+			diagnosis.LastError = &event
+		}
+		if diagnosis.FailingObject == nil && current && event.Kind != r.kind && (event.Error || event.Status == "False") {
+			diagnosis.FailingObject = &event
+		}
+	}
+
+	return diagnosis
+}
+
+func (d *Diagnosis) String() string {
+	var description strings.Builder
+	fmt.Fprintf(&description, "not ready after %v", d.Elapsed.Round(time.Second))
+
+	if d.StuckCondition != nil {
+		fmt.Fprintf(&description, "\n  stuck: %s", d.StuckCondition)
+	}
+	if d.FailingObject != nil {
+		fmt.Fprintf(&description, "\n  failing: %s", d.FailingObject)
+	}
+	if d.LastError != nil {
+		fmt.Fprintf(&description, "\n  last error: %s", d.LastError)
+	}
+	for _, phase := range d.Phases {
+		fmt.Fprintf(&description, "\n  phase %s took %v", phase.Name, phase.Duration.Round(time.Second))
+	}
+
+	return description.String()
+}
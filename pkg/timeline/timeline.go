@@ -0,0 +1,224 @@
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/wrangler/pkg/summary"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Event is a transition of the state, or of a condition, of an object.
+type Event struct {
+	Time time.Time `json:"time" yaml:"time"`
+	// Kind and Name, namespace/name, identify the object.
+	Kind string `json:"kind" yaml:"kind"`
+	Name string `json:"name" yaml:"name"`
+	// Condition is the type of the condition that changed, empty when the state of the object changed.
+	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+	// Status is the new state of the object, or the new status of the condition.
+	Status  string `json:"status" yaml:"status"`
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	Error   bool   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (e Event) String() string {
+	subject := e.Kind + " " + e.Name
+	if e.Condition != "" {
+		subject += " condition " + e.Condition
+	}
+
+	description := fmt.Sprintf("%s %s is %s", e.Time.Format(time.RFC3339), subject, e.Status)
+	if e.Reason != "" {
+		description += " (" + e.Reason + ")"
+	}
+	if e.Message != "" {
+		description += ": " + e.Message
+	}
+
+	return description
+}
+
+// Phase is the time between the previous phase and the first time a condition of the tracked object became true.
+type Phase struct {
+	Name      string        `json:"name" yaml:"name"`
+	Started   time.Time     `json:"started" yaml:"started"`
+	Completed time.Time     `json:"completed" yaml:"completed"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+}
+
+// Recorder records the transitions of the objects it observes. The phases are the conditions of the object of the
+// tracked kind, e.g. the provisioning cluster, while the objects of the other kinds, e.g. its machines, explain
+// where it is stuck. It is safe for concurrent use.
+type Recorder struct {
+	// Now returns the current time, time.Now by default.
+	Now func() time.Time
+
+	kind    string
+	start   time.Time
+	started bool
+
+	lock   sync.Mutex
+	events []Event
+	last   map[string]Event
+}
+
+// NewRecorder returns a Recorder whose phases are the conditions of the objects of kind.
+func NewRecorder(kind string) *Recorder {
+	return &Recorder{
+		Now:   time.Now,
+		kind:  kind,
+		start: time.Now(),
+		last:  map[string]Event{},
+	}
+}
+
+// Start sets the time the first phase starts at, the creation of the Recorder by default. Only the first call has an
+// effect, so that the start can be set from every observation of the tracked object.
+func (r *Recorder) Start(start time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.started {
+		return
+	}
+
+	r.start = start
+	r.started = true
+}
+
+// Observe records the transitions of obj since it was last observed: its state, as summarized by Rancher, and its
+// status conditions.
+func (r *Recorder) Observe(obj *unstructured.Unstructured) {
+	now := r.Now()
+	kind := obj.GetKind()
+	name := obj.GetName()
+	if obj.GetNamespace() != "" {
+		name = obj.GetNamespace() + "/" + name
+	}
+
+	objSummary := summary.Summarize(obj)
+	state := Event{
+		Time:    now,
+		Kind:    kind,
+		Name:    name,
+		Status:  objSummary.State,
+		Message: strings.Join(objSummary.Message, "; "),
+		Error:   objSummary.Error,
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.record(state)
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		conditionType, _ := condition["type"].(string)
+		status, _ := condition["status"].(string)
+		reason, _ := condition["reason"].(string)
+		message, _ := condition["message"].(string)
+		if conditionType == "" {
+			continue
+		}
+
+		r.record(Event{
+			Time:      now,
+			Kind:      kind,
+			Name:      name,
+			Condition: conditionType,
+			Status:    status,
+			Reason:    reason,
+			Message:   message,
+			Error:     reason == "Error" || (status == "False" && message != "" && strings.Contains(strings.ToLower(message), "error")),
+		})
+	}
+}
+
+// record appends event when it differs from the last event of the same state or condition.
+func (r *Recorder) record(event Event) {
+	key := event.Kind + "/" + event.Name + "/" + event.Condition
+	last, ok := r.last[key]
+	if ok && last.Status == event.Status && last.Reason == event.Reason && last.Message == event.Message && last.Error == event.Error {
+		return
+	}
+
+	r.last[key] = event
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events, in the order they were observed.
+func (r *Recorder) Events() []Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]Event(nil), r.events...)
+}
+
+// Phases returns the phases completed so far, sorted by completion: one per condition of the tracked kind, ended the
+// first time the condition became true.
+func (r *Recorder) Phases() []Phase {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	completed := map[string]time.Time{}
+	for _, event := range r.events {
+		if event.Kind != r.kind || event.Condition == "" || event.Status != "True" {
+			continue
+		}
+		if _, ok := completed[event.Condition]; !ok {
+			completed[event.Condition] = event.Time
+		}
+	}
+
+	phases := make([]Phase, 0, len(completed))
+	for name, end := range completed {
+		phases = append(phases, Phase{Name: name, Completed: end})
+	}
+	sort.SliceStable(phases, func(i, j int) bool {
+		if phases[i].Completed.Equal(phases[j].Completed) {
+			return phases[i].Name < phases[j].Name
+		}
+		return phases[i].Completed.Before(phases[j].Completed)
+	})
+
+	started := r.start
+	for i := range phases {
+		phases[i].Started = started
+		phases[i].Duration = phases[i].Completed.Sub(started)
+		started = phases[i].Completed
+	}
+
+	return phases
+}
+
+// Report is the timeline of a run, to chart the phase durations across runs.
+type Report struct {
+	Started time.Time `json:"started" yaml:"started"`
+	Phases  []Phase   `json:"phases" yaml:"phases"`
+	Events  []Event   `json:"events" yaml:"events"`
+}
+
+// Report returns the Report of the observed objects.
+func (r *Recorder) Report() *Report {
+	phases := r.Phases()
+	events := r.Events()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return &Report{
+		Started: r.start,
+		Phases:  phases,
+		Events:  events,
+	}
+}
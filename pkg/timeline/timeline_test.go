@@ -0,0 +1,86 @@
+package timeline
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func object(kind, name, state string, conditions ...map[string]interface{}) *unstructured.Unstructured {
+	statusConditions := make([]interface{}, 0, len(conditions))
+	for _, condition := range conditions {
+		statusConditions = append(statusConditions, condition)
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "provisioning.cattle.io/v1",
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "fleet-default",
+		},
+		"status": map[string]interface{}{
+			"conditions": statusConditions,
+			"state":      state,
+		},
+	}}
+}
+
+func condition(conditionType, status, reason, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    conditionType,
+		"status":  status,
+		"reason":  reason,
+		"message": message,
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	recorder := NewRecorder("Cluster")
+	recorder.Now = func() time.Time { return now }
+	recorder.Start(start)
+	recorder.Start(start.Add(time.Minute))
+
+	now = start.Add(time.Minute)
+	recorder.Observe(object("Cluster", "test", "",
+		condition("Created", "True", "", ""),
+		condition("Ready", "False", "", "waiting for machines")))
+	recorder.Observe(object("Cluster", "test", "",
+		condition("Created", "True", "", ""),
+		condition("Ready", "False", "", "waiting for machines")))
+
+	now = start.Add(3 * time.Minute)
+	recorder.Observe(object("Machine", "test-pool-1", "",
+		condition("InfrastructureReady", "False", "Error", "failed to create the instance")))
+
+	now = start.Add(4 * time.Minute)
+	recorder.Observe(object("Cluster", "test", "",
+		condition("Created", "True", "", ""),
+		condition("Provisioned", "True", "", ""),
+		condition("Ready", "False", "", "waiting for machines")))
+
+	phases := recorder.Phases()
+	if len(phases) != 2 || phases[0].Name != "Created" || phases[1].Name != "Provisioned" {
+		t.Fatalf("unexpected phases %+v", phases)
+	}
+	if phases[0].Duration != time.Minute || phases[1].Duration != 3*time.Minute {
+		t.Errorf("phases took %v and %v, want 1m0s and 3m0s", phases[0].Duration, phases[1].Duration)
+	}
+
+	diagnosis := recorder.Diagnose()
+	if diagnosis.StuckCondition == nil || diagnosis.StuckCondition.Condition != "Ready" {
+		t.Errorf("stuck condition is %v, want Ready", diagnosis.StuckCondition)
+	}
+	if diagnosis.FailingObject == nil || diagnosis.FailingObject.Name != "fleet-default/test-pool-1" {
+		t.Errorf("failing object is %v, want the machine fleet-default/test-pool-1", diagnosis.FailingObject)
+	}
+	if diagnosis.LastError == nil || diagnosis.LastError.Message != "failed to create the instance" {
+		t.Errorf("last error is %v, want the machine error", diagnosis.LastError)
+	}
+	if diagnosis.Elapsed != 4*time.Minute {
+		t.Errorf("elapsed %v, want 4m0s", diagnosis.Elapsed)
+	}
+}
@@ -0,0 +1,278 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ArtifactsEnvironmentKey is the environment variable naming the directory the workspaces are archived to when
+	// their session is cleaned up, see Workspace.ArchiveFile.
+	ArtifactsEnvironmentKey = "CATTLE_TEST_ARTIFACTS_DIR"
+	// KeepEnvironmentKey is the environment variable keeping the workspaces on disk when their session is cleaned up,
+	// e.g. to debug a failed run.
+	KeepEnvironmentKey = "CATTLE_TEST_KEEP_WORKSPACE"
+
+	fileMode   = 0644
+	secretMode = 0600
+	dirMode    = 0700
+)
+
+// Artifact is a file of a Workspace.
+type Artifact struct {
+	// Name is the path of the file relative to the workspace directory.
+	Name string
+	// Secret files, e.g. kubeconfigs, SSH keys or RKE states, are only readable by the owner and left out of the
+	// archives.
+	Secret bool
+}
+
+// Workspace is a private scratch directory of a session, where the files written by the tests and the tools they
+// run, e.g. kubeconfigs, cluster.yml and RKE state files, are kept instead of the working directory.
+type Workspace struct {
+	dir string
+
+	lock      sync.Mutex
+	artifacts map[string]Artifact
+}
+
+// New creates a Workspace in a new temporary directory named after name. When ts is not nil, the workspace is
+// archived to ArtifactsEnvironmentKey, if set, and removed, unless KeepEnvironmentKey is set, when ts is cleaned up.
+func New(ts *session.Session, name string) (*Workspace, error) {
+	dir, err := os.MkdirTemp("", "shepherd-"+name+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workspace{
+		dir:       dir,
+		artifacts: map[string]Artifact{},
+	}
+
+	if ts != nil {
+		ts.RegisterCleanupFunc(w.close)
+	}
+
+	return w, nil
+}
+
+// close archives the workspace to ArtifactsEnvironmentKey, if set, and removes it, unless KeepEnvironmentKey is set.
+func (w *Workspace) close() error {
+	if artifacts := os.Getenv(ArtifactsEnvironmentKey); artifacts != "" {
+		err := os.MkdirAll(artifacts, 0755)
+		if err != nil {
+			return err
+		}
+
+		archive := filepath.Join(artifacts, filepath.Base(w.dir)+".tar.gz")
+		err = w.ArchiveFile(archive)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("workspace %s archived to %s", w.dir, archive)
+	}
+
+	if os.Getenv(KeepEnvironmentKey) != "" {
+		logrus.Infof("workspace %s is kept", w.dir)
+		return nil
+	}
+
+	return w.Remove()
+}
+
+// Dir returns the directory of the workspace.
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Path returns the path of the file name in the workspace, it fails if name is outside of the workspace.
+func (w *Workspace) Path(name string) (string, error) {
+	if filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%s is not a relative path in the workspace", name)
+	}
+
+	return filepath.Join(w.dir, name), nil
+}
+
+// Mkdir creates the directory name in the workspace, with its parents, and returns its path.
+func (w *Workspace) Mkdir(name string) (string, error) {
+	path, err := w.Path(name)
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.MkdirAll(path, dirMode)
+}
+
+// WriteFile writes data to the file name in the workspace, creating its directory, and returns its path.
+func (w *Workspace) WriteFile(name string, data []byte) (string, error) {
+	return w.write(name, data, false)
+}
+
+// WriteSecret writes data to the file name in the workspace, only readable by the owner, and returns its path.
+// Secrets are left out of the archives.
+func (w *Workspace) WriteSecret(name string, data []byte) (string, error) {
+	return w.write(name, data, true)
+}
+
+func (w *Workspace) write(name string, data []byte, secret bool) (string, error) {
+	path, err := w.Path(name)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), dirMode)
+	if err != nil {
+		return "", err
+	}
+
+	mode := os.FileMode(fileMode)
+	if secret {
+		mode = secretMode
+	}
+
+	err = os.WriteFile(path, data, mode)
+	if err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file
+	err = os.Chmod(path, mode)
+	if err != nil {
+		return "", err
+	}
+
+	w.Track(name, secret)
+
+	return path, nil
+}
+
+// Track records the file name written in the workspace by another program, e.g. the state written by the RKE CLI.
+// A secret file is only readable by the owner and left out of the archives.
+func (w *Workspace) Track(name string, secret bool) {
+	name = filepath.Clean(name)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	artifact := w.artifacts[name]
+	artifact.Name = name
+	artifact.Secret = artifact.Secret || secret
+	w.artifacts[name] = artifact
+
+	if secret {
+		if path, err := w.Path(name); err == nil {
+			// the file may not be written yet
+			_ = os.Chmod(path, secretMode)
+		}
+	}
+}
+
+// Artifacts returns the tracked files of the workspace, sorted by name.
+func (w *Workspace) Artifacts() []Artifact {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	artifacts := make([]Artifact, 0, len(w.artifacts))
+	for _, artifact := range w.artifacts {
+		artifacts = append(artifacts, artifact)
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Name < artifacts[j].Name
+	})
+
+	return artifacts
+}
+
+// Archive writes the tracked files of the workspace to out as a gzipped tarball. The secrets are left out, and so are
+// the files not tracked, since the programs run in the workspace may write secrets, e.g. the kubeconfig written by
+// the RKE CLI.
+func (w *Workspace) Archive(out io.Writer) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	for _, artifact := range w.Artifacts() {
+		if artifact.Secret {
+			continue
+		}
+
+		err := w.archiveFile(tw, artifact.Name)
+		if err != nil {
+			return fmt.Errorf("failed to archive workspace %s: %w", w.dir, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// archiveFile writes the regular file name of the workspace to tw, the tracked files not written yet are skipped.
+func (w *Workspace) archiveFile(tw *tar.Writer, name string) error {
+	path, err := w.Path(name)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+
+	err = tw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ArchiveFile writes the archive of the workspace, see Archive, to the file at path.
+func (w *Workspace) ArchiveFile(path string) error {
+	if strings.HasPrefix(filepath.Clean(path), w.dir+string(filepath.Separator)) {
+		return fmt.Errorf("cannot archive workspace %s into itself", w.dir)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileMode)
+	if err != nil {
+		return err
+	}
+
+	err = w.Archive(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Remove deletes the workspace directory and its files.
+func (w *Workspace) Remove() error {
+	return os.RemoveAll(w.dir)
+}
@@ -0,0 +1,112 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/rancher/shepherd/pkg/session"
+)
+
+func archivedNames(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func Test_Workspace(t *testing.T) {
+	artifacts := t.TempDir()
+	t.Setenv(ArtifactsEnvironmentKey, artifacts)
+
+	ts := session.NewSession()
+	w, err := New(ts, "test")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if info, _ := os.Stat(w.Dir()); info.Mode().Perm() != dirMode {
+		t.Errorf("workspace mode = %v, want %v", info.Mode().Perm(), os.FileMode(dirMode))
+	}
+
+	logs, err := w.WriteFile("logs/test.log", []byte("log"))
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	kubeconfig, err := w.WriteSecret("kubeconfig.yaml", []byte("token"))
+	if err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if info, _ := os.Stat(kubeconfig); info.Mode().Perm() != secretMode {
+		t.Errorf("secret mode = %v, want %v", info.Mode().Perm(), os.FileMode(secretMode))
+	}
+	if filepath.Dir(filepath.Dir(logs)) != w.Dir() {
+		t.Errorf("WriteFile() = %s, want a file of %s", logs, w.Dir())
+	}
+
+	if _, err := w.WriteFile("../escape", nil); err == nil {
+		t.Error("WriteFile() outside of the workspace succeeded")
+	}
+
+	// written by another program
+	err = os.WriteFile(filepath.Join(w.Dir(), "cluster.rkestate"), []byte("state"), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	w.Track("cluster.rkestate", true)
+	err = os.WriteFile(filepath.Join(w.Dir(), "kube_config_cluster.yml"), []byte("token"), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	w.Track("missing.log", false)
+
+	got := w.Artifacts()
+	want := []Artifact{
+		{Name: "cluster.rkestate", Secret: true}, {Name: "kubeconfig.yaml", Secret: true}, {Name: "logs/test.log"}, {Name: "missing.log"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Artifacts() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Artifacts()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	ts.Cleanup()
+
+	if _, err := os.Stat(w.Dir()); !os.IsNotExist(err) {
+		t.Errorf("workspace %s still exists after the session cleanup", w.Dir())
+	}
+
+	names := archivedNames(t, filepath.Join(artifacts, filepath.Base(w.Dir())+".tar.gz"))
+	if len(names) != 1 || names[0] != "logs/test.log" {
+		t.Errorf("archive = %v, want [logs/test.log] without the secrets and the untracked files", names)
+	}
+}
@@ -185,6 +185,7 @@ type Controller[T RuntimeMetaObject, TList runtime.Object] struct {
 	groupResource schema.GroupResource
 	objType       reflect.Type
 	objListType   reflect.Type
+	clusterID     string
 	ts            *session.Session
 }
 
@@ -206,9 +207,10 @@ func NewController[T RuntimeMetaObject, TList runtime.Object](gvk schema.GroupVe
 	if objListPtrType.Kind() != reflect.Pointer {
 		panic(fmt.Sprintf("Controller requires Object TList to be a pointer not %v", objListPtrType))
 	}
+	client := sharedCtrl.Client()
 	return &Controller[T, TList]{
 		controller:     sharedCtrl,
-		embeddedClient: sharedCtrl.Client(),
+		embeddedClient: client,
 		gvk:            gvk,
 		groupResource: schema.GroupResource{
 			Group:    gvk.Group,
@@ -216,6 +218,7 @@ func NewController[T RuntimeMetaObject, TList runtime.Object](gvk schema.GroupVe
 		},
 		objType:     objPtrType.Elem(),
 		objListType: objListPtrType.Elem(),
+		clusterID:   session.ClusterIDFromURL(client.Config.Host),
 		ts:          ts,
 	}
 }
@@ -281,21 +284,35 @@ func (c *Controller[T, TList]) Cache() CacheInterface[T] {
 }
 
 // Create creates a new object and return the newly created Object or an error.
+// The run labels of the session are added to the object, see session.RunLabels.
 func (c *Controller[T, TList]) Create(obj T) (T, error) {
 	result := reflect.New(c.objType).Interface().(T)
 
-	c.ts.RegisterCleanupFunc(func() error {
-		err := c.embeddedClient.Delete(context.TODO(), obj.GetNamespace(), obj.GetName(), metav1.DeleteOptions{})
-		if errors.IsNotFound(err) {
-			return nil
-		}
-		name := obj.GetName()
-		if obj.GetNamespace() != "" {
-			name = obj.GetNamespace() + "/" + name
-		}
-		gvk := obj.GetObjectKind().GroupVersionKind()
-
-		return fmt.Errorf("unable to delete (%v) %v: %w", gvk, name, err)
+	obj = obj.DeepCopyObject().(T)
+	c.ts.StampRunLabels(obj)
+
+	c.ts.RegisterCleanupEntry(session.CleanupEntry{
+		Resource: &session.Resource{
+			Group:     c.gvk.Group,
+			Version:   c.gvk.Version,
+			Kind:      c.gvk.Kind,
+			Resource:  c.groupResource.Resource,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			ClusterID: c.clusterID,
+		},
+		Cleanup: func() error {
+			err := c.embeddedClient.Delete(context.TODO(), obj.GetNamespace(), obj.GetName(), metav1.DeleteOptions{})
+			if err == nil || errors.IsNotFound(err) {
+				return nil
+			}
+			name := obj.GetName()
+			if obj.GetNamespace() != "" {
+				name = obj.GetNamespace() + "/" + name
+			}
+
+			return fmt.Errorf("unable to delete (%v) %v: %w", c.gvk, name, err)
+		},
 	})
 
 	return result, c.embeddedClient.Create(context.TODO(), obj.GetNamespace(), obj, result, metav1.CreateOptions{})